@@ -0,0 +1,110 @@
+// Package chanaffine provides a feature transform which applies
+// an affine transform (e.g. PCA) to the channels of another transform.
+package chanaffine
+
+import (
+	"fmt"
+	"image"
+	"path"
+	"sync"
+
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/featset"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/imset"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func init() {
+	featset.RegisterImage("channel-affine", func() featset.Image { return new(Transform) })
+}
+
+// Transform applies an affine transform to the channel vector
+// of every pixel in the output of Feat.
+// The affine transform is loaded from TransformFile
+// the first time that it is needed.
+//
+// Since Channels cannot return an error,
+// it panics if the transform cannot be loaded.
+type Transform struct {
+	Feat featset.ImageMarshaler
+	// vecset.Affine in JSON, as computed by cmd/channel-pca.
+	// Must be an absolute path since the transform
+	// may be applied on a host with a different working directory.
+	TransformFile string
+
+	once   sync.Once
+	affine *vecset.Affine
+	err    error
+}
+
+// Transform returns the transform itself.
+func (t *Transform) Transform() feat.Image {
+	return t
+}
+
+func (t *Transform) Rate() int {
+	return t.Feat.Transform().Rate()
+}
+
+func (t *Transform) Size(x image.Point) image.Point {
+	return t.Feat.Transform().Size(x)
+}
+
+func (t *Transform) MinInputSize(x image.Point) image.Point {
+	return t.Feat.Transform().MinInputSize(x)
+}
+
+func (t *Transform) Channels() int {
+	affine, err := t.Affine()
+	if err != nil {
+		panic(err)
+	}
+	return affine.Dim(t.Feat.Transform().Channels())
+}
+
+// Apply computes the features of x and transforms their channels.
+func (t *Transform) Apply(x image.Image) (*rimg64.Multi, error) {
+	affine, err := t.Affine()
+	if err != nil {
+		return nil, err
+	}
+	f, err := t.Feat.Transform().Apply(x)
+	if err != nil {
+		return nil, err
+	}
+	return imset.ApplyChannels(affine, f), nil
+}
+
+// Affine loads the channel transform
+// if it has not been done already.
+func (t *Transform) Affine() (*vecset.Affine, error) {
+	t.once.Do(func() {
+		t.affine, t.err = t.load()
+	})
+	return t.affine, t.err
+}
+
+func (t *Transform) load() (*vecset.Affine, error) {
+	if !path.IsAbs(t.TransformFile) {
+		return nil, fmt.Errorf(`channel transform file must be an absolute path: "%s"`, t.TransformFile)
+	}
+	affine := new(vecset.Affine)
+	if err := fileutil.LoadJSON(t.TransformFile, affine); err != nil {
+		return nil, fmt.Errorf(`load channel transform "%s": %v`, t.TransformFile, err)
+	}
+	in := t.Feat.Transform().Channels()
+	if affine.Mean != nil && len(affine.Mean) != in {
+		return nil, fmt.Errorf("channel transform: mean has dimension %d, feature has %d channels", len(affine.Mean), in)
+	}
+	if affine.Scale != nil && len(affine.Scale) != in {
+		return nil, fmt.Errorf("channel transform: scale has dimension %d, feature has %d channels", len(affine.Scale), in)
+	}
+	for i, row := range affine.Proj {
+		if len(row) != in {
+			return nil, fmt.Errorf("channel transform: projection row %d has dimension %d, feature has %d channels", i, len(row), in)
+		}
+	}
+	return affine, nil
+}
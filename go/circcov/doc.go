@@ -3,5 +3,10 @@ Package circcov addresses circulant covariance matrices.
 
 Circulant covariance matrices are derived from Toeplitz ones.
 There are a number of ways in which to do this, specified by CoeffsFunc.
+The circulant matrix may also be formed on a grid larger than the template,
+specified by Embed, in which case solutions are cropped to the template.
+
+InvApprox uses the circulant approximation to compute
+a banded approximation to the inverse of a Toeplitz covariance.
 */
 package circcov
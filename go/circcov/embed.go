@@ -0,0 +1,144 @@
+package circcov
+
+import (
+	"fmt"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Embed specifies the circulant grid in which a template is embedded.
+//
+// The template occupies the top-left corner of the grid.
+// Images are padded with zeros to the size of the grid,
+// the circulant system is solved on the whole grid
+// and the result is cropped back to the size of the template.
+type Embed struct {
+	// Dimensions of the circulant grid.
+	// If zero, the template dimension is used.
+	// Otherwise must not be less than the template dimension.
+	Width, Height int
+	// Determines how the circulant matrix is formed from the Toeplitz matrix.
+	// If nil, Convex is used.
+	// Ignored if ZeroBoundary is true.
+	Coeffs CoeffsFunc
+	// Discard displacements which do not occur within the template,
+	// and those which would overlap when wrapped around the grid,
+	// instead of mixing them.
+	// The rest of each row of the circulant matrix is zero.
+	// If the grid is at least (2w-1) x (2h-1), the template block of
+	// the circulant matrix is exactly the Toeplitz matrix,
+	// regardless of the bandwidth of the covariance.
+	ZeroBoundary bool
+}
+
+// Grid returns the dimensions of the circulant grid
+// for a w x h template.
+// Panics if the grid is smaller than the template.
+func (e Embed) Grid(w, h int) (m, n int) {
+	m, n = e.Width, e.Height
+	if m == 0 {
+		m = w
+	}
+	if n == 0 {
+		n = h
+	}
+	if m < w || n < h {
+		panic(fmt.Sprintf("grid smaller than template: grid %dx%d, template %dx%d", m, n, w, h))
+	}
+	return m, n
+}
+
+// coeffs returns nil if elements should be summed.
+func (e Embed) coeffs() CoeffsFunc {
+	if e.ZeroBoundary {
+		return nil
+	}
+	if e.Coeffs == nil {
+		return Convex
+	}
+	return e.Coeffs
+}
+
+// band returns the maximum displacement in x and y
+// for a w x h template.
+func (e Embed) band(g *toepcov.Covar, w, h int) (bx, by int) {
+	bx, by = g.Bandwidth, g.Bandwidth
+	if e.ZeroBoundary {
+		m, n := e.Grid(w, h)
+		bx = min(min(bx, w-1), (m-1)/2)
+		by = min(min(by, h-1), (n-1)/2)
+	}
+	return bx, by
+}
+
+// MulEmbed computes the product of the embedded circulant covariance matrix
+// with an image, cropped to the size of the image.
+func MulEmbed(g *toepcov.Covar, f *rimg64.Multi, e Embed) *rimg64.Multi {
+	var op Muler
+	op.InitEmbed(g, f.Width, f.Height, e)
+	return op.Mul(f)
+}
+
+// InvMulEmbed solves for x in S x = f where S is the embedded circulant
+// covariance matrix and f is padded with zeros.
+// The solution is cropped to the size of f.
+func InvMulEmbed(g *toepcov.Covar, f *rimg64.Multi, e Embed) (*rimg64.Multi, error) {
+	var op InvMuler
+	if err := op.InitEmbed(g, f.Width, f.Height, e); err != nil {
+		return nil, err
+	}
+	return op.Mul(f), nil
+}
+
+// MatrixEmbed constructs the full circulant covariance matrix
+// on the grid in which a w x h template is embedded.
+// The matrix is indexed in the same order as MatrixMode.
+func MatrixEmbed(g *toepcov.Covar, w, h int, e Embed) *mat.Mat {
+	m, n := e.Grid(w, h)
+	bx, by := e.band(g, w, h)
+	return matrixBand(g, m, n, e.coeffs(), bx, by)
+}
+
+// PadImage copies an image into the top-left corner of a larger one.
+func PadImage(f *rimg64.Multi, m, n int) *rimg64.Multi {
+	g := rimg64.NewMulti(m, n, f.Channels)
+	for i := 0; i < f.Width; i++ {
+		for j := 0; j < f.Height; j++ {
+			for p := 0; p < f.Channels; p++ {
+				g.Set(i, j, p, f.At(i, j, p))
+			}
+		}
+	}
+	return g
+}
+
+// CropImage copies the top-left w x h corner of an image.
+func CropImage(f *rimg64.Multi, w, h int) *rimg64.Multi {
+	g := rimg64.NewMulti(w, h, f.Channels)
+	for i := 0; i < w; i++ {
+		for j := 0; j < h; j++ {
+			for p := 0; p < f.Channels; p++ {
+				g.Set(i, j, p, f.At(i, j, p))
+			}
+		}
+	}
+	return g
+}
+
+// wrapImage copies an image into an m x n grid, shifted by -margin
+// in each dimension and wrapped around the boundary.
+// The rest of the grid is zero.
+func wrapImage(f *rimg64.Multi, margin, m, n int) *rimg64.Multi {
+	g := rimg64.NewMulti(m, n, f.Channels)
+	for i := 0; i < f.Width; i++ {
+		for j := 0; j < f.Height; j++ {
+			u, v := mod(i-margin, m), mod(j-margin, n)
+			for p := 0; p < f.Channels; p++ {
+				g.Set(u, v, p, f.At(i, j, p))
+			}
+		}
+	}
+	return g
+}
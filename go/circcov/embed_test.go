@@ -0,0 +1,207 @@
+package circcov
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jvlmdr/lin-go/mat"
+)
+
+// Checks that embedding without padding gives MatrixMode.
+func TestMatrixEmbed_noPad(t *testing.T) {
+	const (
+		bandwidth = 6
+		width     = 5
+		height    = 4
+		channels  = 2
+	)
+	modes := []struct {
+		Name string
+		Func CoeffsFunc
+	}{
+		{"convex", Convex},
+		{"mean", Mean},
+		{"nearest", Nearest},
+	}
+
+	g := randCovar(channels, bandwidth)
+	for _, mode := range modes {
+		want := MatrixMode(g, width, height, mode.Func)
+		got := MatrixEmbed(g, width, height, Embed{Coeffs: mode.Func})
+		if eq, msg := matsEq(want, got); !eq {
+			t.Errorf(`mode "%s": %s`, mode.Name, msg)
+		}
+	}
+}
+
+// Checks that the template block of the zero-boundary matrix
+// is the Toeplitz matrix when the grid is large enough.
+func TestMatrixEmbed_zeroBoundary(t *testing.T) {
+	const (
+		bandwidth = 8
+		width     = 4
+		height    = 3
+		channels  = 2
+	)
+
+	g := randCovar(channels, bandwidth)
+	e := Embed{Width: 2*width - 1, Height: 2*height - 1, ZeroBoundary: true}
+	m, n := e.Grid(width, height)
+	s := MatrixEmbed(g, width, height, e)
+	want := g.Matrix(width, height)
+
+	// Extract template block.
+	got := mat.New(width*height*channels, width*height*channels)
+	for u := 0; u < width; u++ {
+		for v := 0; v < height; v++ {
+			for p := 0; p < channels; p++ {
+				for i := 0; i < width; i++ {
+					for j := 0; j < height; j++ {
+						for q := 0; q < channels; q++ {
+							row := (u*height+v)*channels + p
+							col := (i*height+j)*channels + q
+							x := s.At((u*n+v)*channels+p, (i*n+j)*channels+q)
+							got.Set(row, col, x)
+						}
+					}
+				}
+			}
+		}
+	}
+	if eq, msg := matsEq(want, got); !eq {
+		t.Errorf("grid %dx%d: %s", m, n, msg)
+	}
+}
+
+// Checks that InvMulEmbed gives the same solution as
+// solving the padded system in the time domain.
+func TestInvMulEmbed_vsMat(t *testing.T) {
+	const (
+		bandwidth = 10
+		width     = 6
+		height    = 4
+		channels  = 2
+	)
+	embeds := []Embed{
+		{Width: 9, Height: 7},
+		{Width: 9, Height: 7, Coeffs: Nearest},
+		{Width: 11, Height: 7, ZeroBoundary: true},
+	}
+
+	g := randCovar(channels, bandwidth)
+	f := randImage(width, height, channels)
+	for _, e := range embeds {
+		got, err := InvMulEmbed(g, f, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, n := e.Grid(width, height)
+		A := MatrixEmbed(g, width, height, e)
+		x, err := matInvMulImage(A, PadImage(f, m, n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := CropImage(x, width, height)
+		if eq, msg := imagesEq(want, got); !eq {
+			t.Errorf("embed %+v: %s", e, msg)
+		}
+	}
+}
+
+// Checks that MulEmbed gives the same product as the padded matrix.
+func TestMulEmbed_vsMat(t *testing.T) {
+	const (
+		bandwidth = 10
+		width     = 6
+		height    = 4
+		channels  = 2
+	)
+	e := Embed{Width: 8, Height: 9}
+
+	g := randCovar(channels, bandwidth)
+	f := randImage(width, height, channels)
+	m, n := e.Grid(width, height)
+	want := CropImage(matMulImage(MatrixEmbed(g, width, height, e), PadImage(f, m, n)), width, height)
+	got := MulEmbed(g, f, e)
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Error(msg)
+	}
+}
+
+// Checks that an InvMuler can be re-used with an embedding.
+func TestInvMuler_embed(t *testing.T) {
+	const (
+		bandwidth = 10
+		width     = 6
+		height    = 4
+		channels  = 2
+	)
+	e := Embed{Width: 11, Height: 7, ZeroBoundary: true}
+
+	g := randCovar(channels, bandwidth)
+	f := randImage(width, height, channels)
+	want, err := InvMulEmbed(g, f, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var muler InvMuler
+	if err := muler.InitEmbed(g, width, height, e); err != nil {
+		t.Fatal(err)
+	}
+	got := muler.Mul(f)
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Error(msg)
+	}
+}
+
+// Checks that MulContext gives the same solution as solving
+// the system with the context wrapped around the grid,
+// and that it is equivalent to Mul without a margin.
+func TestInvMuler_mulContext(t *testing.T) {
+	const (
+		bandwidth = 10
+		width     = 6
+		height    = 4
+		channels  = 2
+		margin    = 2
+	)
+	e := Embed{Width: width + 2*margin, Height: height + 2*margin + 1}
+
+	g := randCovar(channels, bandwidth)
+	f := randImage(width+2*margin, height+2*margin, channels)
+	var muler InvMuler
+	if err := muler.InitEmbed(g, width, height, e); err != nil {
+		t.Fatal(err)
+	}
+	got := muler.MulContext(f, margin)
+	m, n := e.Grid(width, height)
+	x, err := matInvMulImage(MatrixEmbed(g, width, height, e), wrapImage(f, margin, m, n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CropImage(x, width, height)
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Errorf("margin %d: %s", margin, msg)
+	}
+
+	f = randImage(width, height, channels)
+	want = muler.Mul(f)
+	got = muler.MulContext(f, 0)
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Errorf("margin 0: %s", msg)
+	}
+}
+
+func matsEq(want, got *mat.Mat) (bool, string) {
+	if want.Rows != got.Rows || want.Cols != got.Cols {
+		return false, fmt.Sprintf("dims: want %dx%d, got %dx%d", want.Rows, want.Cols, got.Rows, got.Cols)
+	}
+	for i := 0; i < want.Rows; i++ {
+		for j := 0; j < want.Cols; j++ {
+			if !epsEq(want.At(i, j), got.At(i, j), eps) {
+				return false, fmt.Sprintf("at %d, %d: want %.6g, got %.6g", i, j, want.At(i, j), got.At(i, j))
+			}
+		}
+	}
+	return true, ""
+}
@@ -51,28 +51,62 @@ func idftToChannel(dst *rimg64.Multi, channel int, src *fftw.Array2) {
 }
 
 func dftCovarCirc(g *toepcov.Covar, m, n, p, q int, coeffs CoeffsFunc) *fftw.Array2 {
-	at := func(g *toepcov.Covar, du, dv, p, q int) float64 {
-		if abs(du) > g.Bandwidth {
+	return dftCovarCircBand(g, m, n, p, q, coeffs, g.Bandwidth, g.Bandwidth)
+}
+
+// Like dftCovarCirc but displacements greater than bx or by are discarded.
+func dftCovarCircBand(g *toepcov.Covar, m, n, p, q int, coeffs CoeffsFunc, bx, by int) *fftw.Array2 {
+	dst := fftw.NewArray2(m, n)
+	for du := 0; du < m; du++ {
+		for dv := 0; dv < n; dv++ {
+			dst.Set(du, dv, complex(circAt(g, du, dv, m, n, p, q, coeffs, bx, by), 0))
+		}
+	}
+	fftw.FFT2To(dst, dst)
+	return dst
+}
+
+// circAt gives the element of the m x n circulant matrix
+// formed from g using coeffs.
+// Displacements greater than bx or by are treated as zero.
+// If coeffs is nil, the elements are summed instead of mixed.
+// This is only sensible when 2*bx+1 <= m and 2*by+1 <= n,
+// in which case at most one element is non-zero.
+func circAt(g *toepcov.Covar, du, dv, m, n, p, q int, coeffs CoeffsFunc, bx, by int) float64 {
+	at := func(du, dv int) float64 {
+		if abs(du) > bx || abs(du) > g.Bandwidth {
 			return 0
 		}
-		if abs(dv) > g.Bandwidth {
+		if abs(dv) > by || abs(dv) > g.Bandwidth {
 			return 0
 		}
 		return g.At(du, dv, p, q)
 	}
 
-	dst := fftw.NewArray2(m, n)
-	for du := 0; du < m; du++ {
-		for dv := 0; dv < n; dv++ {
-			a, b := coeffs(du, dv, m, n)
-			var h float64
-			h += (1 - a) * (1 - b) * at(g, mod(du, m), mod(dv, n), p, q)
-			h += (1 - a) * b * at(g, mod(du, m), -mod(-dv, n), p, q)
-			h += a * (1 - b) * at(g, -mod(-du, m), mod(dv, n), p, q)
-			h += a * b * at(g, -mod(-du, m), -mod(-dv, n), p, q)
-			dst.Set(du, dv, complex(h, 0))
+	if coeffs == nil {
+		// Count each distinct displacement once.
+		// The positive and negative displacements coincide if either is zero.
+		xs := []int{mod(du, m)}
+		if mod(-du, m) != 0 {
+			xs = append(xs, -mod(-du, m))
+		}
+		ys := []int{mod(dv, n)}
+		if mod(-dv, n) != 0 {
+			ys = append(ys, -mod(-dv, n))
 		}
+		var h float64
+		for _, x := range xs {
+			for _, y := range ys {
+				h += at(x, y)
+			}
+		}
+		return h
 	}
-	fftw.FFT2To(dst, dst)
-	return dst
+	a, b := coeffs(du, dv, m, n)
+	var h float64
+	h += (1 - a) * (1 - b) * at(mod(du, m), mod(dv, n))
+	h += (1 - a) * b * at(mod(du, m), -mod(-dv, n))
+	h += a * (1 - b) * at(-mod(-du, m), mod(dv, n))
+	h += a * b * at(-mod(-du, m), -mod(-dv, n))
+	return h
 }
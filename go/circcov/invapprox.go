@@ -0,0 +1,159 @@
+package circcov
+
+import (
+	"math"
+
+	"github.com/jvlmdr/go-fftw/fftw"
+	"github.com/jvlmdr/lin-go/clap"
+	"github.com/jvlmdr/lin-go/cmat"
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// InvApproxOpts specifies how to approximate the inverse of a Toeplitz covariance.
+type InvApproxOpts struct {
+	// Dimensions of the circulant grid on which the inverse is computed.
+	// Larger grids approximate the inverse of the infinite Toeplitz matrix better.
+	Width, Height int
+	// Bandwidth of the output.
+	// Reduced to fit in the grid if necessary,
+	// separately in each dimension.
+	Band int
+	// Added to every eigenvalue before inversion.
+	Lambda float64
+	// Eigenvalues less than MinEig are set to MinEig before adding Lambda.
+	MinEig float64
+}
+
+// InvApprox computes a banded approximation to the inverse
+// of a stationary covariance.
+// The covariance is wrapped around a circulant grid,
+// the channels x channels block at each frequency is inverted
+// and the result is truncated to the output bandwidth.
+// Displacements which do not fit in the grid are discarded.
+func InvApprox(g *toepcov.Covar, opts InvApproxOpts) (*toepcov.Covar, error) {
+	return approxFunc(g, opts, func(x float64) float64 { return 1 / x })
+}
+
+// InvSqrtApprox is like InvApprox but computes
+// an approximation to the inverse square root.
+// The result is a whitening filter:
+// its Toeplitz matrix W satisfies W S W ~ I.
+func InvSqrtApprox(g *toepcov.Covar, opts InvApproxOpts) (*toepcov.Covar, error) {
+	return approxFunc(g, opts, func(x float64) float64 { return 1 / math.Sqrt(x) })
+}
+
+// approxFunc applies a function to the eigenvalues
+// of the circulant approximation to g.
+// The eigenvalues are thresholded and regularized first.
+func approxFunc(g *toepcov.Covar, opts InvApproxOpts, f func(float64) float64) (*toepcov.Covar, error) {
+	m, n := opts.Width, opts.Height
+	// Limit displacements in each dimension such that
+	// no two wrap to the same element of the grid.
+	// For even m, the displacements -m/2 and m/2 would coincide.
+	maxX, maxY := (m-1)/2, (n-1)/2
+	bxIn, byIn := min(g.Bandwidth, maxX), min(g.Bandwidth, maxY)
+	bandOut := min(opts.Band, max(maxX, maxY))
+	bxOut, byOut := min(bandOut, maxX), min(bandOut, maxY)
+	num := float64(m * n)
+	// Take Fourier transform of each channel pair.
+	a := make([][]*fftw.Array2, g.Channels)
+	for p := range a {
+		a[p] = make([]*fftw.Array2, g.Channels)
+		for q := range a[p] {
+			apq := fftw.NewArray2(m, n)
+			a[p][q] = apq
+			for u := -bxIn; u <= bxIn; u++ {
+				for v := -byIn; v <= byIn; v++ {
+					umod, vmod := mod(u, m), mod(v, n)
+					apq.Set(umod, vmod, apq.At(umod, vmod)+complex(g.At(u, v, p, q), 0))
+				}
+			}
+			fftw.FFT2To(apq, apq)
+		}
+	}
+	// Take inverse of each channels x channels block.
+	auv := cmat.New(g.Channels, g.Channels)
+	d := cmat.New(g.Channels, g.Channels)
+	for u := 0; u < m; u++ {
+		for v := 0; v < n; v++ {
+			// Over-write matrix.
+			for p := 0; p < g.Channels; p++ {
+				for q := 0; q < g.Channels; q++ {
+					auv.Set(p, q, a[p][q].At(u, v))
+				}
+			}
+			vecs, vals, err := clap.EigHerm(auv)
+			if err != nil {
+				return nil, err
+			}
+			for i := range vals {
+				if vals[i] < opts.MinEig {
+					vals[i] = opts.MinEig
+				}
+				vals[i] += opts.Lambda
+				d.Set(i, i, complex(f(vals[i]), 0))
+			}
+			inv := cmat.Mul(cmat.Mul(vecs, d), cmat.H(vecs))
+			for p := 0; p < g.Channels; p++ {
+				for q := 0; q < g.Channels; q++ {
+					a[p][q].Set(u, v, inv.At(p, q))
+				}
+			}
+		}
+	}
+	// Take inverse FFT of each channel pair.
+	prec := toepcov.NewCovar(g.Channels, bandOut)
+	for p := range a {
+		for q := range a[p] {
+			apq := a[p][q]
+			fftw.IFFT2To(apq, apq)
+			// Elements outside the grid in either dimension remain zero.
+			for u := -bxOut; u <= bxOut; u++ {
+				for v := -byOut; v <= byOut; v++ {
+					x := apq.At(mod(u, m), mod(v, n))
+					prec.Set(u, v, p, q, real(x)/num)
+				}
+			}
+		}
+	}
+	return prec, nil
+}
+
+// InvApproxErr measures the error of an approximate inverse
+// on a w x h template.
+// It returns the Frobenius norm of the difference between
+// the Toeplitz matrix of prec and the exact inverse of
+// the Toeplitz matrix of g plus lambda I,
+// relative to the Frobenius norm of the exact inverse.
+// This instantiates and inverts the full matrix and
+// should only be used for small sizes.
+func InvApproxErr(g, prec *toepcov.Covar, lambda float64, w, h int) (float64, error) {
+	s := g.Matrix(w, h)
+	k := w * h * g.Channels
+	for i := 0; i < k; i++ {
+		s.Set(i, i, s.At(i, i)+lambda)
+	}
+	fact, err := lapack.Chol(s)
+	if err != nil {
+		return 0, err
+	}
+	p := prec.Matrix(w, h)
+	var num, den float64
+	e := make([]float64, k)
+	for j := 0; j < k; j++ {
+		// Obtain j-th column of exact inverse.
+		e[j] = 1
+		x, err := fact.Solve(e)
+		e[j] = 0
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < k; i++ {
+			d := p.At(i, j) - x[i]
+			num += d * d
+			den += x[i] * x[i]
+		}
+	}
+	return math.Sqrt(num / den), nil
+}
@@ -0,0 +1,194 @@
+package circcov
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+)
+
+// Checks that the inverse is exact when there is no spatial correlation.
+func TestInvApprox_bandZero(t *testing.T) {
+	const (
+		width    = 5
+		height   = 4
+		channels = 3
+		lambda   = 1e-2
+		eps      = 1e-6
+	)
+
+	g := randCovar(channels, 2).CloneBandwidth(0)
+	opts := InvApproxOpts{Width: width, Height: height, Band: 2, Lambda: lambda}
+	prec, err := InvApprox(g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := InvApproxErr(g, prec, lambda, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e > eps {
+		t.Errorf("relative error: want <= %.3g, got %.3g", eps, e)
+	}
+}
+
+// Checks that thresholding all eigenvalues gives a scaled identity.
+func TestInvApprox_minEig(t *testing.T) {
+	const (
+		bandwidth = 3
+		channels  = 2
+		minEig    = 1e6
+		lambda    = 1
+	)
+
+	g := randCovar(channels, bandwidth)
+	opts := InvApproxOpts{Width: 9, Height: 8, Band: bandwidth, Lambda: lambda, MinEig: minEig}
+	prec, err := InvApprox(g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for u := -prec.Bandwidth; u <= prec.Bandwidth; u++ {
+		for v := -prec.Bandwidth; v <= prec.Bandwidth; v++ {
+			for p := 0; p < channels; p++ {
+				for q := 0; q < channels; q++ {
+					var want float64
+					if u == 0 && v == 0 && p == q {
+						want = 1 / (minEig + lambda)
+					}
+					if got := prec.At(u, v, p, q); !epsEq(want, got, eps) {
+						t.Errorf("at %d, %d, %d, %d: want %.6g, got %.6g", u, v, p, q, want, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Checks that the output is symmetric.
+func TestInvApprox_symm(t *testing.T) {
+	const (
+		bandwidth = 4
+		channels  = 3
+		lambda    = 1e-2
+	)
+
+	g := randCovar(channels, bandwidth)
+	opts := InvApproxOpts{Width: 16, Height: 12, Band: 5, Lambda: lambda}
+	prec, err := InvApprox(g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := prec.Bandwidth
+	for u := -b; u <= b; u++ {
+		for v := -b; v <= b; v++ {
+			for p := 0; p < channels; p++ {
+				for q := 0; q < channels; q++ {
+					want, got := prec.At(u, v, p, q), prec.At(-u, -v, q, p)
+					if !epsEq(want, got, eps) {
+						t.Errorf("at %d, %d, %d, %d: want %.6g, got %.6g", u, v, p, q, want, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Checks that a larger grid does not give a worse approximation.
+func TestInvApproxErr_grid(t *testing.T) {
+	const (
+		bandwidth = 2
+		width     = 4
+		height    = 3
+		channels  = 2
+		lambda    = 1
+	)
+
+	g := randCovar(channels, bandwidth)
+	var errs []float64
+	for _, k := range []int{1, 4} {
+		opts := InvApproxOpts{Width: k * width, Height: k * height, Band: width, Lambda: lambda}
+		prec, err := InvApprox(g, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := InvApproxErr(g, prec, lambda, width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("grid %dx%d: relative error %.3g", opts.Width, opts.Height, e)
+		errs = append(errs, e)
+	}
+	if errs[1] > errs[0] {
+		t.Errorf("error increased with grid size: %.3g > %.3g", errs[1], errs[0])
+	}
+}
+
+// Checks that the inverse on a small, even, non-square grid is
+// the exact inverse of the circulant matrix in which each displacement
+// occurs at most once and displacements beyond the grid are discarded.
+func TestInvApprox_evenGrid(t *testing.T) {
+	const (
+		bandwidth = 3
+		width     = 4
+		height    = 6
+		channels  = 2
+		lambda    = 1e-2
+	)
+	// Largest displacements which fit in the grid.
+	const bx, by = (width - 1) / 2, (height - 1) / 2
+
+	g := randCovar(channels, bandwidth)
+	// Truncating the bandwidth may give an indefinite matrix,
+	// whose negative eigenvalues InvApprox would clamp.
+	// Make it diagonally dominant to ensure that it is definite.
+	var sum float64
+	for u := -bandwidth; u <= bandwidth; u++ {
+		for v := -bandwidth; v <= bandwidth; v++ {
+			for p := 0; p < channels; p++ {
+				for q := 0; q < channels; q++ {
+					sum += math.Abs(g.At(u, v, p, q))
+				}
+			}
+		}
+	}
+	g.AddLambdaI(sum)
+	opts := InvApproxOpts{Width: width, Height: height, Band: bandwidth, Lambda: lambda}
+	prec, err := InvApprox(g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prec.Bandwidth != by {
+		t.Fatalf("bandwidth: want %d, got %d", by, prec.Bandwidth)
+	}
+
+	a := matrixBand(g, width, height, nil, bx, by)
+	for i := 0; i < width*height*channels; i++ {
+		a.Set(i, i, a.At(i, i)+lambda)
+	}
+	for p := 0; p < channels; p++ {
+		// Obtain the column of the inverse for pixel (0, 0) and channel p.
+		e := rimg64.NewMulti(width, height, channels)
+		e.Set(0, 0, p, 1)
+		x, err := matInvMulImage(a, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for u := -prec.Bandwidth; u <= prec.Bandwidth; u++ {
+			for v := -prec.Bandwidth; v <= prec.Bandwidth; v++ {
+				for q := 0; q < channels; q++ {
+					var want float64
+					if abs(u) <= bx && abs(v) <= by {
+						want = x.At(mod(u, width), mod(v, height), q)
+					}
+					if got := prec.At(u, v, p, q); !epsEq(want, got, eps) {
+						t.Errorf("at %d, %d, %d, %d: want %.6g, got %.6g", u, v, p, q, want, got)
+					}
+				}
+			}
+		}
+	}
+	// The approximation must also be usable on a template.
+	if _, err := InvApproxErr(g, prec, lambda, width, height); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -21,7 +21,10 @@ func TestInvMul_mul(t *testing.T) {
 	g := randCovar(channels, bandwidth)
 	f := randImage(width, height, channels)
 	gf := Mul(g, f)
-	got := InvMul(g, gf)
+	got, err := InvMul(g, gf)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if eq, msg := imagesEq(f, got); !eq {
 		t.Error(msg)
 	}
@@ -49,7 +52,10 @@ func TestInvMulMode_mul(t *testing.T) {
 	f := randImage(width, height, channels)
 	for _, mode := range modes {
 		gf := MulMode(g, f, mode.Func)
-		got := InvMulMode(g, gf, mode.Func)
+		got, err := InvMulMode(g, gf, mode.Func)
+		if err != nil {
+			t.Fatal(err)
+		}
 		if eq, msg := imagesEq(f, got); !eq {
 			t.Errorf(`mode "%s": %s`, mode.Name, msg)
 		}
@@ -70,7 +76,10 @@ func TestInvMul_vsMat(t *testing.T) {
 	f := randImage(width, height, channels)
 
 	// Solve in Fourier domain.
-	got := InvMul(g, f)
+	got, err := InvMul(g, f)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Solve in time domain.
 	A := Matrix(g, f.Width, f.Height)
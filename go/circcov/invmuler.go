@@ -17,10 +17,12 @@ import (
 // Stores one k x k factorization per pixel, where k is the number of channels.
 type InvMuler struct {
 	// Factorized matries.
-	// Fact[u][v] with 0 <= u < Width, 0 <= v < Height.
+	// Fact[u][v] with 0 <= u < M, 0 <= v < N.
 	Fact [][]*clap.CholFact
 	// Dimensions of image.
 	Width, Height, Channels int
+	// Dimensions of circulant grid.
+	M, N int
 }
 
 // Init does pre-computation for multiplying by the inverse covariance matrix.
@@ -29,23 +31,31 @@ type InvMuler struct {
 // Total time is O(mnk^2 (k+log(mn))).
 // Returns an error if any of the complex systems are not positive definite.
 func (op *InvMuler) Init(g *toepcov.Covar, w, h int) error {
+	return op.InitEmbed(g, w, h, Embed{Coeffs: Convex})
+}
+
+// InitEmbed is like Init but the image is embedded in a circulant grid.
+// Images are padded with zeros and solutions are cropped to the size of the image.
+func (op *InvMuler) InitEmbed(g *toepcov.Covar, w, h int, e Embed) error {
 	op.Width = w
 	op.Height = h
 	op.Channels = g.Channels
+	op.M, op.N = e.Grid(w, h)
+	bx, by := e.band(g, w, h)
 
 	// Compute the Fourier transform of every channel pair.
 	gHat := make([][]*fftw.Array2, g.Channels)
 	for p := range gHat {
 		gHat[p] = make([]*fftw.Array2, g.Channels)
 		for q := range gHat[p] {
-			gHat[p][q] = dftCovarCirc(g, w, h, p, q, Convex)
+			gHat[p][q] = dftCovarCircBand(g, op.M, op.N, p, q, e.coeffs(), bx, by)
 		}
 	}
 
 	// Compute factorizations.
-	op.Fact = make([][]*clap.CholFact, w)
+	op.Fact = make([][]*clap.CholFact, op.M)
 	for u := range op.Fact {
-		op.Fact[u] = make([]*clap.CholFact, h)
+		op.Fact[u] = make([]*clap.CholFact, op.N)
 		for v := range op.Fact[u] {
 			a := cmat.New(g.Channels, g.Channels)
 			for p := 0; p < g.Channels; p++ {
@@ -88,20 +98,55 @@ func (op *InvMuler) Mul(f *rimg64.Multi) *rimg64.Multi {
 			f.Width, f.Height,
 		))
 	}
+	return op.mul(f)
+}
+
+// MulContext is like Mul but f contains the template surrounded by
+// a margin of context on every side, which replaces the zero padding.
+// The image is wrapped around the grid such that the template
+// occupies the top-left corner, therefore the template plus margin
+// must not be larger than the grid.
+// The solution is cropped to the template.
+func (op *InvMuler) MulContext(f *rimg64.Multi, margin int) *rimg64.Multi {
+	if f.Channels != op.Channels {
+		panic(fmt.Sprintf(
+			"bad number of channels: covar %d, image %d",
+			op.Channels, f.Channels,
+		))
+	}
+	if f.Width != op.Width+2*margin || f.Height != op.Height+2*margin {
+		panic(fmt.Sprintf(
+			"bad dimensions: operator %dx%d with margin %d, image %dx%d",
+			op.Width, op.Height, margin,
+			f.Width, f.Height,
+		))
+	}
+	if f.Width > op.M || f.Height > op.N {
+		panic(fmt.Sprintf(
+			"image larger than grid: grid %dx%d, image %dx%d",
+			op.M, op.N,
+			f.Width, f.Height,
+		))
+	}
+	return op.mul(wrapImage(f, margin, op.M, op.N))
+}
 
+// mul takes an image which is at most the size of the grid
+// and returns the solution cropped to the template.
+func (op *InvMuler) mul(f *rimg64.Multi) *rimg64.Multi {
 	fHat := make([]*fftw.Array2, op.Channels)
 	for p := 0; p < op.Channels; p++ {
-		fHat[p] = dftChannel(f, p, f.Width, f.Height)
+		fHat[p] = dftChannel(f, p, op.M, op.N)
 	}
 	xHat := make([]*fftw.Array2, op.Channels)
 	for p := 0; p < op.Channels; p++ {
-		xHat[p] = fftw.NewArray2(f.Width, f.Height)
+		xHat[p] = fftw.NewArray2(op.M, op.N)
 	}
 
 	// Solve a channels x channels system per pixel.
-	N := float64(op.Width) * float64(op.Height)
-	for u := 0; u < f.Width; u++ {
-		for v := 0; v < f.Height; v++ {
+	N := float64(op.M) * float64(op.N)
+	for u := 0; u < op.M; u++ {
+		for v := 0; v < op.N; v++ {
 			y := make([]complex128, op.Channels)
 			for p := 0; p < op.Channels; p++ {
 				y[p] = fHat[p].At(u, v)
@@ -117,7 +162,7 @@ func (op *InvMuler) Mul(f *rimg64.Multi) *rimg64.Multi {
 	}
 
 	// Take inverse transform of each channel.
-	x := rimg64.NewMulti(f.Width, f.Height, op.Channels)
+	x := rimg64.NewMulti(op.Width, op.Height, op.Channels)
 	for p := 0; p < op.Channels; p++ {
 		idftToChannel(x, p, xHat[p])
 	}
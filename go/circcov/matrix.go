@@ -18,6 +18,12 @@ func Matrix(g *toepcov.Covar, m, n int) *mat.Mat {
 // The manner in which the circulant covariance is formed is
 // determined by the coefficients function.
 func MatrixMode(g *toepcov.Covar, m, n int, coeffs CoeffsFunc) *mat.Mat {
+	return matrixBand(g, m, n, coeffs, g.Bandwidth, g.Bandwidth)
+}
+
+// matrixBand constructs the full m x n circulant covariance matrix.
+// Displacements greater than bx or by are treated as zero.
+func matrixBand(g *toepcov.Covar, m, n int, coeffs CoeffsFunc, bx, by int) *mat.Mat {
 	c := g.Channels
 	s := mat.New(m*n*c, m*n*c)
 	// Populate matrix.
@@ -28,14 +34,7 @@ func MatrixMode(g *toepcov.Covar, m, n int, coeffs CoeffsFunc) *mat.Mat {
 					for j := 0; j < n; j++ {
 						for q := 0; q < c; q++ {
 							du, dv := i-u, j-v
-							a, b := coeffs(du, dv, m, n)
-
-							var h float64
-							h += (1 - a) * (1 - b) * g.At(mod(du, m), mod(dv, n), p, q)
-							h += (1 - a) * b * g.At(mod(du, m), -mod(-dv, n), p, q)
-							h += a * (1 - b) * g.At(-mod(-du, m), mod(dv, n), p, q)
-							h += a * b * g.At(-mod(-du, m), -mod(-dv, n), p, q)
-
+							h := circAt(g, du, dv, m, n, p, q, coeffs, bx, by)
 							row := (u*n+v)*c + p
 							col := (i*n+j)*c + q
 							s.Set(row, col, h)
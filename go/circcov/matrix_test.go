@@ -1,6 +1,9 @@
 package circcov
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestMatrix_symm(t *testing.T) {
 	const (
@@ -41,3 +44,36 @@ func TestMatrixMode_symm(t *testing.T) {
 		}
 	}
 }
+
+// Checks that Nearest selects the nearest displacement on odd grids
+// and only mixes the two displacements on even grids.
+func TestMatrixMode_nearest(t *testing.T) {
+	const (
+		channels  = 1
+		bandwidth = 6
+	)
+	cases := []struct {
+		M  int
+		Du int
+		// Weights of displacements du and du-m.
+		Pos, Neg float64
+	}{
+		{5, 1, 1, 0},
+		{5, 2, 1, 0},
+		{5, 3, 0, 1},
+		{5, 4, 0, 1},
+		{4, 1, 1, 0},
+		{4, 2, 0.5, 0.5},
+		{4, 3, 0, 1},
+	}
+
+	cov := randCovar(channels, bandwidth)
+	for _, c := range cases {
+		// Use a grid of height one to consider displacements in x alone.
+		a := MatrixMode(cov, c.M, 1, Nearest)
+		want := c.Pos*cov.At(c.Du, 0, 0, 0) + c.Neg*cov.At(c.Du-c.M, 0, 0, 0)
+		if got := a.At(0, c.Du); math.Abs(want-got) > 1e-9 {
+			t.Errorf("m %d, du %d: want %.6g, got %.6g", c.M, c.Du, want, got)
+		}
+	}
+}
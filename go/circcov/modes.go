@@ -40,10 +40,11 @@ func Nearest(du, dv, m, n int) (float64, float64) {
 //	0.5 if du/m = 0.5
 func stepFrac(du, m int) float64 {
 	du = mod(du, m)
+	// Compare 2*du to m so that there is no tie when m is odd.
 	switch {
-	case du < m/2:
+	case 2*du < m:
 		return 0
-	case du > m/2:
+	case 2*du > m:
 		return 1
 	default:
 		return 0.5
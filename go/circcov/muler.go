@@ -19,21 +19,31 @@ type Muler struct {
 	GHat [][]*fftw.Array2
 	// Dimensions of image.
 	Width, Height, Channels int
+	// Dimensions of circulant grid.
+	M, N int
 }
 
 // Init does pre-computation for multiplying by the covariance matrix.
 // It takes k^2 transforms in O(mnk^2 log(mn)) time.
 func (op *Muler) Init(g *toepcov.Covar, w, h int) {
+	op.InitEmbed(g, w, h, Embed{Coeffs: Convex})
+}
+
+// InitEmbed is like Init but the image is embedded in a circulant grid.
+// Products are cropped to the size of the image.
+func (op *Muler) InitEmbed(g *toepcov.Covar, w, h int, e Embed) {
 	op.Width = w
 	op.Height = h
 	op.Channels = g.Channels
+	op.M, op.N = e.Grid(w, h)
+	bx, by := e.band(g, w, h)
 
 	// Compute the Fourier transform of every channel pair.
 	op.GHat = make([][]*fftw.Array2, g.Channels)
 	for p := range op.GHat {
 		op.GHat[p] = make([]*fftw.Array2, g.Channels)
 		for q := range op.GHat[p] {
-			op.GHat[p][q] = dftCovarCirc(g, w, h, p, q, Convex)
+			op.GHat[p][q] = dftCovarCircBand(g, op.M, op.N, p, q, e.coeffs(), bx, by)
 		}
 	}
 }
@@ -59,16 +69,16 @@ func (op *Muler) Mul(f *rimg64.Multi) *rimg64.Multi {
 
 	fHat := make([]*fftw.Array2, op.Channels)
 	for p := 0; p < op.Channels; p++ {
-		fHat[p] = dftChannel(f, p, f.Width, f.Height)
+		fHat[p] = dftChannel(f, p, op.M, op.N)
 	}
 	xHat := make([]*fftw.Array2, op.Channels)
 	for p := 0; p < op.Channels; p++ {
-		xHat[p] = fftw.NewArray2(f.Width, f.Height)
+		xHat[p] = fftw.NewArray2(op.M, op.N)
 	}
 
-	n := float64(f.Width * f.Height)
-	for u := 0; u < f.Width; u++ {
-		for v := 0; v < f.Height; v++ {
+	n := float64(op.M * op.N)
+	for u := 0; u < op.M; u++ {
+		for v := 0; v < op.N; v++ {
 			for p := 0; p < f.Channels; p++ {
 				var total complex128
 				for q := 0; q < f.Channels; q++ {
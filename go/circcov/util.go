@@ -7,6 +7,20 @@ func abs(a int) int {
 	return a
 }
 
+func min(a, b int) int {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+func max(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
+
 func mod(a, b int) int {
 	if b <= 0 {
 		panic("non-positive mod")
@@ -0,0 +1,31 @@
+package circcov
+
+import (
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Whitener applies a stationary whitening transform to feature images.
+type Whitener struct {
+	// Mean to subtract from every pixel.
+	Mean []float64
+	// Approximate inverse square root of the covariance.
+	Filter *toepcov.Covar
+}
+
+// NewWhitener constructs the whitening filter bank of a distribution.
+// The filter is computed by InvSqrtApprox.
+func NewWhitener(distr *toepcov.Distr, opts InvApproxOpts) (*Whitener, error) {
+	filter, err := InvSqrtApprox(distr.Covar, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Whitener{Mean: distr.Mean, Filter: filter}, nil
+}
+
+// Apply subtracts the mean and applies the filter bank to an image
+// of any size.
+// The image is treated as zero-mean beyond its boundary.
+func (w *Whitener) Apply(f *rimg64.Multi) *rimg64.Multi {
+	return toepcov.MulFFT(w.Filter, toepcov.SubMean(f, w.Mean))
+}
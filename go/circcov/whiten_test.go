@@ -0,0 +1,69 @@
+package circcov
+
+import (
+	"testing"
+
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Checks that W (S + lambda I) W = I when there is no spatial correlation.
+func TestInvSqrtApprox_bandZero(t *testing.T) {
+	const (
+		width    = 4
+		height   = 3
+		channels = 3
+		lambda   = 1e-2
+		eps      = 1e-6
+	)
+
+	g := randCovar(channels, 2).CloneBandwidth(0)
+	opts := InvApproxOpts{Width: width, Height: height, Band: 1, Lambda: lambda}
+	filter, err := InvSqrtApprox(g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := g.Matrix(width, height)
+	n := width * height * channels
+	for i := 0; i < n; i++ {
+		s.Set(i, i, s.At(i, i)+lambda)
+	}
+	w := filter.Matrix(width, height)
+	got := mat.Mul(mat.Mul(w, s), w)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			var want float64
+			if i == j {
+				want = 1
+			}
+			if !epsEq(want, got.At(i, j), eps) {
+				t.Fatalf("at %d, %d: want %.6g, got %.6g", i, j, want, got.At(i, j))
+			}
+		}
+	}
+}
+
+// Checks that an image equal to the mean is mapped to zero.
+func TestWhitener_mean(t *testing.T) {
+	const (
+		bandwidth = 3
+		width     = 20
+		height    = 15
+		channels  = 4
+	)
+
+	distr := &toepcov.Distr{
+		Mean:  randImage(1, 1, channels).Elems,
+		Covar: randCovar(channels, bandwidth),
+	}
+	whitener, err := NewWhitener(distr, InvApproxOpts{Width: 16, Height: 16, Band: bandwidth, Lambda: 1e-2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := toepcov.ConstImage(width, height, distr.Mean)
+	got := whitener.Apply(f)
+	want := toepcov.ConstImage(width, height, make([]float64, channels))
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import (
+	_ "github.com/jvlmdr/go-cv/hog"
+	_ "github.com/jvlmdr/shift-invar/go/whiten"
+)
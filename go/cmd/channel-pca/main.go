@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/jvlmdr/go-cv/featset"
+	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/imset"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "[flags] images.txt feat.json transform.json")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Fits the projection of feature channels onto their principal components.")
+		fmt.Fprintln(os.Stderr, `The result can be given to cmd/stats (-channel-transform) and to the "channel-affine" feature.`)
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	var (
+		dir = flag.String("images-dir", "", "Directory to which paths in images.txt are relative.")
+		dim = flag.Int("dim", 0, "Number of principal components to keep (0 for all).")
+	)
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var (
+		imsFile   = flag.Arg(0)
+		featFile  = flag.Arg(1)
+		transFile = flag.Arg(2)
+	)
+
+	phi := new(featset.ImageMarshaler)
+	if err := fileutil.LoadJSON(featFile, phi); err != nil {
+		log.Fatalln("load feature:", err)
+	}
+	ims, err := fileutil.LoadLines(imsFile)
+	if err != nil {
+		log.Fatalln("load image list:", err)
+	}
+	channels := phi.Transform().Channels()
+	k := *dim
+	if k <= 0 {
+		k = channels
+	}
+
+	m := vecset.NewMoments(channels)
+	for i, file := range ims {
+		log.Printf("image %d of %d: %s", i+1, len(ims), file)
+		im, err := loadImage(path.Join(*dir, file))
+		if err != nil {
+			log.Fatalln("load image:", err)
+		}
+		f, err := phi.Transform().Apply(im)
+		if err != nil {
+			log.Fatalln("compute features:", err)
+		}
+		imset.AddPixels(m, f)
+	}
+	transform, vars, err := m.PCA(k)
+	if err != nil {
+		log.Fatalln("fit pca:", err)
+	}
+
+	var total float64
+	covar := m.Covar()
+	for i := 0; i < channels; i++ {
+		total += covar.At(i, i)
+	}
+	var cum float64
+	for i, v := range vars {
+		cum += v
+		log.Printf("component %d: variance %.4g, cumulative fraction %.4f", i+1, v, cum/total)
+	}
+	if err := fileutil.SaveJSON(transFile, transform); err != nil {
+		log.Fatalln("save channel transform:", err)
+	}
+}
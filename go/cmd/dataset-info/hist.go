@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Hist is a histogram with uniformly spaced bins.
+// Values outside [Min, Max) are counted in Under and Over.
+type Hist struct {
+	Min, Max float64
+	Counts   []int
+	Under    int
+	Over     int
+}
+
+func NewHist(min, max float64, bins int) *Hist {
+	return &Hist{Min: min, Max: max, Counts: make([]int, bins)}
+}
+
+func (h *Hist) Add(x float64) {
+	if x < h.Min {
+		h.Under++
+		return
+	}
+	if x >= h.Max {
+		h.Over++
+		return
+	}
+	i := int(math.Floor((x - h.Min) / (h.Max - h.Min) * float64(len(h.Counts))))
+	if i >= len(h.Counts) {
+		i = len(h.Counts) - 1
+	}
+	h.Counts[i]++
+}
+
+// Total returns the number of values which have been added.
+func (h *Hist) Total() int {
+	n := h.Under + h.Over
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// Edge returns the lower limit of bin i.
+func (h *Hist) Edge(i int) float64 {
+	return h.Min + float64(i)*(h.Max-h.Min)/float64(len(h.Counts))
+}
+
+// Print writes the histogram as a bar chart.
+func (h *Hist) Print(w io.Writer, width int) {
+	var max int
+	for _, c := range h.Counts {
+		if c > max {
+			max = c
+		}
+	}
+	if h.Under > 0 {
+		fmt.Fprintf(w, "  %10s %8s: %d\n", "<", fmtNum(h.Min), h.Under)
+	}
+	for i, c := range h.Counts {
+		var n int
+		if max > 0 {
+			n = int(0.5 + float64(width*c)/float64(max))
+		}
+		fmt.Fprintf(w, "  [%8s, %8s): %6d %s\n", fmtNum(h.Edge(i)), fmtNum(h.Edge(i+1)), c, strings.Repeat("#", n))
+	}
+	if h.Over > 0 {
+		fmt.Fprintf(w, "  %10s %8s: %d\n", ">=", fmtNum(h.Max), h.Over)
+	}
+}
+
+func fmtNum(x float64) string {
+	return fmt.Sprintf("%.3g", x)
+}
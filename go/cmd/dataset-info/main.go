@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/shift-invar/go/data"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "[flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Summarizes a dataset and checks it for problems.")
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	var (
+		datasetName = flag.String("dataset", "", fmt.Sprint(data.ListDatasets()))
+		datasetSpec = flag.String("dataset-spec", "", "Dataset parameters (JSON)")
+		jsonOut     = flag.Bool("json", false, "Write report to stdout as JSON")
+		bins        = flag.Int("bins", 10, "Number of bins in each histogram")
+		decode      = flag.Bool("decode", false, "Decode every image in full to check that it is readable (slow)")
+		// Options which determine which positive examples are kept.
+		width        = flag.Int("width", 32, "Example width before padding")
+		height       = flag.Int("height", 96, "Example height before padding")
+		pad          = flag.Int("pad", 0, "Dilate bounding box to obtain region from which features are extracted")
+		aspectReject = flag.Float64("reject-aspect", 0, "Reject examples not between r and 1/r times aspect ratio")
+		resizeFor    = flag.String("resize-for", "area", "One of {area, width, height, fit, fill}")
+		maxScale     = flag.Float64("max-train-scale", 2, "Discount examples which would need to be scaled more than this")
+		margin       = flag.Int("margin", 0, "Margin to add to image before taking features")
+	)
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	dataset, err := data.Load(*datasetName, *datasetSpec)
+	if err != nil {
+		log.Fatalln("load dataset:", err)
+	}
+
+	size := image.Pt(*width, *height)
+	region := detect.PadRect{
+		Size: image.Pt(size.X+(*pad)*2, size.Y+(*pad)*2),
+		Int:  image.Rectangle{image.ZP, size}.Add(image.Pt((*pad), (*pad))),
+	}
+	exampleOpts := data.ExampleOpts{
+		AspectReject: *aspectReject,
+		FitMode:      *resizeFor,
+		MaxScale:     *maxScale,
+	}
+
+	report, err := summarize(dataset, *bins, *decode, feat.UniformMargin(*margin), region, exampleOpts)
+	if err != nil {
+		log.Fatalln("summarize dataset:", err)
+	}
+	report.Name = *datasetName
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		if err := enc.Encode(report); err != nil {
+			log.Fatalln("encode report:", err)
+		}
+		return
+	}
+	report.Print()
+}
+
+// Report summarizes the contents of a dataset.
+type Report struct {
+	Name string
+	// Number of images by role.
+	Images   int
+	Train    int
+	Test     int
+	Neg      int
+	TrainPos int
+	TrainNeg int
+	TestPos  int
+	TestNeg  int
+	// Number of annotated regions.
+	Instances int
+	Ignore    int
+	// Distribution of instance height (pixels),
+	// instance aspect ratio (width over height)
+	// and fraction of each image covered by ignore regions.
+	Height      *Hist
+	Aspect      *Hist
+	IgnoreCover *Hist
+	// Problems found in the dataset.
+	Unreadable  []Unreadable
+	OutOfBounds []OutOfBounds
+	// Positive examples which would be used for training.
+	Examples ExampleCount
+}
+
+// Unreadable describes an image which could not be decoded.
+type Unreadable struct {
+	Image string
+	Err   string
+}
+
+// OutOfBounds describes an annotation which is not contained in its image.
+type OutOfBounds struct {
+	Image  string
+	Rect   image.Rectangle
+	Size   image.Point
+	Ignore bool
+}
+
+// ExampleCount gives the number of positive examples
+// which are kept and excluded from the training images.
+type ExampleCount struct {
+	Valid    int
+	Excluded data.ExcludeCount
+}
+
+func summarize(dataset data.ImageSet, bins int, decode bool, margin feat.Margin, region detect.PadRect, opts data.ExampleOpts) (*Report, error) {
+	r := &Report{
+		Height:      NewHist(0, 512, bins),
+		Aspect:      NewHist(0, 2, bins),
+		IgnoreCover: NewHist(0, 1, bins),
+	}
+	ims := data.CachesOf(dataset).Images
+	var posIms []string
+	for _, im := range dataset.Images() {
+		r.Images++
+		neg := dataset.IsNeg(im)
+		if neg {
+			r.Neg++
+		}
+		if dataset.CanTrain(im) {
+			r.Train++
+			if neg {
+				r.TrainNeg++
+			} else {
+				r.TrainPos++
+			}
+		}
+		if dataset.CanTest(im) {
+			r.Test++
+			if neg {
+				r.TestNeg++
+			} else {
+				r.TestPos++
+			}
+		}
+
+		annot := dataset.Annot(im)
+		r.Instances += len(annot.Instances)
+		r.Ignore += len(annot.Ignore)
+		for _, obj := range annot.Instances {
+			r.Height.Add(float64(obj.Dy()))
+			if obj.Dy() > 0 {
+				r.Aspect.Add(float64(obj.Dx()) / float64(obj.Dy()))
+			}
+		}
+
+		size, err := imageSize(ims, dataset.File(im), decode)
+		if err != nil {
+			r.Unreadable = append(r.Unreadable, Unreadable{im, err.Error()})
+			continue
+		}
+		bounds := image.Rectangle{image.ZP, size}
+		for _, obj := range annot.Instances {
+			if !obj.In(bounds) {
+				r.OutOfBounds = append(r.OutOfBounds, OutOfBounds{im, obj, size, false})
+			}
+		}
+		for _, obj := range annot.Ignore {
+			if !obj.In(bounds) {
+				r.OutOfBounds = append(r.OutOfBounds, OutOfBounds{im, obj, size, true})
+			}
+		}
+		if area := size.X * size.Y; area > 0 {
+			r.IgnoreCover.Add(float64(unionArea(annot.Ignore, bounds)) / float64(area))
+		}
+		if !neg && dataset.CanTrain(im) {
+			posIms = append(posIms, im)
+		}
+	}
+
+	// Only consider images which could be read.
+	valid, excl, err := data.PosExampleCount(posIms, dataset, margin, region, opts)
+	if err != nil {
+		return nil, err
+	}
+	r.Examples = ExampleCount{valid, excl}
+	return r, nil
+}
+
+func (r *Report) Print() {
+	fmt.Printf("dataset: %s\n", r.Name)
+	fmt.Printf("images: %d (neg: %d)\n", r.Images, r.Neg)
+	fmt.Printf("train: %d (pos: %d, neg: %d)\n", r.Train, r.TrainPos, r.TrainNeg)
+	fmt.Printf("test: %d (pos: %d, neg: %d)\n", r.Test, r.TestPos, r.TestNeg)
+	fmt.Printf("instances: %d, ignore regions: %d\n", r.Instances, r.Ignore)
+	fmt.Println()
+	fmt.Println("instance height:")
+	r.Height.Print(os.Stdout, 40)
+	fmt.Println("instance aspect ratio:")
+	r.Aspect.Print(os.Stdout, 40)
+	fmt.Println("ignore region coverage:")
+	r.IgnoreCover.Print(os.Stdout, 40)
+	fmt.Println()
+	excl := r.Examples.Excluded
+	fmt.Printf(
+		"training examples: valid: %d, bad aspect: %d, too small: %d, not inside: %d\n",
+		r.Examples.Valid, excl.BadAspect, excl.TooSmall, excl.NotInside,
+	)
+	fmt.Printf("unreadable images: %d\n", len(r.Unreadable))
+	for _, x := range r.Unreadable {
+		fmt.Printf("  %s: %s\n", x.Image, x.Err)
+	}
+	fmt.Printf("boxes outside image: %d\n", len(r.OutOfBounds))
+	for _, x := range r.OutOfBounds {
+		kind := "instance"
+		if x.Ignore {
+			kind = "ignore"
+		}
+		fmt.Printf("  %s: %s %v outside %dx%d\n", x.Image, kind, x.Rect, x.Size.X, x.Size.Y)
+	}
+}
+
+// imageSize reads only the header of the image unless decode is true.
+// Truncated or corrupt images are only detected by decoding them.
+func imageSize(ims data.ImageProvider, fname string, decode bool) (image.Point, error) {
+	if !decode {
+		return ims.Size(fname)
+	}
+	x, err := ims.Image(fname)
+	if err != nil {
+		return image.ZP, err
+	}
+	return x.Bounds().Size(), nil
+}
+
+// unionArea returns the number of pixels within lims
+// which are covered by at least one rectangle.
+func unionArea(rects []image.Rectangle, lims image.Rectangle) int {
+	var clip []image.Rectangle
+	for _, r := range rects {
+		if r = r.Intersect(lims); !r.Empty() {
+			clip = append(clip, r)
+		}
+	}
+	if len(clip) == 0 {
+		return 0
+	}
+	// Sweep over columns between consecutive x coordinates.
+	xs := make([]int, 0, 2*len(clip))
+	for _, r := range clip {
+		xs = append(xs, r.Min.X, r.Max.X)
+	}
+	sort.Ints(xs)
+	var area int
+	for i := 0; i+1 < len(xs); i++ {
+		a, b := xs[i], xs[i+1]
+		if a == b {
+			continue
+		}
+		var ivals [][2]int
+		for _, r := range clip {
+			if r.Min.X <= a && b <= r.Max.X {
+				ivals = append(ivals, [2]int{r.Min.Y, r.Max.Y})
+			}
+		}
+		area += (b - a) * coverLen(ivals)
+	}
+	return area
+}
+
+// coverLen returns the total length of the union of intervals.
+func coverLen(ivals [][2]int) int {
+	var n int
+	end := math.MinInt32
+	sort.Sort(byStart(ivals))
+	for _, v := range ivals {
+		if v[0] > end {
+			end = v[0]
+		}
+		if v[1] > end {
+			n += v[1] - end
+			end = v[1]
+		}
+	}
+	return n
+}
+
+type byStart [][2]int
+
+func (s byStart) Len() int           { return len(s) }
+func (s byStart) Less(i, j int) bool { return s[i][0] < s[j][0] }
+func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jvlmdr/shift-invar/go/data"
+)
+
+func TestUnionArea(t *testing.T) {
+	lims := image.Rect(0, 0, 10, 10)
+	cases := []struct {
+		Rects []image.Rectangle
+		Want  int
+	}{
+		{nil, 0},
+		{[]image.Rectangle{image.Rect(0, 0, 2, 3)}, 6},
+		// Overlapping rectangles.
+		{[]image.Rectangle{image.Rect(0, 0, 4, 4), image.Rect(2, 2, 6, 6)}, 28},
+		// Nested rectangles.
+		{[]image.Rectangle{image.Rect(0, 0, 4, 4), image.Rect(1, 1, 2, 2)}, 16},
+		// Clipped to limits.
+		{[]image.Rectangle{image.Rect(-5, -5, 5, 5)}, 25},
+	}
+	for _, c := range cases {
+		if got := unionArea(c.Rects, lims); got != c.Want {
+			t.Errorf("rects %v: want %d, got %d", c.Rects, c.Want, got)
+		}
+	}
+}
+
+func TestHist(t *testing.T) {
+	h := NewHist(0, 1, 4)
+	for _, x := range []float64{-1, 0, 0.3, 0.5, 0.99, 1, 2} {
+		h.Add(x)
+	}
+	want := []int{1, 1, 1, 1}
+	for i := range want {
+		if h.Counts[i] != want[i] {
+			t.Errorf("bin %d: want %d, got %d", i, want[i], h.Counts[i])
+		}
+	}
+	if h.Under != 1 || h.Over != 2 {
+		t.Errorf("want under 1 and over 2, got %d and %d", h.Under, h.Over)
+	}
+	if h.Total() != 7 {
+		t.Errorf("want total 7, got %d", h.Total())
+	}
+}
+
+// Checks that a truncated image is only found to be unreadable
+// if it is decoded in full.
+func TestImageSize_truncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dataset-info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(dir, "truncated.png")
+	if err := ioutil.WriteFile(fname, buf.Bytes()[:buf.Len()/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := imageSize(data.DiskImages{}, fname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Pt(40, 30); !size.Eq(want) {
+		t.Errorf("want %v, got %v", want, size)
+	}
+	if _, err := imageSize(data.DiskImages{}, fname, true); err == nil {
+		t.Error("decode: expected error")
+	}
+}
@@ -21,7 +21,7 @@ func init() {
 
 func main() {
 	var (
-		datasetName = flag.String("dataset", "", "{inria, caltech}")
+		datasetName = flag.String("dataset", "", fmt.Sprint(data.ListDatasets()))
 		datasetSpec = flag.String("dataset-spec", "", "Dataset parameters (JSON)")
 	)
 	flag.Parse()
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jvlmdr/shift-invar/go/data"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "[flags] manifest.(csv|jsonl)")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Exports a dataset to a manifest which can be loaded as the \"manifest\" dataset.")
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	var (
+		datasetName = flag.String("dataset", "", fmt.Sprint(data.ListDatasets()))
+		datasetSpec = flag.String("dataset-spec", "", "Dataset parameters (JSON)")
+		dir         = flag.String("dir", ".", "Directory to which paths in the manifest are relative")
+	)
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	fname := flag.Arg(0)
+
+	dataset, err := data.Load(*datasetName, *datasetSpec)
+	if err != nil {
+		log.Fatalln("load dataset:", err)
+	}
+	log.Printf("export %d images", len(dataset.Images()))
+	if err := data.SaveManifest(fname, dataset, *dir); err != nil {
+		log.Fatalln("save manifest:", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jvlmdr/go-cv/featset"
+	"github.com/jvlmdr/go-file/fileutil"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "[flags] images.txt feat.json out-dir")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Saves the feature image of every image.")
+		fmt.Fprintln(os.Stderr, "The directories in images.txt are kept under out-dir.")
+		fmt.Fprintln(os.Stderr, `Use the "whiten" feature to export whitened features.`)
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	var (
+		dir = flag.String("images-dir", "", "Directory to which paths in images.txt are relative.")
+		ext = flag.String("ext", "gob", "Format of feature images {gob, json}")
+	)
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var (
+		imsFile  = flag.Arg(0)
+		featFile = flag.Arg(1)
+		outDir   = flag.Arg(2)
+	)
+
+	phi := new(featset.ImageMarshaler)
+	if err := fileutil.LoadJSON(featFile, phi); err != nil {
+		log.Fatalln("load feature:", err)
+	}
+	ims, err := fileutil.LoadLines(imsFile)
+	if err != nil {
+		log.Fatalln("load image list:", err)
+	}
+	for i, file := range ims {
+		log.Printf("image %d of %d: %s", i+1, len(ims), file)
+		im, err := loadImage(path.Join(*dir, file))
+		if err != nil {
+			log.Fatalln("load image:", err)
+		}
+		f, err := phi.Transform().Apply(im)
+		if err != nil {
+			log.Fatalln("compute features:", err)
+		}
+		log.Printf("feature image: %d x %d x %d", f.Width, f.Height, f.Channels)
+		outFile, err := outputFile(outDir, file, *ext)
+		if err != nil {
+			log.Fatalln("output file:", err)
+		}
+		if err := os.MkdirAll(path.Dir(outFile), 0755); err != nil {
+			log.Fatalln("create output dir:", err)
+		}
+		if err := fileutil.SaveExt(outFile, f); err != nil {
+			log.Fatalln("save features:", err)
+		}
+	}
+}
+
+// outputFile replaces the extension of the image path
+// and places it under the output directory.
+// Images in different directories with the same name
+// (e.g. set00/V000/I00029.jpg and set00/V001/I00029.jpg)
+// therefore do not overwrite each other.
+// Absolute paths are placed under the output directory as well.
+func outputFile(outDir, file, ext string) (string, error) {
+	rel := strings.TrimPrefix(path.Clean(strings.TrimSuffix(file, path.Ext(file))), "/")
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf(`image path is outside images dir: "%s"`, file)
+	}
+	return path.Join(outDir, rel+"."+ext), nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestOutputFile(t *testing.T) {
+	cases := []struct {
+		File string
+		Want string
+	}{
+		{"set00/V000/I00029.jpg", "out/set00/V000/I00029.gob"},
+		{"set00/V001/I00029.jpg", "out/set00/V001/I00029.gob"},
+		{"a.png", "out/a.gob"},
+		{"/data/a.png", "out/data/a.gob"},
+	}
+	for _, c := range cases {
+		got, err := outputFile("out", c.File, "gob")
+		if err != nil {
+			t.Errorf("%s: %v", c.File, err)
+			continue
+		}
+		if got != c.Want {
+			t.Errorf("%s: want %s, got %s", c.File, c.Want, got)
+		}
+	}
+	if _, err := outputFile("out", "../a.png", "gob"); err == nil {
+		t.Error("path outside images dir: expected error")
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/jvlmdr/go-cv/feat"
 	"github.com/jvlmdr/go-cv/featset"
 	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/imset"
 	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/jvlmdr/shift-invar/go/vecset"
 )
 
 func init() {
@@ -24,6 +26,7 @@ func main() {
 	var (
 		dir  = flag.String("images-dir", "", "Directory to which paths in images.txt are relative.")
 		band = flag.Int("bandwidth", 16, "Covariance bandwidth (in feature pixels not image pixels).")
+		proj = flag.String("channel-transform", "", "Affine transform of channels to apply before computing stats (JSON as written by channel-pca, optional).")
 	)
 	flag.Parse()
 	if flag.NArg() != 3 {
@@ -44,7 +47,14 @@ func main() {
 	if err != nil {
 		log.Fatalln("load image list:", err)
 	}
-	total, err := totalStats(ims, *dir, phi, *band)
+	var transform *vecset.Affine
+	if *proj != "" {
+		transform = new(vecset.Affine)
+		if err := fileutil.LoadJSON(*proj, transform); err != nil {
+			log.Fatalln("load channel transform:", err)
+		}
+	}
+	total, err := totalStats(ims, *dir, phi, transform, *band)
 	if err != nil {
 		log.Fatalln("compute stats:", err)
 	}
@@ -54,11 +64,11 @@ func main() {
 	}
 }
 
-func totalStats(files []string, dir string, phi feat.Image, band int) (*toepcov.Total, error) {
+func totalStats(files []string, dir string, phi feat.Image, transform *vecset.Affine, band int) (*toepcov.Total, error) {
 	var total *toepcov.Total
 	for i, file := range files {
 		log.Printf("image %d of %d: %s", i+1, len(files), file)
-		distr, err := imageStats(path.Join(dir, file), phi, band)
+		distr, err := imageStats(path.Join(dir, file), phi, transform, band)
 		if err != nil {
 			return nil, err
 		}
@@ -71,7 +81,7 @@ func totalStats(files []string, dir string, phi feat.Image, band int) (*toepcov.
 	return total, nil
 }
 
-func imageStats(file string, phi feat.Image, band int) (*toepcov.Total, error) {
+func imageStats(file string, phi feat.Image, transform *vecset.Affine, band int) (*toepcov.Total, error) {
 	log.Print("load feature image")
 	im, err := loadImage(file)
 	if err != nil {
@@ -82,6 +92,10 @@ func imageStats(file string, phi feat.Image, band int) (*toepcov.Total, error) {
 		return nil, err
 	}
 	log.Printf("feature image: %d x %d x %d", f.Width, f.Height, f.Channels)
+	if transform != nil {
+		f = imset.ApplyChannels(transform, f)
+		log.Printf("transformed image: %d x %d x %d", f.Width, f.Height, f.Channels)
+	}
 	log.Print("compute stats")
 	return toepcov.Stats(f, band), nil
 }
@@ -39,8 +39,9 @@ func (f DiffFeature) Eval(im *rimg64.Multi) float64 {
 	return im.At(f.Point1.X, f.Point1.Y, f.Channel1) - im.At(f.Point2.X, f.Point2.Y, f.Channel2)
 }
 
+// FeatureDistribution samples features using the given source of randomness.
 type FeatureDistribution interface {
-	Sample() Feature
+	Sample(r *rand.Rand) Feature
 }
 
 // UniformElem is a uniform distribution over ElemFeatures.
@@ -49,10 +50,10 @@ type UniformElem struct {
 	Channels int
 }
 
-func (d UniformElem) Sample() Feature {
-	u := rand.Intn(d.Size.X)
-	v := rand.Intn(d.Size.Y)
-	p := rand.Intn(d.Channels)
+func (d UniformElem) Sample(r *rand.Rand) Feature {
+	u := r.Intn(d.Size.X)
+	v := r.Intn(d.Size.Y)
+	p := r.Intn(d.Channels)
 	return ElemFeature{image.Pt(u, v), p}
 }
 
@@ -63,17 +64,17 @@ type UniformDiff struct {
 	SameChannel bool
 }
 
-func (d UniformDiff) Sample() Feature {
-	u := rand.Intn(d.Size.X)
-	v := rand.Intn(d.Size.Y)
-	p := rand.Intn(d.Channels)
-	i := rand.Intn(d.Size.X)
-	j := rand.Intn(d.Size.Y)
+func (d UniformDiff) Sample(r *rand.Rand) Feature {
+	u := r.Intn(d.Size.X)
+	v := r.Intn(d.Size.Y)
+	p := r.Intn(d.Channels)
+	i := r.Intn(d.Size.X)
+	j := r.Intn(d.Size.Y)
 	var q int
 	if d.SameChannel {
 		q = p
 	} else {
-		q = rand.Intn(d.Channels)
+		q = r.Intn(d.Channels)
 	}
 	return DiffFeature{Point1: image.Pt(u, v), Channel1: p, Point2: image.Pt(i, j), Channel2: q}
 }
@@ -88,15 +89,15 @@ type NormalDiff struct {
 	Sigma       float64
 }
 
-func (d NormalDiff) Sample() Feature {
-	u := rand.Intn(d.Size.X)
-	v := rand.Intn(d.Size.Y)
-	p := rand.Intn(d.Channels)
+func (d NormalDiff) Sample(r *rand.Rand) Feature {
+	u := r.Intn(d.Size.X)
+	v := r.Intn(d.Size.Y)
+	p := r.Intn(d.Channels)
 	var q int
 	if d.SameChannel {
 		q = p
 	} else {
-		q = rand.Intn(d.Channels)
+		q = r.Intn(d.Channels)
 	}
 	n := d.Size.X * d.Size.Y
 	pts := make([]image.Point, 0, n)
@@ -111,6 +112,6 @@ func (d NormalDiff) Sample() Feature {
 	}
 	// Find x such that cdf[x] <= r < cdf[x+1], or
 	// the minimum x such that r < cdf[x+1].
-	x := MultinomSum(cdf)
+	x := MultinomSum(r, cdf)
 	return DiffFeature{Point1: image.Pt(u, v), Channel1: p, Point2: pts[x], Channel2: q}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"image"
 	"math"
+	"math/rand"
 	"sort"
 
 	"github.com/jvlmdr/go-cv/rimg64"
@@ -40,7 +41,9 @@ func (a byValue) Len() int           { return len(a) }
 func (a byValue) Less(i, j int) bool { return a[i].x < a[j].x }
 func (a byValue) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
-func TrainForest(x []*rimg64.Multi, y []float64, distr FeatureDistribution, size image.Point, numTrees, depth, numCands int) (*Forest, error) {
+// TrainForest trains a random forest.
+// Candidate features are sampled using r.
+func TrainForest(x []*rimg64.Multi, y []float64, distr FeatureDistribution, size image.Point, numTrees, depth, numCands int, r *rand.Rand) (*Forest, error) {
 	forest := &Forest{InputSize: size}
 	subset := make([]int, len(x))
 	for i := range subset {
@@ -48,7 +51,7 @@ func TrainForest(x []*rimg64.Multi, y []float64, distr FeatureDistribution, size
 	}
 	for i := 0; i < numTrees; i++ {
 		log.Println("train tree", i+1)
-		tree, err := trainTree(x, y, subset, distr, depth, numCands)
+		tree, err := trainTree(x, y, subset, distr, depth, numCands, r)
 		if err != nil {
 			return nil, err
 		}
@@ -57,7 +60,7 @@ func TrainForest(x []*rimg64.Multi, y []float64, distr FeatureDistribution, size
 	return forest, nil
 }
 
-func trainTree(x []*rimg64.Multi, y []float64, subset []int, distr FeatureDistribution, depth, numCands int) (*Node, error) {
+func trainTree(x []*rimg64.Multi, y []float64, subset []int, distr FeatureDistribution, depth, numCands int, r *rand.Rand) (*Node, error) {
 	if len(subset) == 0 {
 		panic("empty list")
 	}
@@ -72,7 +75,7 @@ func trainTree(x []*rimg64.Multi, y []float64, subset []int, distr FeatureDistri
 	// Sample candidate features.
 	cands := make([]Feature, numCands)
 	for j := range cands {
-		cands[j] = distr.Sample()
+		cands[j] = distr.Sample(r)
 	}
 	var (
 		scores    = make([]scalar, len(subset)) // Re-use memory.
@@ -107,11 +110,11 @@ func trainTree(x []*rimg64.Multi, y []float64, subset []int, distr FeatureDistri
 	for k, score := range optScores {
 		order[k] = score.i
 	}
-	left, err := trainTree(x, y, order[:optSplit], distr, depth-1, numCands)
+	left, err := trainTree(x, y, order[:optSplit], distr, depth-1, numCands, r)
 	if err != nil {
 		return nil, err
 	}
-	right, err := trainTree(x, y, order[optSplit:], distr, depth-1, numCands)
+	right, err := trainTree(x, y, order[optSplit:], distr, depth-1, numCands, r)
 	if err != nil {
 		return nil, err
 	}
@@ -29,9 +29,9 @@ func init() {
 
 func main() {
 	var (
-		trainDatasetName = flag.String("train-dataset", "", "{inria, caltech}")
+		trainDatasetName = flag.String("train-dataset", "", fmt.Sprint(data.ListDatasets()))
 		trainDatasetSpec = flag.String("train-dataset-spec", "", "Dataset parameters (JSON)")
-		testDatasetName  = flag.String("test-dataset", "", "{inria, caltech}")
+		testDatasetName  = flag.String("test-dataset", "", fmt.Sprint(data.ListDatasets()))
 		testDatasetSpec  = flag.String("test-dataset-spec", "", "Dataset parameters (JSON)")
 		// numFolds    = flag.Int("folds", 5, "Cross-validation folds")
 		// Positive example configuration.
@@ -44,6 +44,8 @@ func main() {
 		flip          = flag.Bool("flip", false, "Incorporate horizontally mirrored examples?")
 		trainInterp   = flag.Int("train-interp", 1, "Interpolation for multi-scale search (0=nearest, 1=linear, 2=cubic)")
 		numNeg        = flag.Int("num-neg", 1000, "Number of negative examples")
+		seed          = flag.Int64("seed", 0, "Seed for sampling negatives and features")
+		workers       = flag.Int("workers", 0, "Number of goroutines for example extraction (0 for number of CPUs)")
 		// Forest options.
 		numTrees = flag.Int("trees", 100, "Number of trees in forest")
 		depth    = flag.Int("depth", 4, "Depth of trees")
@@ -61,6 +63,7 @@ func main() {
 	)
 	flag.Parse()
 
+	r := rand.New(rand.NewSource(*seed))
 	exampleOpts := data.ExampleOpts{
 		AspectReject: *aspectReject,
 		FitMode:      *resizeFor,
@@ -71,6 +74,13 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	caches, err := data.CacheOpts{Workers: *workers}.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer caches.Close()
+	// Extract training examples with the given number of goroutines.
+	trainDataset = data.WithCaches(trainDataset, caches)
 	testDataset, err := data.Load(*testDatasetName, *testDatasetSpec)
 	if err != nil {
 		log.Fatal(err)
@@ -142,7 +152,7 @@ func main() {
 	// Choose an initial set of random negatives.
 	// TODO: Check trainDataset.CanTrain()?
 	log.Print("choose initial negative examples")
-	negRects, err := data.RandomWindows(*numNeg, negIms, trainDataset, feat.UniformMargin(*margin), region.Size)
+	negRects, err := data.RandomWindows(*numNeg, negIms, trainDataset, feat.UniformMargin(*margin), region.Size, r)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -166,7 +176,7 @@ func main() {
 		y = append(y, -1)
 	}
 
-	forest, err := TrainForest(x, y, distr, phi.Size(region.Size), *numTrees, *depth, *numCands)
+	forest, err := TrainForest(x, y, distr, phi.Size(region.Size), *numTrees, *depth, *numCands, r)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -178,7 +188,7 @@ func main() {
 	//	}
 	//	ml.Sort(vals)
 	//	fmt.Printf("avg prec: %.4g\n", ml.Enum(vals).AvgPrec())
-	//	shuffle(ScoreList(vals))
+	//	shuffle(r, ScoreList(vals))
 	//	fmt.Printf("chance: %.4g\n", ml.Enum(vals).AvgPrec())
 
 	trainIms := trainDataset.Images()
@@ -224,8 +234,8 @@ type List interface {
 	Swap(i, j int)
 }
 
-func shuffle(xs List) {
-	for i, j := range rand.Perm(xs.Len()) {
+func shuffle(r *rand.Rand, xs List) {
+	for i, j := range r.Perm(xs.Len()) {
 		xs.Swap(i, j)
 	}
 }
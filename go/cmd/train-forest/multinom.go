@@ -20,12 +20,12 @@ func CumSum(p []float64) []float64 {
 
 // Multinom returns an index from 0 to len(ws)-1.
 // The mass of index i is ws[i].
-func Multinom(ws []float64) int {
+func Multinom(r *rand.Rand, ws []float64) int {
 	var total float64
 	for _, w := range ws {
 		total += w
 	}
-	x := rand.Float64() * total
+	x := r.Float64() * total
 	// Find i such that sum([:i]) <= x < sum([:i+1]).
 	// Equivalent to smallest index i such that x < s[i+1].
 	var sum float64
@@ -41,9 +41,9 @@ func Multinom(ws []float64) int {
 // MultinomSum returns an index from 0 to len(sum)-2.
 // The mass of index i is sum[i+1] - sum[i].
 // Uses binary search.
-func MultinomSum(sum []float64) int {
+func MultinomSum(r *rand.Rand, sum []float64) int {
 	n := len(sum) - 1
-	x := rand.Float64() * sum[n]
+	x := r.Float64() * sum[n]
 	// Find i such that sum[i] <= x < sum[i+1].
 	// Equivalent to smallest index i such that x < sum[i+1].
 	return sort.Search(n-1, func(i int) bool { return x < sum[i+1] })
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math/rand"
 	"testing"
 
 	"code.google.com/p/probab/dst"
@@ -21,12 +22,13 @@ func TestMultinom(t *testing.T) {
 	// Null hypothesis: Multinomial distribution specified.
 	// Check if there is little or no evidence against null hypothesis.
 	const pval = 0.1
+	r := rand.New(rand.NewSource(1))
 
 	for _, ws := range cases {
 		k := len(ws)
 		x := make([]int, k)
 		for i := 0; i < n; i++ {
-			x[Multinom(ws)]++
+			x[Multinom(r, ws)]++
 		}
 		var total float64
 		for _, m := range ws {
@@ -51,13 +53,14 @@ func TestMultinomSum(t *testing.T) {
 	// Null hypothesis: Multinomial distribution specified.
 	// Check if there is little or no evidence against null hypothesis.
 	const pval = 0.1
+	r := rand.New(rand.NewSource(1))
 
 	for _, ws := range cases {
 		k := len(ws)
 		sum := CumSum(ws)
 		x := make([]int, k)
 		for i := 0; i < n; i++ {
-			x[MultinomSum(sum)]++
+			x[MultinomSum(r, sum)]++
 		}
 		var total float64
 		for _, m := range ws {
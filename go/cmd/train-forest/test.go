@@ -26,7 +26,7 @@ func test(dataset data.ImageSet, ims []string, scorer slide.Scorer, region detec
 		// Load image.
 		file := dataset.File(name)
 		t := time.Now()
-		im, err := loadImage(file)
+		im, err := data.CachesOf(dataset).Images.Image(file)
 		if err != nil {
 			log.Printf("load test image: %s, error: %v", file, err)
 			continue
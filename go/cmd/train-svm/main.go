@@ -13,6 +13,7 @@ import (
 	"image"
 	"log"
 	"math"
+	"math/rand"
 	"os"
 	"path"
 
@@ -49,9 +50,10 @@ var (
 
 func main() {
 	var (
-		datasetName = flag.String("dataset", "", "{inria, caltech}")
+		datasetName = flag.String("dataset", "", fmt.Sprint(data.ListDatasets()))
 		datasetSpec = flag.String("dataset-spec", "", "Dataset parameters (JSON)")
 		numFolds    = flag.Int("folds", 5, "Cross-validation folds")
+		seed        = flag.Int64("seed", 0, "Seed for splitting folds and sampling negative images")
 
 		//	algoName  = flag.String("algo", "", "{svm, struct-svm, hnm-svm, toep, circ}")
 		//	algoSpec  = flag.String("algo-spec", "", "Algorithm options (JSON)")
@@ -140,7 +142,8 @@ func main() {
 		log.Fatal(err)
 	}
 	// Split images into folds.
-	folds := split(dataset.Images(), *numFolds)
+	r := rand.New(rand.NewSource(*seed))
+	folds := split(dataset.Images(), *numFolds, r)
 
 	// Train a detector for each fold.
 	perfs := make([]float64, *numFolds)
@@ -160,7 +163,7 @@ func main() {
 		}
 		// Take a random subset of the negative training images.
 		numNegIms := subsetSize(len(examples.NegImages), *maxNegTrainFrac, *maxNegTrainNum)
-		examples.NegImages = selectSubset(examples.NegImages, subset(len(examples.NegImages), numNegIms))
+		examples.NegImages = selectSubset(examples.NegImages, subset(len(examples.NegImages), numNegIms, r))
 		log.Println("number of negative images:", len(examples.NegImages))
 
 		// Obtain detector.
@@ -2,7 +2,6 @@ package main
 
 import (
 	"log"
-	"time"
 
 	"github.com/gonum/floats"
 	"github.com/jvlmdr/go-cv/detect"
@@ -22,25 +21,17 @@ func test(tmpl *detect.FeatTmpl, ims []string, dataset data.ImageSet, phi feat.I
 	var imvals []*detect.ValSet
 	for i, name := range ims {
 		log.Printf("test image %d / %d: %s", i+1, len(ims), name)
-		// Load image.
-		file := dataset.File(name)
-		t := time.Now()
-		im, err := loadImage(file)
+		dets, durSearch, err := data.MultiScale(dataset, name, tmpl.Scorer, tmpl.PixelShape, opts)
 		if err != nil {
-			log.Printf("load test image: %s, error: %v", file, err)
+			log.Printf("search test image: %s, error: %v", dataset.File(name), err)
 			continue
 		}
-		durLoad := time.Since(t)
-		dets, durSearch, err := detect.MultiScale(im, tmpl, opts)
-		if err != nil {
-			return 0, err
-		}
 		annot := dataset.Annot(name)
 		imval := detect.Validate(dets, annot.Instances, annot.Ignore, minMatchIOU, minIgnoreCover)
 		imvals = append(imvals, imval.Set())
 		log.Printf(
-			"load %v, resize %v, feat %v, slide %v, suppr %v",
-			durLoad, durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
+			"resize %v, feat %v, slide %v, suppr %v",
+			durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
 		)
 	}
 	valset := detect.MergeValSets(imvals...)
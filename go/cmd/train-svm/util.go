@@ -21,11 +21,11 @@ func max(a, b int) int {
 
 // Split divides x randomly into n groups.
 // It is possible that one of the groups is empty.
-func split(x []string, n int) [][]string {
+func split(x []string, n int, r *rand.Rand) [][]string {
 	y := make([][]string, n)
 	for _, xi := range x {
-		r := rand.Intn(n)
-		y[r] = append(y[r], xi)
+		k := r.Intn(n)
+		y[k] = append(y[k], xi)
 	}
 	return y
 }
@@ -43,8 +43,8 @@ func mergeExcept(x [][]string, j int) []string {
 
 // Subset returns a random length-m subset of 0..n-1.
 // Elements are ordered.
-func subset(n, m int) []int {
-	p := rand.Perm(n)[:m]
+func subset(n, m int, r *rand.Rand) []int {
+	p := r.Perm(n)[:m]
 	sort.Ints(p)
 	return p
 }
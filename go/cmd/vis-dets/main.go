@@ -24,7 +24,7 @@ func init() {
 func main() {
 	var (
 		minScore    = flag.Float64("min-score", 0, "Minimum score to include detection")
-		datasetName = flag.String("dataset", "", "{inria, caltech}")
+		datasetName = flag.String("dataset", "", fmt.Sprint(data.ListDatasets()))
 		datasetSpec = flag.String("dataset-spec", "", "Dataset parameters (JSON)")
 	)
 	flag.Parse()
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"hash/fnv"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// Augment describes random perturbations which are applied
+// to positive examples to obtain additional examples.
+// The zero value adds no examples.
+//
+// Each perturbation is sampled independently for each copy.
+// Geometric jitter is applied to the example rectangle
+// before it is sampled from the image,
+// and photometric jitter and blur are applied after resizing.
+type Augment struct {
+	// Number of perturbed copies of each example
+	// in addition to the original.
+	Copies int
+	// Random numbers are determined by the seed,
+	// the image name and the index of the example.
+	Seed int64
+
+	// Maximum translation as a fraction of width and height.
+	Translate float64
+	// Maximum change in scale.
+	// The scale is log-uniform between 1/(1+Scale) and 1+Scale.
+	Scale float64
+	// Maximum rotation in degrees.
+	Rotate float64
+
+	// Maximum offset added to intensity in [0, 1].
+	Brightness float64
+	// Maximum relative change in contrast.
+	Contrast float64
+	// Maximum change in gamma.
+	// The gamma is log-uniform between 1/(1+Gamma) and 1+Gamma.
+	Gamma float64
+	// Maximum standard deviation of Gaussian blur in pixels.
+	Blur float64
+}
+
+// perturb is a single sample of the random perturbations.
+type perturb struct {
+	Shift      [2]float64 // Fraction of width and height.
+	Scale      float64
+	Angle      float64 // Radians.
+	Brightness float64
+	Contrast   float64
+	Gamma      float64
+	Sigma      float64
+}
+
+// rand returns the random number generator for
+// the j-th example in an image.
+func (a *Augment) rand(name string, j int) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	seed := (a.Seed ^ int64(h.Sum64())) + int64(j)
+	return rand.New(rand.NewSource(seed))
+}
+
+func (a *Augment) sample(r *rand.Rand) perturb {
+	uniform := func(max float64) float64 { return max * (2*r.Float64() - 1) }
+	logUniform := func(max float64) float64 { return math.Exp(uniform(math.Log(1 + max))) }
+	return perturb{
+		Shift:      [2]float64{uniform(a.Translate), uniform(a.Translate)},
+		Scale:      logUniform(a.Scale),
+		Angle:      uniform(a.Rotate) * math.Pi / 180,
+		Brightness: uniform(a.Brightness),
+		Contrast:   1 + uniform(a.Contrast),
+		Gamma:      logUniform(a.Gamma),
+		Sigma:      a.Blur * r.Float64(),
+	}
+}
+
+// jitterRect translates and scales a rectangle about its center.
+func jitterRect(rect image.Rectangle, p perturb) image.Rectangle {
+	w, h := float64(rect.Dx()), float64(rect.Dy())
+	cx := float64(rect.Min.X+rect.Max.X)/2 + p.Shift[0]*w
+	cy := float64(rect.Min.Y+rect.Max.Y)/2 + p.Shift[1]*h
+	w, h = w*p.Scale, h*p.Scale
+	return image.Rect(round(cx-w/2), round(cy-h/2), round(cx+w/2), round(cy+h/2))
+}
+
+// rotateRect samples the rectangle from the image rotated about
+// the center of the rectangle.
+// Pixels outside the image take the value of the nearest pixel.
+func rotateRect(im image.Image, rect image.Rectangle, angle float64) image.Image {
+	dst := image.NewRGBA64(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	b := im.Bounds()
+	cx := float64(rect.Min.X+rect.Max.X) / 2
+	cy := float64(rect.Min.Y+rect.Max.Y) / 2
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	for j := 0; j < rect.Dy(); j++ {
+		for i := 0; i < rect.Dx(); i++ {
+			// Position relative to center.
+			x := float64(rect.Min.X+i) + 0.5 - cx
+			y := float64(rect.Min.Y+j) + 0.5 - cy
+			u := int(math.Floor(cx + cos*x - sin*y))
+			v := int(math.Floor(cy + sin*x + cos*y))
+			u = clamp(u, b.Min.X, b.Max.X-1)
+			v = clamp(v, b.Min.Y, b.Max.Y-1)
+			dst.Set(i, j, im.At(u, v))
+		}
+	}
+	return dst
+}
+
+func clamp(x, a, b int) int {
+	if x < a {
+		return a
+	}
+	if x > b {
+		return b
+	}
+	return x
+}
+
+// photometric applies brightness, contrast and gamma to each channel.
+func photometric(src image.Image, p perturb) image.Image {
+	r := src.Bounds()
+	dst := image.NewRGBA64(r)
+	f := func(x uint32) uint16 {
+		y := math.Pow(float64(x)/0xffff, p.Gamma)
+		y = (y-0.5)*p.Contrast + 0.5 + p.Brightness
+		y = math.Max(0, math.Min(1, y))
+		return uint16(0.5 + y*0xffff)
+	}
+	for j := r.Min.Y; j < r.Max.Y; j++ {
+		for i := r.Min.X; i < r.Max.X; i++ {
+			c := color.NRGBA64Model.Convert(src.At(i, j)).(color.NRGBA64)
+			c.R, c.G, c.B = f(uint32(c.R)), f(uint32(c.G)), f(uint32(c.B))
+			dst.Set(i, j, c)
+		}
+	}
+	return dst
+}
+
+// blur convolves an image with a Gaussian.
+// Pixels outside the image take the value of the nearest pixel.
+func blur(src image.Image, sigma float64) image.Image {
+	if sigma <= 0 {
+		return src
+	}
+	rad := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*rad+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - rad)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	r := src.Bounds()
+	w, h := r.Dx(), r.Dy()
+	// Four channels in row-major order.
+	pix := make([]float64, 4*w*h)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			c := color.RGBA64Model.Convert(src.At(r.Min.X+i, r.Min.Y+j)).(color.RGBA64)
+			k := 4 * (j*w + i)
+			pix[k], pix[k+1], pix[k+2], pix[k+3] = float64(c.R), float64(c.G), float64(c.B), float64(c.A)
+		}
+	}
+	pix = convolve1D(pix, w, h, kernel, true)
+	pix = convolve1D(pix, w, h, kernel, false)
+	dst := image.NewRGBA64(r)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			k := 4 * (j*w + i)
+			dst.SetRGBA64(r.Min.X+i, r.Min.Y+j, color.RGBA64{
+				uint16(0.5 + pix[k]), uint16(0.5 + pix[k+1]),
+				uint16(0.5 + pix[k+2]), uint16(0.5 + pix[k+3]),
+			})
+		}
+	}
+	return dst
+}
+
+func convolve1D(pix []float64, w, h int, kernel []float64, horiz bool) []float64 {
+	out := make([]float64, len(pix))
+	rad := len(kernel) / 2
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			for d, g := range kernel {
+				u, v := i, j
+				if horiz {
+					u = clamp(i+d-rad, 0, w-1)
+				} else {
+					v = clamp(j+d-rad, 0, h-1)
+				}
+				src, dst := 4*(v*w+u), 4*(j*w+i)
+				for p := 0; p < 4; p++ {
+					out[dst+p] += g * pix[src+p]
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/imsamp"
+	"github.com/nfnt/resize"
+)
+
+func TestAugmentedExamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "augment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 4)
+	rects := make(map[string][]image.Rectangle)
+	for _, im := range ims {
+		rects[im] = []image.Rectangle{image.Rect(2, 2, 10, 18), image.Rect(4, 4, 12, 20)}
+	}
+	dataset := &testDataset{ims}
+	shape := detect.PadRect{Size: image.Pt(8, 16), Int: image.Rect(0, 0, 8, 16)}
+	aug := &Augment{
+		Copies: 3, Seed: 1,
+		Translate: 0.1, Scale: 0.1, Rotate: 5,
+		Brightness: 0.1, Contrast: 0.1, Gamma: 0.1, Blur: 1,
+	}
+
+	orig, err := Examples(ims, rects, dataset, sumFeat{}, imsamp.Continue, shape, true, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := AugmentedExamples(ims, rects, dataset, sumFeat{}, imsamp.Continue, shape, true, aug, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(orig) * (1 + aug.Copies); len(x) != want {
+		t.Fatalf("want %d examples, got %d", want, len(x))
+	}
+	// Each original example and its flip should precede its copies.
+	per := 2 * (1 + aug.Copies)
+	for i := 0; i < len(orig)/2; i++ {
+		if !reflect.DeepEqual(orig[2*i:2*i+2], x[per*i:per*i+2]) {
+			t.Errorf("example %d: original differs", i)
+		}
+	}
+	// Same seed should give same examples.
+	y, err := AugmentedExamples(ims, rects, dataset, sumFeat{}, imsamp.Continue, shape, true, aug, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x, y) {
+		t.Error("examples differ with same seed")
+	}
+	// Different seed should give different examples.
+	other := *aug
+	other.Seed = 2
+	z, err := AugmentedExamples(ims, rects, dataset, sumFeat{}, imsamp.Continue, shape, true, &other, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(x, z) {
+		t.Error("examples equal with different seed")
+	}
+}
+
+// Checks that the zero perturbation does not modify the window.
+func TestAugmentRect_identity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "augment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 1)
+	im, err := LoadImage(ims[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rect := image.Rect(2, 2, 10, 18)
+	shape := detect.PadRect{Size: rect.Size(), Int: image.Rectangle{Max: rect.Size()}}
+	p := (&Augment{}).sample(rand.New(rand.NewSource(0)))
+	got := augmentRect(im, rect, imsamp.Continue, shape, p, resize.Bilinear)
+	for j := 0; j < rect.Dy(); j++ {
+		for i := 0; i < rect.Dx(); i++ {
+			a := color.GrayModel.Convert(got.At(got.Bounds().Min.X+i, got.Bounds().Min.Y+j))
+			b := color.GrayModel.Convert(im.At(rect.Min.X+i, rect.Min.Y+j))
+			if a != b {
+				t.Fatalf("at %d, %d: want %v, got %v", i, j, b, a)
+			}
+		}
+	}
+}
+
+func TestJitterRect(t *testing.T) {
+	r := image.Rect(0, 0, 10, 20)
+	got := jitterRect(r, perturb{Shift: [2]float64{0.1, -0.1}, Scale: 2})
+	if want := image.Rect(-4, -12, 16, 28); got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestBlur_constant(t *testing.T) {
+	im := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range im.Pix {
+		im.Pix[i] = 100
+	}
+	got := blur(im, 1.5)
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			if c := color.GrayModel.Convert(got.At(i, j)).(color.Gray); c.Y != 100 {
+				t.Fatalf("at %d, %d: want 100, got %d", i, j, c.Y)
+			}
+		}
+	}
+}
@@ -36,7 +36,7 @@ type caltechDataset struct {
 	annots map[string]Annot
 }
 
-func loadCaltech(spec CaltechSpec, filter caltechped.ObjectFilter) (ImageSet, error) {
+func loadCaltech(spec CaltechSpec, role objectRoleFunc) (ImageSet, error) {
 	d := new(caltechDataset)
 	d.dir = spec.Dir
 	d.subdir = spec.Subdir
@@ -77,19 +77,41 @@ func loadCaltech(spec CaltechSpec, filter caltechped.ObjectFilter) (ImageSet, er
 					return nil, fmt.Errorf("load annotation: %v", err)
 				}
 				d.ims = append(d.ims, name)
-				d.annots[name] = annotFromCaltech(annot, filter)
+				d.annots[name] = annotFromCaltech(annot, role)
 			}
 		}
 	}
 	return d, nil
 }
 
-func annotFromCaltech(x caltechped.ImageAnnot, filter caltechped.ObjectFilter) Annot {
+// objectRole describes how an annotated object is used.
+type objectRole int
+
+const (
+	discardObject objectRole = iota
+	ignoreObject
+	instanceObject
+)
+
+type objectRoleFunc func(obj caltechped.Object) objectRole
+
+// filterRole makes objects which do not pass the filter ignored.
+func filterRole(filter caltechped.ObjectFilter) objectRoleFunc {
+	return func(obj caltechped.Object) objectRole {
+		if filter(obj) {
+			return instanceObject
+		}
+		return ignoreObject
+	}
+}
+
+func annotFromCaltech(x caltechped.ImageAnnot, role objectRoleFunc) Annot {
 	var y Annot
 	for _, obj := range x.Objects {
-		if filter(obj) {
+		switch role(obj) {
+		case instanceObject:
 			y.Instances = append(y.Instances, obj.Rect)
-		} else {
+		case ignoreObject:
 			y.Ignore = append(y.Ignore, obj.Rect)
 		}
 	}
@@ -134,7 +156,8 @@ func (d *caltechDataset) Annot(name string) Annot {
 
 type CaltechPreset struct {
 	Dir string
-	// usa, usatrain, usatest, inriatrain, inriatest, ...
+	// usa, usatrain, usatest, inriatrain, inriatest,
+	// eth, tudbrussels, daimler
 	Name string
 }
 
@@ -195,6 +218,34 @@ func (p CaltechPreset) Spec() CaltechSpec {
 			Ext:    "png",
 			Sets:   []CaltechSet{{Index: 1, Videos: []int{0}}},
 		}
+	case "eth":
+		return CaltechSpec{
+			Dir:    p.Dir,
+			Subdir: "ETH",
+			Skip:   1,
+			Ext:    "png",
+			Sets: []CaltechSet{
+				{Index: 0, Videos: []int{0}},
+				{Index: 1, Videos: []int{0}},
+				{Index: 2, Videos: []int{0}},
+			},
+		}
+	case "tudbrussels":
+		return CaltechSpec{
+			Dir:    p.Dir,
+			Subdir: "TudBrussels",
+			Skip:   1,
+			Ext:    "png",
+			Sets:   []CaltechSet{{Index: 0, Videos: []int{0}}},
+		}
+	case "daimler":
+		return CaltechSpec{
+			Dir:    p.Dir,
+			Subdir: "Daimler",
+			Skip:   1,
+			Ext:    "png",
+			Sets:   []CaltechSet{{Index: 0, Videos: []int{0}}},
+		}
 	default:
 		panic(fmt.Sprintf("unknown preset: %s", p.Name))
 	}
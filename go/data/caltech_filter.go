@@ -0,0 +1,164 @@
+package data
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jvlmdr/go-cv/dataset/caltechped"
+)
+
+func init() {
+	RegisterDataset("caltech",
+		func() interface{} { return new(CaltechFilterSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			s := spec.(*CaltechFilterSpec)
+			filter, err := s.Filter.Resolve()
+			if err != nil {
+				return nil, err
+			}
+			return loadCaltech(s.CaltechSpec, filter.role)
+		},
+	)
+}
+
+// CaltechFilterSpec is a Caltech dataset with an object filter.
+// The fields of CaltechSpec are at the top level of the JSON.
+type CaltechFilterSpec struct {
+	CaltechSpec
+	Filter CaltechFilter
+}
+
+// CaltechFilter describes which objects are instances,
+// which are ignored and which are discarded,
+// as in the evaluation code of the Caltech Pedestrian benchmark.
+//
+// Objects with a label in Labels which satisfy the height and
+// visibility constraints are instances.
+// Objects with a label in Labels which do not satisfy them
+// and objects with a label in IgnoreLabels are ignored.
+// Objects with any other label are discarded.
+type CaltechFilter struct {
+	// Name of a standard protocol.
+	// If not empty, all other fields must be zero.
+	// See CaltechProtocols.
+	Protocol string
+
+	// Labels of objects which can be instances e.g. "person".
+	Labels []string
+	// Labels of objects which are always ignored e.g. "people", "person?".
+	IgnoreLabels []string
+	// Height range in pixels, inclusive.
+	// Zero MaxHeight means no upper limit.
+	MinHeight, MaxHeight int
+	// Range of the visible fraction of the object, inclusive.
+	// Zero MaxVis means 1.
+	MinVis, MaxVis float64
+	// Makes the upper limit of the visible fraction exclusive.
+	// This is needed to partition objects by occlusion.
+	ExclMaxVis bool `json:",omitempty"`
+}
+
+// CaltechProtocols gives the filters of the standard evaluation settings.
+var CaltechProtocols = map[string]CaltechFilter{
+	"reasonable":  caltechProtocol(50, 0, 0.65, 0),
+	"all":         caltechProtocol(20, 0, 0.2, 0),
+	"near":        caltechProtocol(80, 0, 0.65, 0),
+	"medium":      caltechProtocol(30, 80, 0.65, 0),
+	"far":         caltechProtocol(20, 30, 0.65, 0),
+	"occ-none":    caltechProtocol(50, 0, 1, 0),
+	"occ-partial": caltechOcclProtocol(50, 0, 0.65, 1),
+	"occ-heavy":   caltechOcclProtocol(50, 0, 0.2, 0.65),
+}
+
+func caltechProtocol(minHeight, maxHeight int, minVis, maxVis float64) CaltechFilter {
+	return CaltechFilter{
+		Labels:       []string{"person"},
+		IgnoreLabels: []string{"people"},
+		MinHeight:    minHeight,
+		MaxHeight:    maxHeight,
+		MinVis:       minVis,
+		MaxVis:       maxVis,
+	}
+}
+
+// caltechOcclProtocol is like caltechProtocol
+// with an exclusive upper limit on visibility
+// so that the occlusion settings do not overlap.
+func caltechOcclProtocol(minHeight, maxHeight int, minVis, maxVis float64) CaltechFilter {
+	f := caltechProtocol(minHeight, maxHeight, minVis, maxVis)
+	f.ExclMaxVis = true
+	return f
+}
+
+// Resolve replaces a protocol with its filter.
+func (f CaltechFilter) Resolve() (CaltechFilter, error) {
+	if f.Protocol == "" {
+		if len(f.Labels) == 0 {
+			return f, fmt.Errorf("filter has no labels")
+		}
+		return f, nil
+	}
+	g, ok := CaltechProtocols[f.Protocol]
+	if !ok {
+		return f, fmt.Errorf("unknown protocol: %q", f.Protocol)
+	}
+	if len(f.Labels) > 0 || len(f.IgnoreLabels) > 0 || f.MinHeight != 0 || f.MaxHeight != 0 || f.MinVis != 0 || f.MaxVis != 0 || f.ExclMaxVis {
+		return f, fmt.Errorf("protocol %q specified with other filter fields", f.Protocol)
+	}
+	return g, nil
+}
+
+func (f CaltechFilter) role(obj caltechped.Object) objectRole {
+	switch {
+	case hasLabel(f.Labels, obj.Label):
+		if f.accept(obj) {
+			return instanceObject
+		}
+		return ignoreObject
+	case hasLabel(f.IgnoreLabels, obj.Label):
+		return ignoreObject
+	default:
+		return discardObject
+	}
+}
+
+func (f CaltechFilter) accept(obj caltechped.Object) bool {
+	h := obj.Rect.Dy()
+	if h < f.MinHeight || (f.MaxHeight > 0 && h > f.MaxHeight) {
+		return false
+	}
+	maxVis := f.MaxVis
+	if maxVis == 0 {
+		maxVis = 1
+	}
+	vis := visibleFraction(obj)
+	if f.ExclMaxVis {
+		return f.MinVis <= vis && vis < maxVis
+	}
+	return f.MinVis <= vis && vis <= maxVis
+}
+
+// visibleFraction is one if the object is not occluded.
+func visibleFraction(obj caltechped.Object) float64 {
+	if !obj.Occl {
+		return 1
+	}
+	total := rectArea(obj.Rect)
+	if total == 0 {
+		return 0
+	}
+	return float64(rectArea(obj.Vis.Intersect(obj.Rect))) / float64(total)
+}
+
+func rectArea(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, x := range labels {
+		if x == label {
+			return true
+		}
+	}
+	return false
+}
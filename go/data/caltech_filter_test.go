@@ -0,0 +1,93 @@
+package data
+
+import (
+	"image"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/dataset/caltechped"
+)
+
+func TestCaltechFilter(t *testing.T) {
+	person := func(h int) image.Rectangle { return image.Rect(0, 0, h/2, h) }
+	occluded := caltechped.Object{
+		Label: "person",
+		Rect:  person(100),
+		Occl:  true,
+		// Top half visible.
+		Vis: image.Rect(0, 0, 50, 50),
+	}
+	partial := caltechped.Object{
+		Label: "person",
+		Rect:  person(100),
+		Occl:  true,
+		// Top 80% visible.
+		Vis: image.Rect(0, 0, 50, 80),
+	}
+	cases := []struct {
+		Protocol string
+		Obj      caltechped.Object
+		Want     objectRole
+	}{
+		{"reasonable", caltechped.Object{Label: "person", Rect: person(60)}, instanceObject},
+		{"reasonable", caltechped.Object{Label: "person", Rect: person(40)}, ignoreObject},
+		{"reasonable", caltechped.Object{Label: "people", Rect: person(60)}, ignoreObject},
+		{"reasonable", caltechped.Object{Label: "person?", Rect: person(60)}, discardObject},
+		{"reasonable", occluded, ignoreObject},
+		{"all", occluded, instanceObject},
+		{"occ-heavy", occluded, instanceObject},
+		{"occ-partial", occluded, ignoreObject},
+		{"occ-partial", caltechped.Object{Label: "person", Rect: person(60)}, ignoreObject},
+		{"occ-partial", partial, instanceObject},
+		{"occ-heavy", partial, ignoreObject},
+		{"occ-none", partial, ignoreObject},
+		{"occ-none", caltechped.Object{Label: "person", Rect: person(60)}, instanceObject},
+		{"medium", caltechped.Object{Label: "person", Rect: person(60)}, instanceObject},
+		{"medium", caltechped.Object{Label: "person", Rect: person(100)}, ignoreObject},
+		{"far", caltechped.Object{Label: "person", Rect: person(24)}, instanceObject},
+	}
+	for _, c := range cases {
+		filter, err := CaltechFilter{Protocol: c.Protocol}.Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filter.role(c.Obj); got != c.Want {
+			t.Errorf("%s: %+v: want %v, got %v", c.Protocol, c.Obj, c.Want, got)
+		}
+	}
+}
+
+func TestCaltechFilter_labels(t *testing.T) {
+	filter := CaltechFilter{
+		Labels:       []string{"person", "person?"},
+		IgnoreLabels: []string{"people"},
+		MinHeight:    50,
+	}
+	filter, err := filter.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	annot := annotFromCaltech(caltechped.ImageAnnot{Objects: []caltechped.Object{
+		{Label: "person?", Rect: image.Rect(0, 0, 30, 60)},
+		{Label: "people", Rect: image.Rect(0, 0, 30, 60)},
+		{Label: "person-fa", Rect: image.Rect(0, 0, 30, 60)},
+		{Label: "person", Rect: image.Rect(0, 0, 20, 40)},
+	}}, filter.role)
+	if len(annot.Instances) != 1 {
+		t.Errorf("instances: want 1, got %d", len(annot.Instances))
+	}
+	if len(annot.Ignore) != 2 {
+		t.Errorf("ignore: want 2, got %d", len(annot.Ignore))
+	}
+}
+
+func TestCaltechFilter_Resolve(t *testing.T) {
+	if _, err := (CaltechFilter{Protocol: "foo"}).Resolve(); err == nil {
+		t.Error("unknown protocol: expected error")
+	}
+	if _, err := (CaltechFilter{Protocol: "all", MinHeight: 10}).Resolve(); err == nil {
+		t.Error("protocol with fields: expected error")
+	}
+	if _, err := (CaltechFilter{}).Resolve(); err == nil {
+		t.Error("no labels: expected error")
+	}
+}
@@ -0,0 +1,385 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jvlmdr/go-file/fileutil"
+)
+
+func init() {
+	RegisterDataset("coco",
+		func() interface{} { return new(COCOSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return loadCOCO(*spec.(*COCOSpec))
+		},
+	)
+}
+
+// COCOSpec describes a dataset in the format of
+// the COCO instances_*.json annotation files.
+type COCOSpec struct {
+	// Annotation file.
+	File string
+	// Directory to which the file_name of each image is relative.
+	ImageDir string
+	// Name of category to detect e.g. "person".
+	Category string
+	// Objects of the category which are smaller than this (area of box)
+	// or shorter than this (height of box) are added to Ignore (pixels).
+	MinArea   float64
+	MinHeight int
+	// Objects whose visible fraction is less than this are added to Ignore.
+	// The visible fraction is the area of "vis_bbox" divided by
+	// the area of "bbox", or one if there is no "vis_bbox".
+	MinVisible float64
+
+	// The training and testing images can be specified by
+	// a field of the image whose value is TrainSplit or TestSplit,
+	// or by files which list one image id per line.
+	// If neither is given, all images can be used for both.
+	SplitField            string
+	TrainSplit, TestSplit string
+	TrainList, TestList   string
+
+	// Exclude negative images from the test set?
+	ExclNegTest bool
+}
+
+// cocoDataset is a list of images with annotations.
+// The name of an image is its file_name.
+type cocoDataset struct {
+	dir         string
+	ims         []string
+	annots      map[string]Annot
+	isNeg       map[string]bool
+	canTrain    map[string]bool
+	canTest     map[string]bool
+	exclNegTest bool
+}
+
+type cocoCategory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type cocoObject struct {
+	ImageID    int64     `json:"image_id"`
+	CategoryID int64     `json:"category_id"`
+	Box        []float64 `json:"bbox"`
+	VisBox     []float64 `json:"vis_bbox"`
+	IsCrowd    int       `json:"iscrowd"`
+}
+
+func loadCOCO(spec COCOSpec) (ImageSet, error) {
+	if spec.Category == "" {
+		return nil, fmt.Errorf("no category specified")
+	}
+	bySplit := spec.SplitField != ""
+	byList := spec.TrainList != "" || spec.TestList != ""
+	if bySplit && byList {
+		return nil, fmt.Errorf("split specified by both field and list")
+	}
+
+	// Find the category first, since it may come after the annotations.
+	var (
+		catID    int64
+		catFound bool
+	)
+	err := scanCOCO(spec.File, map[string]func(*json.Decoder) error{
+		"categories": func(dec *json.Decoder) error {
+			return decodeArray(dec, func(dec *json.Decoder) error {
+				var cat cocoCategory
+				if err := dec.Decode(&cat); err != nil {
+					return err
+				}
+				if cat.Name == spec.Category {
+					catID, catFound = cat.ID, true
+				}
+				return nil
+			})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !catFound {
+		return nil, fmt.Errorf("category not found: %s", spec.Category)
+	}
+
+	d := new(cocoDataset)
+	d.dir = spec.ImageDir
+	d.exclNegTest = spec.ExclNegTest
+	d.annots = make(map[string]Annot)
+	d.isNeg = make(map[string]bool)
+	d.canTrain = make(map[string]bool)
+	d.canTest = make(map[string]bool)
+	// Images in order of appearance in the file.
+	var order []int64
+	names := make(map[int64]string)
+	splits := make(map[int64]string)
+	// Only keep objects of the category.
+	objs := make(map[int64][]cocoObject)
+	err = scanCOCO(spec.File, map[string]func(*json.Decoder) error{
+		"images": func(dec *json.Decoder) error {
+			return decodeArray(dec, func(dec *json.Decoder) error {
+				var fields map[string]json.RawMessage
+				if err := dec.Decode(&fields); err != nil {
+					return err
+				}
+				var (
+					id   int64
+					name string
+				)
+				if err := json.Unmarshal(fields["id"], &id); err != nil {
+					return fmt.Errorf("image id: %v", err)
+				}
+				if err := json.Unmarshal(fields["file_name"], &name); err != nil {
+					return fmt.Errorf("image file_name: %v", err)
+				}
+				names[id] = name
+				order = append(order, id)
+				if bySplit {
+					split, err := splitValue(fields[spec.SplitField])
+					if err != nil {
+						return fmt.Errorf("image %d: field %s: %v", id, spec.SplitField, err)
+					}
+					splits[id] = split
+				}
+				return nil
+			})
+		},
+		"annotations": func(dec *json.Decoder) error {
+			return decodeArray(dec, func(dec *json.Decoder) error {
+				var obj cocoObject
+				if err := dec.Decode(&obj); err != nil {
+					return err
+				}
+				if obj.CategoryID == catID {
+					objs[obj.ImageID] = append(objs[obj.ImageID], obj)
+				}
+				return nil
+			})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var trainIDs, testIDs map[int64]bool
+	if byList {
+		if trainIDs, err = loadIDList(spec.TrainList); err != nil {
+			return nil, err
+		}
+		if testIDs, err = loadIDList(spec.TestList); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range order {
+		name := names[id]
+		d.ims = append(d.ims, name)
+		d.annots[name] = annotFromCOCO(objs[id], spec)
+		d.isNeg[name] = len(objs[id]) == 0
+		switch {
+		case bySplit:
+			d.canTrain[name] = splits[id] == spec.TrainSplit
+			d.canTest[name] = splits[id] == spec.TestSplit
+		case byList:
+			d.canTrain[name] = trainIDs[id]
+			d.canTest[name] = testIDs[id]
+		default:
+			d.canTrain[name] = true
+			d.canTest[name] = true
+		}
+	}
+	return d, nil
+}
+
+func annotFromCOCO(objs []cocoObject, spec COCOSpec) Annot {
+	var y Annot
+	for _, obj := range objs {
+		r := cocoRect(obj.Box)
+		switch {
+		case obj.IsCrowd != 0:
+			y.Ignore = append(y.Ignore, r)
+		case cocoArea(obj.Box) < spec.MinArea:
+			y.Ignore = append(y.Ignore, r)
+		case r.Dy() < spec.MinHeight:
+			y.Ignore = append(y.Ignore, r)
+		case visibleFrac(obj) < spec.MinVisible:
+			y.Ignore = append(y.Ignore, r)
+		default:
+			y.Instances = append(y.Instances, r)
+		}
+	}
+	return y
+}
+
+// cocoRect converts [x, y, width, height] to a rectangle.
+func cocoRect(box []float64) image.Rectangle {
+	if len(box) != 4 {
+		return image.ZR
+	}
+	x0, y0 := math.Floor(box[0]+0.5), math.Floor(box[1]+0.5)
+	x1, y1 := math.Floor(box[0]+box[2]+0.5), math.Floor(box[1]+box[3]+0.5)
+	return image.Rect(int(x0), int(y0), int(x1), int(y1))
+}
+
+func cocoArea(box []float64) float64 {
+	if len(box) != 4 {
+		return 0
+	}
+	return box[2] * box[3]
+}
+
+func visibleFrac(obj cocoObject) float64 {
+	if len(obj.VisBox) != 4 {
+		return 1
+	}
+	area := cocoArea(obj.Box)
+	if area == 0 {
+		return 0
+	}
+	return cocoArea(obj.VisBox) / area
+}
+
+// splitValue accepts strings and numbers.
+func splitValue(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var x json.Number
+	if err := json.Unmarshal(raw, &x); err != nil {
+		return "", err
+	}
+	return x.String(), nil
+}
+
+// loadIDList returns nil if the file name is empty.
+func loadIDList(fname string) (map[int64]bool, error) {
+	if fname == "" {
+		return nil, nil
+	}
+	lines, err := fileutil.LoadLines(fname)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int64]bool, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("image id list %s: %v", fname, err)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// scanCOCO reads the top-level object of a JSON file one field at a time.
+// Fields with a handler are passed to it, others are skipped.
+func scanCOCO(fname string, handlers map[string]func(*json.Decoder) error) error {
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expect object key: got %v", tok)
+		}
+		handler, ok := handlers[key]
+		if !ok {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := handler(dec); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+	return nil
+}
+
+// decodeArray calls elem for every element of an array.
+func decodeArray(dec *json.Decoder, elem func(*json.Decoder) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := elem(dec); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return fmt.Errorf("expect %v: unexpected end of file", want)
+	}
+	if err != nil {
+		return err
+	}
+	if got, ok := tok.(json.Delim); !ok || got != want {
+		return fmt.Errorf("expect %v: got %v", want, tok)
+	}
+	return nil
+}
+
+func (d *cocoDataset) Images() []string {
+	return d.ims
+}
+
+func (d *cocoDataset) File(name string) string {
+	return path.Join(d.dir, name)
+}
+
+func (d *cocoDataset) CanTrain(name string) bool {
+	return d.canTrain[name]
+}
+
+func (d *cocoDataset) CanTest(name string) bool {
+	if d.exclNegTest && d.isNeg[name] {
+		return false
+	}
+	return d.canTest[name]
+}
+
+func (d *cocoDataset) IsNeg(name string) bool {
+	return d.isNeg[name]
+}
+
+func (d *cocoDataset) Annot(name string) Annot {
+	return d.annots[name]
+}
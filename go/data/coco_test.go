@@ -0,0 +1,149 @@
+package data
+
+import (
+	"encoding/json"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// Annotations are listed before categories, as in the COCO files.
+const cocoTestAnnot = `{
+	"info": {"description": "test"},
+	"images": [
+		{"id": 1, "file_name": "a.jpg", "width": 640, "height": 480, "split": "train"},
+		{"id": 2, "file_name": "b.jpg", "width": 640, "height": 480, "split": "val"},
+		{"id": 3, "file_name": "c.jpg", "width": 640, "height": 480, "split": "train"}
+	],
+	"annotations": [
+		{"id": 10, "image_id": 1, "category_id": 5, "bbox": [10, 20, 30, 90], "area": 2700, "iscrowd": 0},
+		{"id": 11, "image_id": 1, "category_id": 5, "bbox": [100, 20, 200, 100], "area": 20000, "iscrowd": 1},
+		{"id": 12, "image_id": 1, "category_id": 5, "bbox": [300, 20, 5, 10], "area": 50, "iscrowd": 0},
+		{"id": 13, "image_id": 1, "category_id": 5, "bbox": [400, 20, 40, 100], "vis_bbox": [400, 20, 40, 20], "iscrowd": 0},
+		{"id": 14, "image_id": 2, "category_id": 7, "bbox": [10, 20, 30, 90], "iscrowd": 0},
+		{"id": 15, "image_id": 3, "category_id": 5, "bbox": [10, 20, 30, 90], "iscrowd": 1}
+	],
+	"categories": [
+		{"id": 5, "name": "person"},
+		{"id": 7, "name": "car"}
+	]
+}`
+
+func TestLoad_coco(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	annotFile := path.Join(dir, "instances.json")
+	if err := ioutil.WriteFile(annotFile, []byte(cocoTestAnnot), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := json.Marshal(COCOSpec{
+		File:       annotFile,
+		ImageDir:   path.Join(dir, "images"),
+		Category:   "person",
+		MinArea:    100,
+		MinVisible: 0.5,
+		SplitField: "split",
+		TrainSplit: "train",
+		TestSplit:  "val",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := Load("coco", string(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims := dataset.Images()
+	if len(ims) != 3 || ims[0] != "a.jpg" || ims[1] != "b.jpg" || ims[2] != "c.jpg" {
+		t.Fatalf("images: want [a.jpg b.jpg c.jpg], got %v", ims)
+	}
+
+	annot := dataset.Annot("a.jpg")
+	wantInst := []image.Rectangle{image.Rect(10, 20, 40, 110)}
+	wantIgnore := []image.Rectangle{
+		image.Rect(100, 20, 300, 120),
+		image.Rect(300, 20, 305, 30),
+		image.Rect(400, 20, 440, 120),
+	}
+	if !rectsEq(wantInst, annot.Instances) {
+		t.Errorf("instances: want %v, got %v", wantInst, annot.Instances)
+	}
+	if !rectsEq(wantIgnore, annot.Ignore) {
+		t.Errorf("ignore: want %v, got %v", wantIgnore, annot.Ignore)
+	}
+
+	cases := []struct {
+		Name                     string
+		IsNeg, CanTrain, CanTest bool
+	}{
+		{"a.jpg", false, true, false},
+		{"b.jpg", true, false, true},
+		// Crowd region means image is not negative.
+		{"c.jpg", false, true, false},
+	}
+	for _, c := range cases {
+		if got := dataset.IsNeg(c.Name); got != c.IsNeg {
+			t.Errorf("%s: IsNeg: want %v, got %v", c.Name, c.IsNeg, got)
+		}
+		if got := dataset.CanTrain(c.Name); got != c.CanTrain {
+			t.Errorf("%s: CanTrain: want %v, got %v", c.Name, c.CanTrain, got)
+		}
+		if got := dataset.CanTest(c.Name); got != c.CanTest {
+			t.Errorf("%s: CanTest: want %v, got %v", c.Name, c.CanTest, got)
+		}
+	}
+}
+
+func TestLoad_cocoList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"instances.json": cocoTestAnnot,
+		"train.txt":      "1\n3\n",
+		"test.txt":       "2\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dataset, err := loadCOCO(COCOSpec{
+		File:      path.Join(dir, "instances.json"),
+		Category:  "person",
+		TrainList: path.Join(dir, "train.txt"),
+		TestList:  path.Join(dir, "test.txt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dataset.CanTrain("c.jpg") || dataset.CanTest("c.jpg") {
+		t.Error("c.jpg: want train only")
+	}
+	if dataset.CanTrain("b.jpg") || !dataset.CanTest("b.jpg") {
+		t.Error("b.jpg: want test only")
+	}
+}
+
+func TestLoad_cocoUnknownCategory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	annotFile := path.Join(dir, "instances.json")
+	if err := ioutil.WriteFile(annotFile, []byte(cocoTestAnnot), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCOCO(COCOSpec{File: annotFile, Category: "dog"}); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,329 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jvlmdr/go-file/fileutil"
+)
+
+func init() {
+	RegisterDataset("union",
+		func() interface{} { return new(UnionSpec) },
+		func(spec interface{}) (ImageSet, error) { return spec.(*UnionSpec).Load() },
+	)
+	RegisterDataset("subset",
+		func() interface{} { return new(SubsetSpec) },
+		func(spec interface{}) (ImageSet, error) { return spec.(*SubsetSpec).Load() },
+	)
+	RegisterDataset("sample",
+		func() interface{} { return new(SampleSpec) },
+		func(spec interface{}) (ImageSet, error) { return spec.(*SampleSpec).Load() },
+	)
+	RegisterDataset("override-flags",
+		func() interface{} { return new(OverrideSpec) },
+		func(spec interface{}) (ImageSet, error) { return spec.(*OverrideSpec).Load() },
+	)
+}
+
+// DatasetMessage identifies a registered dataset and its spec.
+// It is used to nest datasets within the specs of others, e.g.
+//
+//	{"Name": "inria", "Spec": {"Dir": "INRIAPerson", "Set": "Train"}}
+type DatasetMessage struct {
+	Name string
+	Spec json.RawMessage
+}
+
+func (m DatasetMessage) Load() (ImageSet, error) {
+	spec := string(m.Spec)
+	if spec == "" {
+		spec = "{}"
+	}
+	return Load(m.Name, spec)
+}
+
+// UnionSpec describes the union of several datasets.
+type UnionSpec struct {
+	Members []UnionMember
+}
+
+// UnionMember is a dataset in a union.
+type UnionMember struct {
+	// Prefix to add to image names.
+	// If empty, the name of the dataset is used.
+	Prefix  string
+	Dataset DatasetMessage
+}
+
+func (spec *UnionSpec) Load() (ImageSet, error) {
+	prefixes := make([]string, len(spec.Members))
+	sets := make([]ImageSet, len(spec.Members))
+	for i, m := range spec.Members {
+		prefixes[i] = m.Prefix
+		if prefixes[i] == "" {
+			prefixes[i] = m.Dataset.Name
+		}
+		set, err := m.Dataset.Load()
+		if err != nil {
+			return nil, fmt.Errorf("union member %s: %v", prefixes[i], err)
+		}
+		sets[i] = set
+	}
+	return Union(prefixes, sets)
+}
+
+// SubsetSpec describes a subset of the images in a dataset.
+// An image is kept if it satisfies every criterion which is given.
+type SubsetSpec struct {
+	Dataset DatasetMessage
+	// Names of images.
+	Names []string
+	// File containing one image name per line.
+	NamesFile string
+	// Pattern which names must match (see path.Match).
+	Glob string
+	// Regular expression which names must match.
+	Regexp string
+}
+
+func (spec *SubsetSpec) Load() (ImageSet, error) {
+	set, err := spec.Dataset.Load()
+	if err != nil {
+		return nil, err
+	}
+	var preds []func(string) bool
+	if len(spec.Names) > 0 {
+		preds = append(preds, inList(spec.Names))
+	}
+	if spec.NamesFile != "" {
+		names, err := fileutil.LoadLines(spec.NamesFile)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, inList(names))
+	}
+	if spec.Glob != "" {
+		// Check pattern.
+		if _, err := path.Match(spec.Glob, ""); err != nil {
+			return nil, fmt.Errorf("glob %q: %v", spec.Glob, err)
+		}
+		preds = append(preds, func(name string) bool {
+			match, _ := path.Match(spec.Glob, name)
+			return match
+		})
+	}
+	if spec.Regexp != "" {
+		re, err := regexp.Compile(spec.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, re.MatchString)
+	}
+	return Subset(set, func(name string) bool {
+		for _, pred := range preds {
+			if !pred(name) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+func inList(names []string) func(string) bool {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[strings.TrimSpace(name)] = true
+	}
+	return func(name string) bool { return m[name] }
+}
+
+// SampleSpec describes a random subset of the images in a dataset.
+type SampleSpec struct {
+	Dataset DatasetMessage
+	// Fraction of images to keep.
+	Fraction float64
+	Seed     int64
+}
+
+func (spec *SampleSpec) Load() (ImageSet, error) {
+	if spec.Fraction < 0 || spec.Fraction > 1 {
+		return nil, fmt.Errorf("fraction not in [0, 1]: %g", spec.Fraction)
+	}
+	set, err := spec.Dataset.Load()
+	if err != nil {
+		return nil, err
+	}
+	return Sample(set, spec.Fraction, spec.Seed), nil
+}
+
+// OverrideSpec replaces the flags of every image in a dataset.
+// Flags which are nil are not modified.
+type OverrideSpec struct {
+	Dataset DatasetMessage
+	Flags   Flags
+}
+
+// Flags to override.
+// Flags which are nil are not modified.
+type Flags struct {
+	IsNeg    *bool
+	CanTrain *bool
+	CanTest  *bool
+}
+
+func (spec *OverrideSpec) Load() (ImageSet, error) {
+	set, err := spec.Dataset.Load()
+	if err != nil {
+		return nil, err
+	}
+	return OverrideFlags(set, spec.Flags), nil
+}
+
+// Union returns a dataset containing the images of all sets.
+// The name of an image is the prefix of its set, a slash and
+// its name in the set.
+// Returns an error if the prefixes are not distinct.
+func Union(prefixes []string, sets []ImageSet) (ImageSet, error) {
+	if len(prefixes) != len(sets) {
+		panic("different number of prefixes and sets")
+	}
+	u := &unionDataset{index: make(map[string]int)}
+	for i, prefix := range prefixes {
+		if prefix == "" || strings.Contains(prefix, "/") {
+			return nil, fmt.Errorf("invalid prefix: %q", prefix)
+		}
+		if _, ok := u.index[prefix]; ok {
+			return nil, fmt.Errorf("prefix used twice: %s", prefix)
+		}
+		u.index[prefix] = i
+		for _, name := range sets[i].Images() {
+			u.ims = append(u.ims, prefix+"/"+name)
+		}
+	}
+	u.sets = sets
+	return u, nil
+}
+
+type unionDataset struct {
+	ims   []string
+	sets  []ImageSet
+	index map[string]int
+}
+
+// split returns the set and the name within it.
+func (u *unionDataset) split(name string) (ImageSet, string) {
+	i := strings.Index(name, "/")
+	if i < 0 {
+		panic(fmt.Sprintf("image name has no prefix: %s", name))
+	}
+	j, ok := u.index[name[:i]]
+	if !ok {
+		panic(fmt.Sprintf("unknown prefix: %s", name[:i]))
+	}
+	return u.sets[j], name[i+1:]
+}
+
+func (u *unionDataset) Images() []string {
+	return u.ims
+}
+
+func (u *unionDataset) File(name string) string {
+	set, name := u.split(name)
+	return set.File(name)
+}
+
+func (u *unionDataset) CanTrain(name string) bool {
+	set, name := u.split(name)
+	return set.CanTrain(name)
+}
+
+func (u *unionDataset) CanTest(name string) bool {
+	set, name := u.split(name)
+	return set.CanTest(name)
+}
+
+func (u *unionDataset) IsNeg(name string) bool {
+	set, name := u.split(name)
+	return set.IsNeg(name)
+}
+
+func (u *unionDataset) Annot(name string) Annot {
+	set, name := u.split(name)
+	return set.Annot(name)
+}
+
+// Subset returns a dataset containing the images for which keep is true.
+// The images remain in the same order.
+func Subset(set ImageSet, keep func(name string) bool) ImageSet {
+	var ims []string
+	for _, name := range set.Images() {
+		if keep(name) {
+			ims = append(ims, name)
+		}
+	}
+	return &subsetDataset{set, ims}
+}
+
+// Sample returns a dataset containing a random fraction of the images.
+// The same seed gives the same subset.
+// The images remain in the same order.
+func Sample(set ImageSet, frac float64, seed int64) ImageSet {
+	all := set.Images()
+	n := int(math.Floor(frac*float64(len(all)) + 0.5))
+	ind := rand.New(rand.NewSource(seed)).Perm(len(all))[:n]
+	sort.Ints(ind)
+	ims := make([]string, n)
+	for i, j := range ind {
+		ims[i] = all[j]
+	}
+	return &subsetDataset{set, ims}
+}
+
+type subsetDataset struct {
+	ImageSet
+	ims []string
+}
+
+func (d *subsetDataset) Images() []string {
+	return d.ims
+}
+
+// OverrideFlags returns a dataset whose flags are replaced.
+// The annotations are not modified,
+// therefore forcing IsNeg should only be done for images without instances.
+func OverrideFlags(set ImageSet, flags Flags) ImageSet {
+	return &overrideDataset{set, flags}
+}
+
+type overrideDataset struct {
+	ImageSet
+	flags Flags
+}
+
+func (d *overrideDataset) IsNeg(name string) bool {
+	if d.flags.IsNeg != nil {
+		return *d.flags.IsNeg
+	}
+	return d.ImageSet.IsNeg(name)
+}
+
+func (d *overrideDataset) CanTrain(name string) bool {
+	if d.flags.CanTrain != nil {
+		return *d.flags.CanTrain
+	}
+	return d.ImageSet.CanTrain(name)
+}
+
+func (d *overrideDataset) CanTest(name string) bool {
+	if d.flags.CanTest != nil {
+		return *d.flags.CanTest
+	}
+	return d.ImageSet.CanTest(name)
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	a := &testDataset{[]string{"x", "y"}}
+	b := &testDataset{[]string{"x", "z"}}
+	u, err := Union([]string{"a", "b"}, []ImageSet{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a/x", "a/y", "b/x", "b/z"}
+	if got := u.Images(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := u.File("b/z"); got != "z" {
+		t.Errorf("file: want z, got %s", got)
+	}
+	if _, err := Union([]string{"a", "a"}, []ImageSet{a, b}); err == nil {
+		t.Error("repeated prefix: expected error")
+	}
+}
+
+func TestSample(t *testing.T) {
+	var ims []string
+	for i := 0; i < 100; i++ {
+		ims = append(ims, fmt.Sprint(i))
+	}
+	set := &testDataset{ims}
+	x := Sample(set, 0.3, 1).Images()
+	if len(x) != 30 {
+		t.Errorf("want 30 images, got %d", len(x))
+	}
+	if y := Sample(set, 0.3, 1).Images(); !reflect.DeepEqual(x, y) {
+		t.Error("same seed gave different subsets")
+	}
+}
+
+func TestOverrideFlags(t *testing.T) {
+	no := false
+	set := OverrideFlags(&testDataset{[]string{"x"}}, Flags{IsNeg: &no})
+	if set.IsNeg("x") {
+		t.Error("IsNeg not overridden")
+	}
+	if !set.CanTrain("x") {
+		t.Error("CanTrain modified")
+	}
+}
+
+// Checks that specs can be nested.
+func TestLoad_nested(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := "file,train,test,neg,instances,ignore\n" +
+		"pos/a.png,1,1,0,0 0 10 20,\n" +
+		"pos/b.png,1,1,0,0 0 10 20,\n" +
+		"neg/c.png,1,1,1,,\n"
+	fname := path.Join(dir, "manifest.csv")
+	if err := ioutil.WriteFile(fname, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := fmt.Sprintf(`{
+		"Members": [
+			{
+				"Prefix": "pos",
+				"Dataset": {"Name": "subset", "Spec": {
+					"Glob": "pos/*",
+					"Dataset": {"Name": "manifest", "Spec": {"File": %[1]q, "NoValidate": true}}
+				}}
+			},
+			{
+				"Prefix": "neg",
+				"Dataset": {"Name": "override-flags", "Spec": {
+					"Flags": {"CanTest": false},
+					"Dataset": {"Name": "subset", "Spec": {
+						"Regexp": "^neg/",
+						"Dataset": {"Name": "manifest", "Spec": {"File": %[1]q, "NoValidate": true}}
+					}}
+				}}
+			}
+		]
+	}`, fname)
+	set, err := Load("union", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"pos/pos/a.png", "pos/pos/b.png", "neg/neg/c.png"}
+	if got := set.Images(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if set.CanTest("neg/neg/c.png") {
+		t.Error("CanTest not overridden")
+	}
+	if !set.IsNeg("neg/neg/c.png") {
+		t.Error("IsNeg modified")
+	}
+	if got := set.File("pos/pos/a.png"); got != path.Join(dir, "pos/a.png") {
+		t.Errorf("file: want %s, got %s", path.Join(dir, "pos/a.png"), got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
+	"sort"
+	"strings"
 
 	"github.com/jvlmdr/go-cv/dataset/caltechped"
 )
@@ -44,22 +46,90 @@ type Annot struct {
 	Ignore []image.Rectangle
 }
 
+// Load decodes the spec of a registered dataset and loads it.
 func Load(name, specJSON string) (ImageSet, error) {
-	switch name {
-	case "inria":
-		var spec INRIASpec
-		if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
-			return nil, err
-		}
-		return loadINRIA(spec)
-	case "caltech-preset":
-		var preset CaltechPreset
-		if err := json.Unmarshal([]byte(specJSON), &preset); err != nil {
-			return nil, err
-		}
-		spec := preset.Spec()
-		return loadCaltech(spec, caltechped.Reasonable)
-	default:
-		panic(fmt.Sprintf("unknown dataset: %s", name))
+	return DefaultDatasets.Load(name, specJSON)
+}
+
+// ListDatasets returns the names of the registered datasets in order.
+func ListDatasets() []string {
+	return DefaultDatasets.Names()
+}
+
+// RegisterDataset adds a dataset to DefaultDatasets.
+func RegisterDataset(name string, newSpec func() interface{}, load LoadFunc) {
+	DefaultDatasets.Register(name, newSpec, load)
+}
+
+var DefaultDatasets = NewDatasetFactory()
+
+func init() {
+	RegisterDataset("inria",
+		func() interface{} { return new(INRIASpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return loadINRIA(*spec.(*INRIASpec))
+		},
+	)
+	RegisterDataset("caltech-preset",
+		func() interface{} { return new(CaltechPreset) },
+		func(spec interface{}) (ImageSet, error) {
+			preset := spec.(*CaltechPreset)
+			return loadCaltech(preset.Spec(), filterRole(caltechped.Reasonable))
+		},
+	)
+}
+
+// LoadFunc loads a dataset from a spec.
+// The spec is the value returned by the newSpec function
+// with which the dataset was registered, after decoding JSON into it.
+type LoadFunc func(spec interface{}) (ImageSet, error)
+
+type datasetType struct {
+	newSpec func() interface{}
+	load    LoadFunc
+}
+
+// DatasetFactory maps names to datasets.
+type DatasetFactory struct {
+	types map[string]datasetType
+}
+
+func NewDatasetFactory() *DatasetFactory {
+	f := new(DatasetFactory)
+	f.types = make(map[string]datasetType)
+	return f
+}
+
+// Register adds a dataset to the factory.
+// The newSpec function must return a pointer into which JSON can be decoded.
+// Panics if the name is already registered.
+func (f *DatasetFactory) Register(name string, newSpec func() interface{}, load LoadFunc) {
+	if _, ok := f.types[name]; ok {
+		panic(fmt.Sprintf("dataset already registered: %s", name))
+	}
+	f.types[name] = datasetType{newSpec, load}
+}
+
+// Load decodes the spec and loads the dataset.
+// Returns an error if the name is not registered.
+func (f *DatasetFactory) Load(name, specJSON string) (ImageSet, error) {
+	typ, ok := f.types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dataset: %q (registered: %s)", name, strings.Join(f.Names(), ", "))
+	}
+	spec := typ.newSpec()
+	if err := json.Unmarshal([]byte(specJSON), spec); err != nil {
+		return nil, fmt.Errorf("decode spec of dataset %s: %v", name, err)
+	}
+	return typ.load(spec)
+}
+
+// Names returns the names of the registered datasets in order.
+func (f *DatasetFactory) Names() []string {
+	var names []string
+	for name := range f.types {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
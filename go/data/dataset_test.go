@@ -0,0 +1,69 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+type testSpec struct {
+	Images []string
+}
+
+type testDataset struct {
+	ims []string
+}
+
+func (d *testDataset) Images() []string        { return d.ims }
+func (d *testDataset) File(im string) string   { return im }
+func (d *testDataset) CanTrain(im string) bool { return true }
+func (d *testDataset) CanTest(im string) bool  { return true }
+func (d *testDataset) IsNeg(im string) bool    { return true }
+func (d *testDataset) Annot(im string) Annot   { return Annot{} }
+
+func newTestFactory() *DatasetFactory {
+	f := NewDatasetFactory()
+	f.Register("test",
+		func() interface{} { return new(testSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return &testDataset{spec.(*testSpec).Images}, nil
+		},
+	)
+	return f
+}
+
+func TestDatasetFactory_Load(t *testing.T) {
+	f := newTestFactory()
+	dataset, err := f.Load("test", `{"Images": ["a", "b"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ims := dataset.Images(); len(ims) != 2 || ims[0] != "a" || ims[1] != "b" {
+		t.Errorf("want [a b], got %v", ims)
+	}
+}
+
+func TestDatasetFactory_Load_unknown(t *testing.T) {
+	f := newTestFactory()
+	_, err := f.Load("foo", "{}")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "test") {
+		t.Errorf("error does not list registered datasets: %v", err)
+	}
+}
+
+func TestListDatasets(t *testing.T) {
+	names := ListDatasets()
+	for _, want := range []string{"caltech-preset", "inria"} {
+		var found bool
+		for _, name := range names {
+			if name == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("dataset not registered: %s", want)
+		}
+	}
+}
@@ -16,54 +16,133 @@ import (
 // Examples extracts windows from the image, resizes them to
 // the given size and computes their feature transform.
 // Does not check dataset.CanTrain or CanTest.
+// Images are processed by the number of goroutines in CachesOf(dataset).
+// The examples are in the same order as the images.
 func Examples(ims []string, rects map[string][]image.Rectangle, dataset ImageSet, phi feat.Image, extend imsamp.At, shape detect.PadRect, addFlip bool, interp resize.InterpolationFunction) ([]*rimg64.Multi, error) {
-	var examples []*rimg64.Multi
-	for _, name := range ims {
-		log.Println("load image:", name)
-		t := time.Now()
-		file := dataset.File(name)
-		im, err := loadImage(file)
+	return AugmentedExamples(ims, rects, dataset, phi, extend, shape, addFlip, nil, interp)
+}
+
+// AugmentedExamples is like Examples but adds aug.Copies
+// perturbed copies of every example.
+// Each example is followed by its copies.
+// If addFlip is true, the copies are flipped as well.
+// If aug is nil, no copies are added.
+func AugmentedExamples(ims []string, rects map[string][]image.Rectangle, dataset ImageSet, phi feat.Image, extend imsamp.At, shape detect.PadRect, addFlip bool, aug *Augment, interp resize.InterpolationFunction) ([]*rimg64.Multi, error) {
+	t := time.Now()
+	workers := numWorkers(dataset)
+	out := make([][]*rimg64.Multi, len(ims))
+	durs := make([]exampleDur, len(ims))
+	err := forEach(len(ims), workers, func(i int) error {
+		x, dur, err := imageExamples(ims[i], rects[ims[i]], dataset, phi, extend, shape, addFlip, aug, interp)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		durLoad := time.Since(t)
-		var durSamp, durResize, durFlip, durFeat time.Duration
-		for _, rect := range rects[name] {
-			// Extract and resize window.
-			t = time.Now()
-			subim := imsamp.Rect(im, rect, extend)
-			durSamp += time.Since(t)
+		out[i], durs[i] = x, dur
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var examples []*rimg64.Multi
+	var total exampleDur
+	for i := range out {
+		examples = append(examples, out[i]...)
+		total = total.Plus(durs[i])
+	}
+	log.Printf(
+		"examples from %d images in %v (%d workers): load %v, sample %v, resize %v, augment %v, flip %v, feat %v",
+		len(ims), time.Since(t), workers, total.Load, total.Samp, total.Resize, total.Aug, total.Flip, total.Feat,
+	)
+	return examples, nil
+}
+
+// exampleDur is the time spent in each stage of example extraction.
+type exampleDur struct {
+	Load, Samp, Resize, Aug, Flip, Feat time.Duration
+}
+
+func (a exampleDur) Plus(b exampleDur) exampleDur {
+	a.Load += b.Load
+	a.Samp += b.Samp
+	a.Resize += b.Resize
+	a.Aug += b.Aug
+	a.Flip += b.Flip
+	a.Feat += b.Feat
+	return a
+}
+
+func imageExamples(name string, rects []image.Rectangle, dataset ImageSet, phi feat.Image, extend imsamp.At, shape detect.PadRect, addFlip bool, aug *Augment, interp resize.InterpolationFunction) ([]*rimg64.Multi, exampleDur, error) {
+	var dur exampleDur
+	log.Println("load image:", name)
+	t := time.Now()
+	file := dataset.File(name)
+	im, err := CachesOf(dataset).Images.Image(file)
+	if err != nil {
+		return nil, dur, err
+	}
+	dur.Load = time.Since(t)
+	var examples []*rimg64.Multi
+	for j, rect := range rects {
+		// Extract and resize window.
+		t = time.Now()
+		subim := imsamp.Rect(im, rect, extend)
+		dur.Samp += time.Since(t)
+		t = time.Now()
+		subim = resize.Resize(uint(shape.Size.X), uint(shape.Size.Y), subim, interp)
+		dur.Resize += time.Since(t)
+		subims := []image.Image{subim}
+		if aug != nil && aug.Copies > 0 {
 			t = time.Now()
-			subim = resize.Resize(uint(shape.Size.X), uint(shape.Size.Y), subim, interp)
-			durResize += time.Since(t)
-			// Add flip if desired.
-			flips := []bool{false}
-			if addFlip {
-				flips = []bool{false, true}
+			r := aug.rand(name, j)
+			for k := 0; k < aug.Copies; k++ {
+				subims = append(subims, augmentRect(im, rect, extend, shape, aug.sample(r), interp))
 			}
+			dur.Aug += time.Since(t)
+		}
+		// Add flip if desired.
+		flips := []bool{false}
+		if addFlip {
+			flips = []bool{false, true}
+		}
+		for _, subim := range subims {
 			for _, flip := range flips {
 				pix := subim
 				t = time.Now()
 				if flip {
 					pix = flipImageX(subim)
 				}
-				durFlip += time.Since(t)
+				dur.Flip += time.Since(t)
 				t = time.Now()
 				x, err := phi.Apply(pix)
 				if err != nil {
-					return nil, err
+					return nil, dur, err
 				}
-				durFeat += time.Since(t)
+				dur.Feat += time.Since(t)
 				examples = append(examples, x)
 			}
 		}
-		log.Printf(
-			"load %.3gms, sample %.3gms, resize %.3gms, flip %.3gms, feat %.3gms",
-			durLoad.Seconds()*1000, durSamp.Seconds()*1000, durResize.Seconds()*1000,
-			durFlip.Seconds()*1000, durFeat.Seconds()*1000,
-		)
 	}
-	return examples, nil
+	log.Printf(
+		"load %.3gms, sample %.3gms, resize %.3gms, augment %.3gms, flip %.3gms, feat %.3gms",
+		dur.Load.Seconds()*1000, dur.Samp.Seconds()*1000, dur.Resize.Seconds()*1000,
+		dur.Aug.Seconds()*1000, dur.Flip.Seconds()*1000, dur.Feat.Seconds()*1000,
+	)
+	return examples, dur, nil
+}
+
+// augmentRect samples a perturbed window from the image
+// and resizes it to the given size.
+func augmentRect(im image.Image, rect image.Rectangle, extend imsamp.At, shape detect.PadRect, p perturb, interp resize.InterpolationFunction) image.Image {
+	rect = jitterRect(rect, p)
+	var subim image.Image
+	if p.Angle != 0 {
+		subim = rotateRect(im, rect, p.Angle)
+	} else {
+		subim = imsamp.Rect(im, rect, extend)
+	}
+	subim = resize.Resize(uint(shape.Size.X), uint(shape.Size.Y), subim, interp)
+	subim = blur(subim, p.Sigma)
+	return photometric(subim, p)
 }
 
 func flipImageX(src image.Image) image.Image {
@@ -82,34 +161,59 @@ func flipImageX(src image.Image) image.Image {
 // the set of all windows in the feature image.
 // Does not check dataset.CanTrain or CanTest.
 // Window size and stride are specified in feature pixels.
+// Images are processed by the number of goroutines in CachesOf(dataset).
+// The sets are in the same order as the images.
 func WindowSets(ims []string, dataset ImageSet, phi feat.Image, pad feat.Pad, size image.Point, stride int, interp resize.InterpolationFunction) ([]imset.Set, error) {
-	var sets []imset.Set
-	for _, name := range ims {
-		log.Println("load image:", name)
-		t := time.Now()
-		file := dataset.File(name)
-		im, err := loadImage(file)
-		if err != nil {
-			return nil, err
-		}
-		durLoad := time.Since(t)
-		t = time.Now()
-		// Take transform of entire image.
-		x, err := feat.ApplyPad(phi, im, pad)
+	t := time.Now()
+	workers := numWorkers(dataset)
+	sets := make([]imset.Set, len(ims))
+	durs := make([]exampleDur, len(ims))
+	err := forEach(len(ims), workers, func(i int) error {
+		set, dur, err := imageWindowSet(ims[i], dataset, phi, pad, size, stride, interp)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		durFeat := time.Since(t)
-		set := new(imset.WindowSet)
-		set.Image = x
-		set.Size = size
-		for u := 0; u < x.Width-size.X+1; u += stride {
-			for v := 0; v < x.Height-size.Y+1; v += stride {
-				set.Windows = append(set.Windows, image.Pt(u, v))
-			}
-		}
-		sets = append(sets, set)
-		log.Printf("load %.3gms, feat %.3gms", durLoad.Seconds()*1000, durFeat.Seconds()*1000)
+		sets[i], durs[i] = set, dur
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var total exampleDur
+	for _, dur := range durs {
+		total = total.Plus(dur)
 	}
+	log.Printf(
+		"window sets from %d images in %v (%d workers): load %v, feat %v",
+		len(ims), time.Since(t), workers, total.Load, total.Feat,
+	)
 	return sets, nil
 }
+
+func imageWindowSet(name string, dataset ImageSet, phi feat.Image, pad feat.Pad, size image.Point, stride int, interp resize.InterpolationFunction) (*imset.WindowSet, exampleDur, error) {
+	var dur exampleDur
+	log.Println("load image:", name)
+	t := time.Now()
+	im, err := CachesOf(dataset).Images.Image(dataset.File(name))
+	if err != nil {
+		return nil, dur, err
+	}
+	dur.Load = time.Since(t)
+	t = time.Now()
+	// Take transform of entire image.
+	x, err := feat.ApplyPad(phi, im, pad)
+	if err != nil {
+		return nil, dur, err
+	}
+	dur.Feat = time.Since(t)
+	set := new(imset.WindowSet)
+	set.Image = x
+	set.Size = size
+	for u := 0; u < x.Width-size.X+1; u += stride {
+		for v := 0; v < x.Height-size.Y+1; v += stride {
+			set.Windows = append(set.Windows, image.Pt(u, v))
+		}
+	}
+	log.Printf("load %.3gms, feat %.3gms", dur.Load.Seconds()*1000, dur.Feat.Seconds()*1000)
+	return set, dur, nil
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/imsamp"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/nfnt/resize"
+)
+
+// sumFeat is a feature transform which gives the sum of each row.
+type sumFeat struct{}
+
+func (sumFeat) Rate() int                              { return 1 }
+func (sumFeat) Size(x image.Point) image.Point         { return image.Pt(1, x.Y) }
+func (sumFeat) MinInputSize(x image.Point) image.Point { return image.Pt(1, x.Y) }
+func (sumFeat) Channels() int                          { return 1 }
+
+func (sumFeat) Apply(im image.Image) (*rimg64.Multi, error) {
+	r := im.Bounds()
+	x := rimg64.NewMulti(1, r.Dy(), 1)
+	for j := 0; j < r.Dy(); j++ {
+		var s float64
+		for i := 0; i < r.Dx(); i++ {
+			s += float64(color.GrayModel.Convert(im.At(r.Min.X+i, r.Min.Y+j)).(color.Gray).Y)
+		}
+		x.Set(0, j, 0, s)
+	}
+	return x, nil
+}
+
+func writeRandImage(t *testing.T, fname string, width, height, seed int) {
+	im := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range im.Pix {
+		im.Pix[i] = uint8((i*7 + seed*13) % 256)
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, im); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeExtractTestImages(t *testing.T, dir string, n int) []string {
+	var ims []string
+	for i := 0; i < n; i++ {
+		fname := path.Join(dir, fmt.Sprintf("%d.png", i))
+		writeRandImage(t, fname, 16+i, 24+2*i, i)
+		ims = append(ims, fname)
+	}
+	return ims
+}
+
+// Checks that concurrent extraction gives the same result as sequential.
+func TestExamples_parallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 12)
+	rects := make(map[string][]image.Rectangle)
+	for i, im := range ims {
+		// Leave some images without rectangles.
+		for j := 0; j < i%3; j++ {
+			rects[im] = append(rects[im], image.Rect(j, j, j+8, j+16))
+		}
+	}
+	dataset := &testDataset{ims}
+	shape := detect.PadRect{Size: image.Pt(8, 16), Int: image.Rect(0, 0, 8, 16)}
+
+	want, err := Examples(ims, rects, withWorkers(dataset, 1), sumFeat{}, imsamp.Continue, shape, true, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Examples(ims, rects, withWorkers(dataset, 5), sumFeat{}, imsamp.Continue, shape, true, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) == 0 {
+		t.Fatal("no examples")
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Error("parallel examples differ from sequential")
+	}
+}
+
+func withWorkers(dataset ImageSet, n int) ImageSet {
+	return WithCaches(dataset, &Caches{Images: DiskImages{}, Pyrs: NewFeatPyrCache(0), Workers: n})
+}
+
+func TestWindowSets_parallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 12)
+	dataset := &testDataset{ims}
+
+	want, err := WindowSets(ims, withWorkers(dataset, 1), sumFeat{}, feat.Pad{}, image.Pt(1, 8), 2, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := WindowSets(ims, withWorkers(dataset, 5), sumFeat{}, feat.Pad{}, image.Pt(1, 8), 2, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(ims) {
+		t.Fatalf("want %d sets, got %d", len(ims), len(got))
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Error("parallel window sets differ from sequential")
+	}
+}
+
+func TestForEach_error(t *testing.T) {
+	errBad := errors.New("bad")
+	err := forEach(100, 4, func(i int) error {
+		if i == 10 {
+			return errBad
+		}
+		return nil
+	})
+	if err != errBad {
+		t.Errorf("want %v, got %v", errBad, err)
+	}
+}
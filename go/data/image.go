@@ -7,7 +7,18 @@ import (
 	"os"
 )
 
-func loadImage(name string) (image.Image, error) {
+// LoadImage decodes an image from disk.
+// Use CachesOf to load the images of a dataset.
+func LoadImage(name string) (image.Image, error) {
+	return decodeImage(name)
+}
+
+// LoadImageSize reads the dimensions of an image from disk.
+func LoadImageSize(name string) (image.Point, error) {
+	return decodeImageSize(name)
+}
+
+func decodeImage(name string) (image.Image, error) {
 	file, err := os.Open(name)
 	if err != nil {
 		return nil, err
@@ -20,7 +31,7 @@ func loadImage(name string) (image.Image, error) {
 	return im, nil
 }
 
-func loadImageSize(name string) (image.Point, error) {
+func decodeImageSize(name string) (image.Point, error) {
 	file, err := os.Open(name)
 	if err != nil {
 		return image.ZP, err
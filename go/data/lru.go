@@ -0,0 +1,70 @@
+package data
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a least-recently-used cache whose capacity is
+// measured as the total cost of its elements.
+// If max is zero, nothing is stored.
+type lru struct {
+	max   int64
+	total int64
+	mu    sync.Mutex
+	order *list.List
+	elems map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+	cost  int64
+}
+
+func newLRU(max int64) *lru {
+	return &lru{max: max, order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (c *lru) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.elems[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Add inserts an element and evicts the least-recently-used elements
+// until the total cost is within the limit.
+// Elements which cost more than the limit are not stored.
+func (c *lru) Add(key string, value interface{}, cost int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cost > c.max {
+		return
+	}
+	if e, ok := c.elems[key]; ok {
+		c.total -= e.Value.(*lruEntry).cost
+		c.order.Remove(e)
+		delete(c.elems, key)
+	}
+	c.elems[key] = c.order.PushFront(&lruEntry{key, value, cost})
+	c.total += cost
+	for c.total > c.max {
+		e := c.order.Back()
+		x := e.Value.(*lruEntry)
+		c.order.Remove(e)
+		delete(c.elems, x.key)
+		c.total -= x.cost
+	}
+}
+
+// Len returns the number of elements in the cache.
+func (c *lru) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,38 @@
+package data
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	c := newLRU(10)
+	c.Add("a", 1, 4)
+	c.Add("b", 2, 4)
+	// Access a so that b is least recently used.
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a not found")
+	}
+	c.Add("c", 3, 4)
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	// Too large to store.
+	c.Add("d", 4, 11)
+	if _, ok := c.Get("d"); ok {
+		t.Error("found element larger than capacity")
+	}
+	if c.Len() != 2 {
+		t.Errorf("want 2 elements, got %d", c.Len())
+	}
+}
+
+func TestLRU_zero(t *testing.T) {
+	c := newLRU(0)
+	c.Add("a", 1, 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("zero-capacity cache stored element")
+	}
+}
@@ -0,0 +1,371 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterDataset("manifest",
+		func() interface{} { return new(ManifestSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return loadManifest(*spec.(*ManifestSpec))
+		},
+	)
+}
+
+// ManifestSpec describes a dataset which is listed in a file
+// with one line per image.
+//
+// The file can be CSV, with header
+//
+//	file,train,test,neg,instances,ignore
+//
+// where boxes are "x0 y0 x1 y1" separated by ";", e.g.
+//
+//	set00/I00029.jpg,1,0,0,10 20 40 110;50 20 80 110,
+//
+// or JSON lines, each a ManifestEntry, e.g.
+//
+//	{"File": "set00/I00029.jpg", "Train": true, "Instances": [[10, 20, 40, 110]]}
+type ManifestSpec struct {
+	// Manifest file with extension "csv" or "jsonl".
+	File string
+	// Directory to which the paths are relative.
+	// If empty, the directory containing File.
+	Dir string
+	// Do not check that every image exists and
+	// that every box lies within the bounds of its image.
+	NoValidate bool
+}
+
+// ManifestEntry is the description of one image in a manifest.
+type ManifestEntry struct {
+	// Path relative to the root directory.
+	// Also the name of the image.
+	File        string
+	Train, Test bool
+	Neg         bool
+	Instances   []Box `json:",omitempty"`
+	Ignore      []Box `json:",omitempty"`
+}
+
+// Box is a rectangle [x0, y0, x1, y1].
+type Box [4]int
+
+func BoxFromRect(r image.Rectangle) Box {
+	return Box{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y}
+}
+
+func (b Box) Rect() image.Rectangle {
+	return image.Rect(b[0], b[1], b[2], b[3])
+}
+
+type manifestDataset struct {
+	dir     string
+	ims     []string
+	entries map[string]ManifestEntry
+}
+
+func loadManifest(spec ManifestSpec) (ImageSet, error) {
+	entries, err := LoadManifest(spec.File)
+	if err != nil {
+		return nil, err
+	}
+	d := new(manifestDataset)
+	d.dir = spec.Dir
+	if d.dir == "" {
+		d.dir = path.Dir(spec.File)
+	}
+	d.entries = make(map[string]ManifestEntry, len(entries))
+	for _, e := range entries {
+		if _, ok := d.entries[e.File]; ok {
+			return nil, fmt.Errorf("image listed twice: %s", e.File)
+		}
+		d.ims = append(d.ims, e.File)
+		d.entries[e.File] = e
+	}
+	if !spec.NoValidate {
+		if err := d.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// validate checks that all images exist and contain their boxes.
+func (d *manifestDataset) validate() error {
+	for _, name := range d.ims {
+		size, err := LoadImageSize(d.File(name))
+		if err != nil {
+			return fmt.Errorf("image %s: %v", name, err)
+		}
+		bounds := image.Rectangle{Max: size}
+		e := d.entries[name]
+		for _, boxes := range [][]Box{e.Instances, e.Ignore} {
+			for _, b := range boxes {
+				if !b.Rect().In(bounds) {
+					return fmt.Errorf("image %s: box %v outside bounds %v", name, b.Rect(), bounds)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (d *manifestDataset) Images() []string {
+	return d.ims
+}
+
+func (d *manifestDataset) File(name string) string {
+	return path.Join(d.dir, name)
+}
+
+func (d *manifestDataset) CanTrain(name string) bool {
+	return d.entries[name].Train
+}
+
+func (d *manifestDataset) CanTest(name string) bool {
+	return d.entries[name].Test
+}
+
+func (d *manifestDataset) IsNeg(name string) bool {
+	return d.entries[name].Neg
+}
+
+func (d *manifestDataset) Annot(name string) Annot {
+	e := d.entries[name]
+	return Annot{Instances: boxesToRects(e.Instances), Ignore: boxesToRects(e.Ignore)}
+}
+
+// LoadManifest reads a manifest file.
+// The format is determined by the extension.
+func LoadManifest(fname string) ([]ManifestEntry, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch ext := path.Ext(fname); ext {
+	case ".csv":
+		return ReadManifestCSV(file)
+	case ".jsonl":
+		return ReadManifestJSONL(file)
+	default:
+		return nil, fmt.Errorf("unknown manifest extension: %q", ext)
+	}
+}
+
+// SaveManifest exports a dataset to a manifest file.
+// The format is determined by the extension.
+// Paths are made relative to dir.
+// Note that some datasets contain boxes which extend beyond the image,
+// which must be loaded with NoValidate.
+func SaveManifest(fname string, dataset ImageSet, dir string) error {
+	entries, err := ManifestEntries(dataset, dir)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	switch ext := path.Ext(fname); ext {
+	case ".csv":
+		err = WriteManifestCSV(w, entries)
+	case ".jsonl":
+		err = WriteManifestJSONL(w, entries)
+	default:
+		err = fmt.Errorf("unknown manifest extension: %q", ext)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// ManifestEntries describes every image in a dataset.
+// Paths are made relative to dir.
+func ManifestEntries(dataset ImageSet, dir string) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	for _, name := range dataset.Images() {
+		file, err := filepath.Rel(dir, dataset.File(name))
+		if err != nil {
+			return nil, err
+		}
+		annot := dataset.Annot(name)
+		entries = append(entries, ManifestEntry{
+			File:      filepath.ToSlash(file),
+			Train:     dataset.CanTrain(name),
+			Test:      dataset.CanTest(name),
+			Neg:       dataset.IsNeg(name),
+			Instances: rectsToBoxes(annot.Instances),
+			Ignore:    rectsToBoxes(annot.Ignore),
+		})
+	}
+	return entries, nil
+}
+
+var manifestHeader = []string{"file", "train", "test", "neg", "instances", "ignore"}
+
+func ReadManifestCSV(r io.Reader) ([]ManifestEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(manifestHeader, ",") {
+		return nil, fmt.Errorf("expect header: %s", strings.Join(manifestHeader, ","))
+	}
+	var entries []ManifestEntry
+	for i, rec := range records[1:] {
+		e, err := parseManifestRecord(rec)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseManifestRecord(rec []string) (ManifestEntry, error) {
+	var e ManifestEntry
+	if len(rec) != len(manifestHeader) {
+		return e, fmt.Errorf("expect %d fields: got %d", len(manifestHeader), len(rec))
+	}
+	e.File = rec[0]
+	flags := []*bool{&e.Train, &e.Test, &e.Neg}
+	for i, flag := range flags {
+		x, err := strconv.ParseBool(rec[i+1])
+		if err != nil {
+			return e, fmt.Errorf("field %s: %v", manifestHeader[i+1], err)
+		}
+		*flag = x
+	}
+	var err error
+	if e.Instances, err = parseBoxes(rec[4]); err != nil {
+		return e, fmt.Errorf("field instances: %v", err)
+	}
+	if e.Ignore, err = parseBoxes(rec[5]); err != nil {
+		return e, fmt.Errorf("field ignore: %v", err)
+	}
+	return e, nil
+}
+
+func parseBoxes(s string) ([]Box, error) {
+	var boxes []Box
+	for _, str := range strings.Split(s, ";") {
+		fields := strings.Fields(str)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("box has %d coordinates: %q", len(fields), str)
+		}
+		var b Box
+		for i, field := range fields {
+			x, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, err
+			}
+			b[i] = x
+		}
+		boxes = append(boxes, b)
+	}
+	return boxes, nil
+}
+
+func formatBoxes(boxes []Box) string {
+	strs := make([]string, len(boxes))
+	for i, b := range boxes {
+		strs[i] = fmt.Sprintf("%d %d %d %d", b[0], b[1], b[2], b[3])
+	}
+	return strings.Join(strs, ";")
+}
+
+func WriteManifestCSV(w io.Writer, entries []ManifestEntry) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(manifestHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		rec := []string{
+			e.File,
+			formatFlag(e.Train), formatFlag(e.Test), formatFlag(e.Neg),
+			formatBoxes(e.Instances), formatBoxes(e.Ignore),
+		}
+		if err := c.Write(rec); err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+func formatFlag(x bool) string {
+	if x {
+		return "1"
+	}
+	return "0"
+}
+
+func ReadManifestJSONL(r io.Reader) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var e ManifestEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func WriteManifestJSONL(w io.Writer, entries []ManifestEntry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rectsToBoxes(rs []image.Rectangle) []Box {
+	if len(rs) == 0 {
+		return nil
+	}
+	boxes := make([]Box, len(rs))
+	for i, r := range rs {
+		boxes[i] = BoxFromRect(r)
+	}
+	return boxes
+}
+
+func boxesToRects(boxes []Box) []image.Rectangle {
+	if len(boxes) == 0 {
+		return nil
+	}
+	rs := make([]image.Rectangle, len(boxes))
+	for i, b := range boxes {
+		rs[i] = b.Rect()
+	}
+	return rs
+}
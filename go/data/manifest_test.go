@@ -0,0 +1,107 @@
+package data
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func writeTestImage(t *testing.T, fname string, width, height int) {
+	if err := os.MkdirAll(path.Dir(fname), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Checks that a dataset is unchanged by exporting and loading it.
+func TestSaveManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestImage(t, path.Join(dir, "images", "a.png"), 64, 128)
+	writeTestImage(t, path.Join(dir, "images", "b.png"), 64, 128)
+
+	want := &manifestDataset{
+		dir: dir,
+		ims: []string{"images/a.png", "images/b.png"},
+		entries: map[string]ManifestEntry{
+			"images/a.png": {
+				File:      "images/a.png",
+				Train:     true,
+				Instances: []Box{{1, 2, 30, 100}, {20, 10, 60, 120}},
+				Ignore:    []Box{{0, 0, 10, 10}},
+			},
+			"images/b.png": {File: "images/b.png", Test: true, Neg: true},
+		},
+	}
+
+	for _, ext := range []string{"csv", "jsonl"} {
+		fname := path.Join(dir, "manifest."+ext)
+		if err := SaveManifest(fname, want, dir); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadManifest(ManifestSpec{File: fname})
+		if err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		if !reflect.DeepEqual(want.Images(), got.Images()) {
+			t.Errorf("%s: images: want %v, got %v", ext, want.Images(), got.Images())
+		}
+		for _, name := range want.Images() {
+			if want.File(name) != got.File(name) {
+				t.Errorf("%s: %s: file: want %s, got %s", ext, name, want.File(name), got.File(name))
+			}
+			if want.CanTrain(name) != got.CanTrain(name) || want.CanTest(name) != got.CanTest(name) || want.IsNeg(name) != got.IsNeg(name) {
+				t.Errorf("%s: %s: flags differ", ext, name)
+			}
+			if !reflect.DeepEqual(want.Annot(name), got.Annot(name)) {
+				t.Errorf("%s: %s: annot: want %v, got %v", ext, name, want.Annot(name), got.Annot(name))
+			}
+		}
+	}
+}
+
+func TestLoadManifest_validate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestImage(t, path.Join(dir, "a.png"), 64, 128)
+
+	cases := []struct {
+		Name    string
+		Content string
+		Valid   bool
+	}{
+		{"ok", "file,train,test,neg,instances,ignore\na.png,1,1,0,0 0 64 128,\n", true},
+		{"outside", "file,train,test,neg,instances,ignore\na.png,1,1,0,0 0 65 128,\n", false},
+		{"missing", "file,train,test,neg,instances,ignore\nb.png,1,1,1,,\n", false},
+		{"no-header", "a.png,1,1,0,,\n", false},
+	}
+	for _, c := range cases {
+		fname := path.Join(dir, c.Name+".csv")
+		if err := ioutil.WriteFile(fname, []byte(c.Content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadManifest(ManifestSpec{File: fname})
+		if c.Valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.Name, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("%s: expected error", c.Name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"runtime"
+	"sync"
+)
+
+// numWorkers returns the number of goroutines with which
+// to process the images of a dataset.
+func numWorkers(dataset ImageSet) int {
+	if n := CachesOf(dataset).Workers; n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// forEach calls f(i) for i = 0, ..., n-1 using the given number of goroutines.
+// Once a call returns an error, no further calls are started.
+// Returns the error of the first (lowest index) call which failed.
+func forEach(n, workers int, f func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mu.Lock()
+				stop := failed
+				mu.Unlock()
+				if stop {
+					continue
+				}
+				if err := f(i); err != nil {
+					errs[i] = err
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ImageProvider loads images and their dimensions from files.
+type ImageProvider interface {
+	Image(file string) (image.Image, error)
+	Size(file string) (image.Point, error)
+}
+
+// DiskImages decodes every image from disk when it is requested.
+type DiskImages struct{}
+
+func (DiskImages) Image(file string) (image.Image, error) {
+	return decodeImage(file)
+}
+
+func (DiskImages) Size(file string) (image.Point, error) {
+	return decodeImageSize(file)
+}
+
+// CachedImages keeps recently decoded images in memory
+// and remembers the dimensions of every image it has seen.
+// Image sizes can be persisted to a file using LoadSizes.
+type CachedImages struct {
+	ims *lru
+
+	mu    sync.Mutex
+	sizes map[string]image.Point
+	// File to which new sizes are appended.
+	sizeFile io.WriteCloser
+}
+
+// NewCachedImages returns a provider which keeps
+// at most maxPixels pixels of decoded images in memory.
+func NewCachedImages(maxPixels int64) *CachedImages {
+	return &CachedImages{
+		ims:   newLRU(maxPixels),
+		sizes: make(map[string]image.Point),
+	}
+}
+
+// LoadSizes reads the image sizes in fname, if it exists,
+// and appends the size of every subsequent image to it.
+// Each line contains the file name, width and height separated by tabs.
+// Malformed lines (e.g. from tasks which were killed or which appended
+// to the file concurrently) are skipped.
+func (c *CachedImages) LoadSizes(fname string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.readSizes(fname); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if c.sizeFile != nil {
+		c.sizeFile.Close()
+	}
+	c.sizeFile = w
+	log.Printf("loaded %d image sizes from %s", len(c.sizes), fname)
+	return nil
+}
+
+func (c *CachedImages) readSizes(fname string) error {
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		name, size, err := parseSizeLine(line)
+		if err != nil {
+			log.Printf("skip line %d of %s: %v", n, fname, err)
+			continue
+		}
+		c.sizes[name] = size
+	}
+	return scanner.Err()
+}
+
+func parseSizeLine(line string) (string, image.Point, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 3 {
+		return "", image.ZP, fmt.Errorf("want 3 fields, found %d: %q", len(fields), line)
+	}
+	w, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", image.ZP, err
+	}
+	h, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", image.ZP, err
+	}
+	return fields[0], image.Pt(w, h), nil
+}
+
+// Close closes the size file, if any.
+func (c *CachedImages) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sizeFile == nil {
+		return nil
+	}
+	err := c.sizeFile.Close()
+	c.sizeFile = nil
+	return err
+}
+
+func (c *CachedImages) Image(file string) (image.Image, error) {
+	if x, ok := c.ims.Get(file); ok {
+		return x.(image.Image), nil
+	}
+	im, err := decodeImage(file)
+	if err != nil {
+		return nil, err
+	}
+	size := im.Bounds().Size()
+	c.ims.Add(file, im, int64(size.X*size.Y))
+	if err := c.addSize(file, size); err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+func (c *CachedImages) Size(file string) (image.Point, error) {
+	c.mu.Lock()
+	size, ok := c.sizes[file]
+	c.mu.Unlock()
+	if ok {
+		return size, nil
+	}
+	size, err := decodeImageSize(file)
+	if err != nil {
+		return image.ZP, err
+	}
+	if err := c.addSize(file, size); err != nil {
+		return image.ZP, err
+	}
+	return size, nil
+}
+
+func (c *CachedImages) addSize(file string, size image.Point) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.sizes[file]; ok {
+		return nil
+	}
+	c.sizes[file] = size
+	if c.sizeFile == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(c.sizeFile, "%s\t%d\t%d\n", file, size.X, size.Y)
+	return err
+}
+
+// Caches holds the images and feature pyramids of a dataset
+// and the number of goroutines with which to process its images.
+type Caches struct {
+	Images ImageProvider
+	Pyrs   *FeatPyrCache
+	// Number of goroutines used by Examples and WindowSets.
+	// Zero means the number of CPUs.
+	Workers int
+}
+
+// NoCaches decodes every image and computes every pyramid when it is requested.
+// It is used for datasets which were not given caches by WithCaches.
+var NoCaches = &Caches{Images: DiskImages{}, Pyrs: NewFeatPyrCache(0)}
+
+// Close closes the size file of the image cache, if any.
+func (c *Caches) Close() error {
+	if ims, ok := c.Images.(*CachedImages); ok {
+		return ims.Close()
+	}
+	return nil
+}
+
+// cachedSet is a dataset whose images are loaded using caches.
+type cachedSet struct {
+	ImageSet
+	caches *Caches
+}
+
+// WithCaches returns a dataset whose images are loaded using caches.
+// The functions in this package which are given the dataset
+// use its caches to load images and compute feature pyramids.
+func WithCaches(dataset ImageSet, caches *Caches) ImageSet {
+	if set, ok := dataset.(*cachedSet); ok {
+		dataset = set.ImageSet
+	}
+	return &cachedSet{dataset, caches}
+}
+
+// CachesOf returns the caches given to a dataset by WithCaches
+// or NoCaches if it does not have any.
+func CachesOf(dataset ImageSet) *Caches {
+	if set, ok := dataset.(*cachedSet); ok {
+		return set.caches
+	}
+	return NoCaches
+}
+
+// CacheOpts configures the caches used with a dataset.
+// The zero value disables all caches.
+type CacheOpts struct {
+	// Maximum number of decoded pixels to keep in memory.
+	// Zero disables the image cache.
+	ImagePixels int64
+	// Maximum number of feature pyramid elements to keep in memory.
+	// Zero disables the pyramid cache.
+	PyrElems int64
+	// File in which to persist image sizes.
+	// Empty to keep sizes only in memory.
+	SizeFile string
+	// Number of goroutines with which to process images.
+	// Zero means the number of CPUs.
+	Workers int
+}
+
+// New creates empty caches.
+// The caller should close them when they are no longer needed.
+func (opts CacheOpts) New() (*Caches, error) {
+	caches := &Caches{Pyrs: NewFeatPyrCache(opts.PyrElems), Workers: opts.Workers}
+	if opts.ImagePixels == 0 && opts.SizeFile == "" {
+		caches.Images = DiskImages{}
+		return caches, nil
+	}
+	ims := NewCachedImages(opts.ImagePixels)
+	if opts.SizeFile != "" {
+		if err := ims.LoadSizes(opts.SizeFile); err != nil {
+			return nil, err
+		}
+	}
+	caches.Images = ims
+	return caches, nil
+}
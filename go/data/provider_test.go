@@ -0,0 +1,147 @@
+package data
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestCachedImages_LoadSizes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	imFile := path.Join(dir, "a.png")
+	writeTestImage(t, imFile, 20, 10)
+	sizeFile := path.Join(dir, "sizes.txt")
+
+	c := NewCachedImages(1000)
+	if err := c.LoadSizes(sizeFile); err != nil {
+		t.Fatal(err)
+	}
+	size, err := c.Size(imFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Pt(20, 10); size != want {
+		t.Fatalf("want %v, got %v", want, size)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Size should be read from file after image is removed.
+	if err := os.Remove(imFile); err != nil {
+		t.Fatal(err)
+	}
+	c = NewCachedImages(1000)
+	if err := c.LoadSizes(sizeFile); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	size, err = c.Size(imFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Pt(20, 10); size != want {
+		t.Errorf("want %v, got %v", want, size)
+	}
+	// Size should not have been written twice.
+	buf, err := ioutil.ReadFile(sizeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(buf), "\n"); n != 1 {
+		t.Errorf("want 1 line in size file, got %d", n)
+	}
+}
+
+// Checks that malformed lines in the size file are skipped.
+func TestCachedImages_LoadSizes_malformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sizeFile := path.Join(dir, "sizes.txt")
+	// Second line was interrupted by the third.
+	lines := "a.png\t20\t10\nb.png\t3c.png\t40\t30\nd.png\tx\t1\n"
+	if err := ioutil.WriteFile(sizeFile, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCachedImages(0)
+	if err := c.LoadSizes(sizeFile); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if size, err := c.Size("a.png"); err != nil {
+		t.Error(err)
+	} else if want := image.Pt(20, 10); size != want {
+		t.Errorf("want %v, got %v", want, size)
+	}
+	if n := len(c.sizes); n != 1 {
+		t.Errorf("want 1 size, got %d", n)
+	}
+}
+
+func TestCachedImages_Image(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	imFile := path.Join(dir, "a.png")
+	writeTestImage(t, imFile, 20, 10)
+
+	c := NewCachedImages(1000)
+	if _, err := c.Image(imFile); err != nil {
+		t.Fatal(err)
+	}
+	// Image should be decoded from memory after file is removed.
+	if err := os.Remove(imFile); err != nil {
+		t.Fatal(err)
+	}
+	im, err := c.Image(imFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Pt(20, 10); im.Bounds().Size() != want {
+		t.Errorf("want %v, got %v", want, im.Bounds().Size())
+	}
+	if size, err := c.Size(imFile); err != nil {
+		t.Error(err)
+	} else if want := image.Pt(20, 10); size != want {
+		t.Errorf("want %v, got %v", want, size)
+	}
+}
+
+func TestWithCaches(t *testing.T) {
+	dataset := &testDataset{[]string{"a"}}
+	if got := CachesOf(dataset); got != NoCaches {
+		t.Errorf("want NoCaches, got %v", got)
+	}
+	caches, err := CacheOpts{}.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := caches.Images.(DiskImages); !ok {
+		t.Errorf("zero options: want DiskImages, got %T", caches.Images)
+	}
+	wrapped := WithCaches(dataset, caches)
+	if got := CachesOf(wrapped); got != caches {
+		t.Errorf("want caches given to dataset, got %v", got)
+	}
+	// Caches should be replaced, not nested.
+	other := &Caches{Images: NewCachedImages(1000), Pyrs: NewFeatPyrCache(0)}
+	if got := CachesOf(WithCaches(wrapped, other)); got != other {
+		t.Errorf("want replaced caches, got %v", got)
+	}
+	if ims := wrapped.Images(); len(ims) != 1 || ims[0] != "a" {
+		t.Errorf("want images of dataset, got %v", ims)
+	}
+}
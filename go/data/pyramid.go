@@ -0,0 +1,119 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/nfnt/resize"
+)
+
+// FeatPyr is the feature transform of an image at several scales.
+type FeatPyr struct {
+	Scales []float64
+	Levels []*rimg64.Multi
+}
+
+// Elems returns the number of elements in all levels.
+func (pyr *FeatPyr) Elems() int64 {
+	var n int64
+	for _, x := range pyr.Levels {
+		n += int64(len(x.Elems))
+	}
+	return n
+}
+
+// FeatPyrCache keeps recently computed feature pyramids in memory.
+// Pyramids are identified by the image file, the transform
+// and the set of scales.
+type FeatPyrCache struct {
+	pyrs *lru
+}
+
+// NewFeatPyrCache returns a cache which stores
+// at most maxElems numbers in total.
+func NewFeatPyrCache(maxElems int64) *FeatPyrCache {
+	return &FeatPyrCache{newLRU(maxElems)}
+}
+
+// PyrScales returns the scales at which to search an image.
+// The first scale is maxScale and each subsequent scale is smaller by step.
+// The last scale is the smallest at which the image, with margin added,
+// still contains a window of the given size.
+func PyrScales(im, window image.Point, margin feat.Margin, maxScale, step float64) []float64 {
+	var scales []float64
+	for i := 0; ; i++ {
+		s := maxScale * math.Pow(step, -float64(i))
+		w := round(s*float64(im.X)) + margin.Left + margin.Right
+		h := round(s*float64(im.Y)) + margin.Top + margin.Bottom
+		if w < window.X || h < window.Y {
+			break
+		}
+		scales = append(scales, s)
+	}
+	return scales
+}
+
+// get returns the pyramid identified by key, which is computed by featPyrKey.
+// If the pyramid is not in the cache, the image is loaded from ims.
+// Also returns the time taken to resize the image and compute features,
+// which is zero if the pyramid was in the cache.
+func (c *FeatPyrCache) get(key string, ims ImageProvider, file string, phi feat.Image, pad feat.Pad, scales []float64, interp resize.InterpolationFunction) (*FeatPyr, time.Duration, time.Duration, error) {
+	if x, ok := c.pyrs.Get(key); ok {
+		return x.(*FeatPyr), 0, 0, nil
+	}
+	im, err := ims.Image(file)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	pyr, durResize, durFeat, err := computeFeatPyr(im, phi, pad, scales, interp)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	c.pyrs.Add(key, pyr, pyr.Elems())
+	return pyr, durResize, durFeat, nil
+}
+
+// MaxElems returns the capacity of the cache.
+func (c *FeatPyrCache) MaxElems() int64 {
+	return c.pyrs.max
+}
+
+// computeFeatPyr returns the time taken to resize the image and compute features.
+func computeFeatPyr(im image.Image, phi feat.Image, pad feat.Pad, scales []float64, interp resize.InterpolationFunction) (*FeatPyr, time.Duration, time.Duration, error) {
+	pyr := &FeatPyr{Scales: scales}
+	var durResize, durFeat time.Duration
+	size := im.Bounds().Size()
+	for _, s := range scales {
+		t := time.Now()
+		scaled := im
+		if s != 1 {
+			w, h := round(s*float64(size.X)), round(s*float64(size.Y))
+			scaled = resize.Resize(uint(w), uint(h), im, interp)
+		}
+		durResize += time.Since(t)
+		t = time.Now()
+		x, err := feat.ApplyPad(phi, scaled, pad)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		durFeat += time.Since(t)
+		pyr.Levels = append(pyr.Levels, x)
+	}
+	return pyr, durResize, durFeat, nil
+}
+
+// featPyrKey identifies a pyramid by the parameters of its construction.
+// Returns an error if the transform cannot be serialized,
+// in which case the pyramid is not cached.
+func featPyrKey(file string, phi feat.Image, pad feat.Pad, scales []float64, interp resize.InterpolationFunction) (string, error) {
+	spec, err := json.Marshal(phi)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s|%T%s|%v|%T%+v|%v|%d", file, phi, spec, pad.Margin, pad.Extend, pad.Extend, scales, interp), nil
+}
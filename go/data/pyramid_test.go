@@ -0,0 +1,21 @@
+package data
+
+import (
+	"image"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/feat"
+)
+
+func TestPyrScales(t *testing.T) {
+	scales := PyrScales(image.Pt(100, 200), image.Pt(50, 100), feat.Margin{}, 2, 2)
+	want := []float64{2, 1, 0.5}
+	if len(scales) != len(want) {
+		t.Fatalf("want %v, got %v", want, scales)
+	}
+	for i := range want {
+		if scales[i] != want[i] {
+			t.Errorf("at %d: want %g, got %g", i, want[i], scales[i])
+		}
+	}
+}
@@ -12,13 +12,14 @@ import (
 // If an image is smaller than the window, then it is skipped and
 // the number of windows returned may be less than n.
 // The size is specified in pixels.
-func RandomWindows(n int, ims []string, dataset ImageSet, margin feat.Margin, size image.Point) (map[string][]image.Rectangle, error) {
+// Random numbers are taken from r.
+func RandomWindows(n int, ims []string, dataset ImageSet, margin feat.Margin, size image.Point, r *rand.Rand) (map[string][]image.Rectangle, error) {
 	// Count number of rectangles to take from each image.
 	// Avoid opening same image twice.
 	counts := make([]int, len(ims))
 	for i := 0; i < n; i++ {
 		// Uniform distribution over set of images.
-		counts[rand.Intn(len(ims))]++
+		counts[r.Intn(len(ims))]++
 	}
 
 	rects := make(map[string][]image.Rectangle)
@@ -27,7 +28,7 @@ func RandomWindows(n int, ims []string, dataset ImageSet, margin feat.Margin, si
 			continue
 		}
 		im := ims[i]
-		imsize, err := loadImageSize(dataset.File(im))
+		imsize, err := CachesOf(dataset).Images.Size(dataset.File(im))
 		if err != nil {
 			return nil, err
 		}
@@ -37,10 +38,9 @@ func RandomWindows(n int, ims []string, dataset ImageSet, margin feat.Margin, si
 			continue
 		}
 		for j := 0; j < count; j++ {
-			x := rand.Intn(lims.Dx() - size.X + 1)
-			y := rand.Intn(lims.Dy() - size.Y + 1)
-			r := image.Rect(x, y, x+size.X, y+size.Y)
-			rects[im] = append(rects[im], r)
+			x := r.Intn(lims.Dx() - size.X + 1)
+			y := r.Intn(lims.Dy() - size.Y + 1)
+			rects[im] = append(rects[im], image.Rect(x, y, x+size.X, y+size.Y))
 		}
 	}
 	return rects, nil
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"image"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/feat"
+)
+
+func TestRandomWindows_seed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 8)
+	dataset := &testDataset{ims}
+	size := image.Pt(8, 16)
+
+	a, err := RandomWindows(50, ims, dataset, feat.Margin{}, size, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := RandomWindows(50, ims, dataset, feat.Margin{}, size, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("windows differ with same seed")
+	}
+	var n int
+	for im, rects := range a {
+		bounds := image.Rect(0, 0, 0, 0)
+		for i, x := range ims {
+			if x == im {
+				bounds = image.Rect(0, 0, 16+i, 24+2*i)
+			}
+		}
+		for _, r := range rects {
+			if r.Size() != size || !r.In(bounds) {
+				t.Errorf("invalid window %v in image %v", r, bounds)
+			}
+		}
+		n += len(rects)
+	}
+	if n != 50 {
+		t.Errorf("want 50 windows, got %d", n)
+	}
+}
@@ -0,0 +1,146 @@
+package data
+
+import (
+	"image"
+	"sort"
+	"time"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+)
+
+// MultiScale searches an image in the dataset like detect.MultiScale.
+// The image and its feature pyramid are taken from the caches of the dataset
+// so that repeated searches of the same image (e.g. in each round of
+// hard negative mining) do not decode it and compute its features again.
+// The pyramid is shared with PyramidWindows of the same template shape,
+// transform and scales.
+func MultiScale(dataset ImageSet, name string, scorer slide.Scorer, shape detect.PadRect, opts detect.MultiScaleOpts) ([]detect.Det, detect.MultiScaleDur, error) {
+	var dur detect.MultiScaleDur
+	caches := CachesOf(dataset)
+	file := dataset.File(name)
+	size, err := caches.Images.Size(file)
+	if err != nil {
+		return nil, dur, err
+	}
+	phi, pad := opts.Transform, opts.Pad
+	scales := PyrScales(size, shape.Size, pad.Margin, opts.MaxScale, opts.PyrStep)
+	var pyr *FeatPyr
+	if key, err := featPyrKey(file, phi, pad, scales, opts.Interp); err == nil {
+		pyr, dur.Resize, dur.Feat, err = caches.Pyrs.get(key, caches.Images, file, phi, pad, scales, opts.Interp)
+		if err != nil {
+			return nil, dur, err
+		}
+	} else {
+		// The pyramid cannot be identified and therefore cannot be cached.
+		im, err := caches.Images.Image(file)
+		if err != nil {
+			return nil, dur, err
+		}
+		pyr, dur.Resize, dur.Feat, err = computeFeatPyr(im, phi, pad, scales, opts.Interp)
+		if err != nil {
+			return nil, dur, err
+		}
+	}
+
+	t := time.Now()
+	var dets []detect.Det
+	for i, x := range pyr.Levels {
+		scores, err := scoreWindows(x, scorer)
+		if err != nil {
+			return nil, dur, err
+		}
+		for _, pos := range detectPoints(scores, opts.DetFilter) {
+			rect := featToImageRect(pos, phi.Rate(), pad, shape, pyr.Scales[i])
+			dets = append(dets, detect.Det{Score: scores.At(pos.X, pos.Y), Rect: rect})
+		}
+	}
+	dur.Slide = time.Since(t)
+
+	t = time.Now()
+	sort.Sort(sort.Reverse(detsByScore(dets)))
+	dets = detect.Suppress(dets, opts.MaxNum, opts.Overlap)
+	dur.Suppr = time.Since(t)
+	return dets, dur, nil
+}
+
+// scoreWindows evaluates the scorer at every position in a feature image.
+// The result is empty if the feature image is smaller than the template.
+func scoreWindows(x *rimg64.Multi, scorer slide.Scorer) (*rimg64.Image, error) {
+	size := scorer.Size()
+	cols := numPositions(x.Width, size.X, 1)
+	rows := numPositions(x.Height, size.Y, 1)
+	scores := rimg64.New(cols, rows)
+	win := rimg64.NewMulti(size.X, size.Y, x.Channels)
+	for i := 0; i < cols; i++ {
+		for j := 0; j < rows; j++ {
+			for u := 0; u < size.X; u++ {
+				for v := 0; v < size.Y; v++ {
+					for p := 0; p < x.Channels; p++ {
+						win.Set(u, v, p, x.At(i+u, j+v, p))
+					}
+				}
+			}
+			y, err := scorer.Score(win)
+			if err != nil {
+				return nil, err
+			}
+			scores.Set(i, j, y)
+		}
+	}
+	return scores, nil
+}
+
+// detectPoints returns the positions whose score is at least MinScore
+// and, if LocalMax is set, not less than any of their neighbors.
+func detectPoints(scores *rimg64.Image, opts detect.DetFilter) []image.Point {
+	var pts []image.Point
+	for i := 0; i < scores.Width; i++ {
+		for j := 0; j < scores.Height; j++ {
+			y := scores.At(i, j)
+			if y < opts.MinScore {
+				continue
+			}
+			if opts.LocalMax && !isLocalMax(scores, i, j) {
+				continue
+			}
+			pts = append(pts, image.Pt(i, j))
+		}
+	}
+	return pts
+}
+
+func isLocalMax(scores *rimg64.Image, i, j int) bool {
+	y := scores.At(i, j)
+	for u := i - 1; u <= i+1; u++ {
+		for v := j - 1; v <= j+1; v++ {
+			if u < 0 || u >= scores.Width || v < 0 || v >= scores.Height {
+				continue
+			}
+			if scores.At(u, v) > y {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// featToImageRect maps the position of a window in a level of the pyramid
+// to the interior of the window in the original image.
+func featToImageRect(pos image.Point, rate int, pad feat.Pad, shape detect.PadRect, scale float64) image.Rectangle {
+	// Position of the window in the scaled image without margin.
+	pix := pos.Mul(rate).Sub(image.Pt(pad.Margin.Left, pad.Margin.Top))
+	r := shape.Int.Add(pix)
+	return image.Rect(
+		round(float64(r.Min.X)/scale), round(float64(r.Min.Y)/scale),
+		round(float64(r.Max.X)/scale), round(float64(r.Max.Y)/scale),
+	)
+}
+
+type detsByScore []detect.Det
+
+func (xs detsByScore) Len() int           { return len(xs) }
+func (xs detsByScore) Less(i, j int) bool { return xs[i].Score < xs[j].Score }
+func (xs detsByScore) Swap(i, j int)      { xs[i], xs[j] = xs[j], xs[i] }
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"image"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+	"github.com/nfnt/resize"
+)
+
+// Checks that MultiScale scores every window at a single scale
+// and that a second search re-uses the cached pyramid.
+func TestMultiScale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 1)
+	caches := &Caches{Images: DiskImages{}, Pyrs: NewFeatPyrCache(1 << 20)}
+	dataset := WithCaches(&testDataset{ims}, caches)
+
+	shape := detect.PadRect{Size: image.Pt(8, 16), Int: image.Rect(0, 0, 8, 16)}
+	tmpl := rimg64.NewMulti(1, 16, 1)
+	for j := 0; j < 16; j++ {
+		tmpl.Set(0, j, 0, 1)
+	}
+	scorer := &slide.AffineScorer{Tmpl: tmpl}
+	// Only scale one fits with such a large step.
+	opts := detect.MultiScaleOpts{
+		MaxScale:    1,
+		PyrStep:     1e6,
+		Interp:      resize.Bilinear,
+		Transform:   sumFeat{},
+		DetFilter:   detect.DetFilter{MinScore: math.Inf(-1)},
+		SupprFilter: detect.SupprFilter{MaxNum: 100, Overlap: func(a, b image.Rectangle) bool { return false }},
+	}
+
+	x, err := sumFeat{}.Apply(mustLoadImage(t, ims[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[image.Rectangle]float64)
+	for j := 0; j+16 <= x.Height; j++ {
+		var s float64
+		for v := 0; v < 16; v++ {
+			s += x.At(0, j+v, 0)
+		}
+		want[image.Rect(0, j, 8, j+16)] = s
+	}
+
+	dets, _, err := MultiScale(dataset, ims[0], scorer, shape, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != len(want) {
+		t.Fatalf("want %d detections, got %d", len(want), len(dets))
+	}
+	for i, det := range dets {
+		s, ok := want[det.Rect]
+		if !ok {
+			t.Errorf("unexpected detection: %v", det.Rect)
+			continue
+		}
+		if math.Abs(s-det.Score) > 1e-9*math.Abs(s) {
+			t.Errorf("score of %v: want %g, got %g", det.Rect, s, det.Score)
+		}
+		if i > 0 && det.Score > dets[i-1].Score {
+			t.Errorf("detections not sorted by score at %d", i)
+		}
+	}
+
+	again, dur, err := MultiScale(dataset, ims[0], scorer, shape, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dur.Resize != 0 || dur.Feat != 0 {
+		t.Errorf("pyramid was computed again: resize %v, feat %v", dur.Resize, dur.Feat)
+	}
+	if !reflect.DeepEqual(dets, again) {
+		t.Error("second search gave different detections")
+	}
+}
+
+func mustLoadImage(t *testing.T, fname string) image.Image {
+	im, err := LoadImage(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return im
+}
+
+// Checks that a window is kept by LocalMax unless a neighbor is greater.
+func TestDetectPoints_localMax(t *testing.T) {
+	rows := [][]float64{
+		{1, 2, 1},
+		{0, 1, 0},
+		{3, 0, 3},
+	}
+	scores := rimg64.New(3, 3)
+	for y := range rows {
+		for x := range rows[y] {
+			scores.Set(x, y, rows[y][x])
+		}
+	}
+	got := detectPoints(scores, detect.DetFilter{LocalMax: true, MinScore: 2})
+	want := []image.Point{{0, 2}, {1, 0}, {2, 2}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
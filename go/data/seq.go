@@ -0,0 +1,207 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pedestrian sequences which are evaluated with the Caltech toolbox.
+// They are loaded from the format produced by its conversion scripts,
+// which is the same as that of the Caltech dataset.
+var caltechSeqs = map[string]string{
+	"eth":          "eth",
+	"tud-brussels": "tudbrussels",
+	"daimler":      "daimler",
+}
+
+func init() {
+	for name, preset := range caltechSeqs {
+		preset := preset
+		RegisterDataset(name,
+			func() interface{} { return new(CaltechSeqSpec) },
+			func(spec interface{}) (ImageSet, error) {
+				return loadCaltechSeq(preset, *spec.(*CaltechSeqSpec))
+			},
+		)
+	}
+	RegisterDataset("idl",
+		func() interface{} { return new(IDLSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return loadIDL(*spec.(*IDLSpec))
+		},
+	)
+}
+
+// CaltechSeqSpec describes a sequence in the Caltech format
+// with a standard set of videos.
+type CaltechSeqSpec struct {
+	// Dir contains data-ETH/, data-TudBrussels/, data-Daimler/, ...
+	Dir string
+	// Sub-sample rate.
+	// If zero, every frame is used.
+	Skip int
+	// If empty, the reasonable protocol is used.
+	Filter CaltechFilter
+}
+
+func loadCaltechSeq(preset string, spec CaltechSeqSpec) (ImageSet, error) {
+	caltech := CaltechPreset{Dir: spec.Dir, Name: preset}.Spec()
+	if spec.Skip > 0 {
+		caltech.Skip = spec.Skip
+	}
+	filter := spec.Filter
+	if filter.Protocol == "" && len(filter.Labels) == 0 {
+		filter.Protocol = "reasonable"
+	}
+	filter, err := filter.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	return loadCaltech(caltech, filter.role)
+}
+
+// IDLSpec describes a dataset annotated in the IDL format
+// of the original ETH and TUD-Brussels distributions.
+// Each line gives an image and its boxes:
+//
+//	"left/image_00000001.png": (212, 204, 232, 261), (223, 181, 259, 285);
+type IDLSpec struct {
+	// Annotation file.
+	File string
+	// Directory to which image paths are relative.
+	// If empty, the directory containing File.
+	Dir string
+	// Sub-sample rate.
+	// If 30, use lines 29, 59, ... (not counting blank lines).
+	// If zero, every line is used.
+	// Lines which repeat an image add to its boxes.
+	Skip int
+	// Objects shorter than this are added to Ignore (pixels).
+	MinHeight int
+}
+
+// idlDataset is a list of images with annotations.
+// The name of an image is its path in the annotation file.
+type idlDataset struct {
+	dir    string
+	ims    []string
+	annots map[string]Annot
+}
+
+func loadIDL(spec IDLSpec) (ImageSet, error) {
+	file, err := os.Open(spec.File)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	d := new(idlDataset)
+	d.dir = spec.Dir
+	if d.dir == "" {
+		d.dir = path.Dir(spec.File)
+	}
+	d.annots = make(map[string]Annot)
+	skip := spec.Skip
+	if skip <= 0 {
+		skip = 1
+	}
+
+	s := bufio.NewScanner(file)
+	// Number of non-empty lines.
+	var n int
+	for i := 0; s.Scan(); i++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		n++
+		if n%skip != 0 {
+			continue
+		}
+		name, rects, err := parseIDLLine(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		annot, ok := d.annots[name]
+		if !ok {
+			d.ims = append(d.ims, name)
+		}
+		for _, r := range rects {
+			if r.Dy() < spec.MinHeight {
+				annot.Ignore = append(annot.Ignore, r)
+			} else {
+				annot.Instances = append(annot.Instances, r)
+			}
+		}
+		d.annots[name] = annot
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var (
+	idlNameRe = regexp.MustCompile(`^\s*"([^"]*)"\s*(.*)$`)
+	idlBoxRe  = regexp.MustCompile(`\(([^)]*)\)`)
+)
+
+// parseIDLLine parses one line of an IDL file.
+// Scores (":0.5") and ids ("/3") after boxes are ignored.
+func parseIDLLine(line string) (string, []image.Rectangle, error) {
+	m := idlNameRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", nil, fmt.Errorf("no image name: %q", line)
+	}
+	name, rest := m[1], m[2]
+	var rects []image.Rectangle
+	for _, box := range idlBoxRe.FindAllStringSubmatch(rest, -1) {
+		fields := strings.Split(box[1], ",")
+		if len(fields) != 4 {
+			return "", nil, fmt.Errorf("box has %d coordinates: %q", len(fields), box[0])
+		}
+		var x [4]int
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return "", nil, err
+			}
+			x[i] = round(v)
+		}
+		// Rect puts the coordinates in order.
+		rects = append(rects, image.Rect(x[0], x[1], x[2], x[3]))
+	}
+	return name, rects, nil
+}
+
+func (d *idlDataset) Images() []string {
+	return d.ims
+}
+
+func (d *idlDataset) File(name string) string {
+	return path.Join(d.dir, name)
+}
+
+func (d *idlDataset) CanTrain(name string) bool {
+	// Do not exclude any images when training.
+	return true
+}
+
+func (d *idlDataset) CanTest(name string) bool {
+	// Do not exclude any images when testing.
+	return true
+}
+
+func (d *idlDataset) IsNeg(name string) bool {
+	// Sequences may contain unannotated people.
+	return false
+}
+
+func (d *idlDataset) Annot(name string) Annot {
+	return d.annots[name]
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseIDLLine(t *testing.T) {
+	cases := []struct {
+		Line  string
+		Name  string
+		Rects []image.Rectangle
+	}{
+		{
+			`"left/image_00000001.png": (212, 204, 232, 261), (223, 181, 259, 285);`,
+			"left/image_00000001.png",
+			[]image.Rectangle{image.Rect(212, 204, 232, 261), image.Rect(223, 181, 259, 285)},
+		},
+		{
+			`"a.png": (259, 285, 223, 181):0.75/2.`,
+			"a.png",
+			[]image.Rectangle{image.Rect(223, 181, 259, 285)},
+		},
+		{`"b.png";`, "b.png", nil},
+	}
+	for _, c := range cases {
+		name, rects, err := parseIDLLine(c.Line)
+		if err != nil {
+			t.Errorf("%q: %v", c.Line, err)
+			continue
+		}
+		if name != c.Name {
+			t.Errorf("%q: name: want %s, got %s", c.Line, c.Name, name)
+		}
+		if !rectsEq(c.Rects, rects) {
+			t.Errorf("%q: rects: want %v, got %v", c.Line, c.Rects, rects)
+		}
+	}
+}
+
+func TestLoad_idl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := `"a.png": (0, 0, 20, 60), (0, 0, 10, 20);
+"b.png": (0, 0, 20, 60);
+"c.png";
+"d.png": (5, 5, 25, 65).
+`
+	fname := path.Join(dir, "annot.idl")
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := loadIDL(IDLSpec{File: fname, Skip: 2, MinHeight: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims := dataset.Images()
+	if len(ims) != 2 || ims[0] != "b.png" || ims[1] != "d.png" {
+		t.Fatalf("images: want [b.png d.png], got %v", ims)
+	}
+	if want, got := path.Join(dir, "d.png"), dataset.File("d.png"); want != got {
+		t.Errorf("file: want %s, got %s", want, got)
+	}
+
+	dataset, err = loadIDL(IDLSpec{File: fname, MinHeight: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annot := dataset.Annot("a.png")
+	if len(annot.Instances) != 1 || len(annot.Ignore) != 1 {
+		t.Errorf("a.png: want 1 instance and 1 ignore, got %+v", annot)
+	}
+}
+
+// Checks that blank lines are not counted when sub-sampling
+// and that repeated images accumulate their boxes.
+func TestLoad_idlBlankAndRepeat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := `"a.png": (0, 0, 20, 60);
+
+"b.png": (0, 0, 20, 60);
+
+"c.png": (0, 0, 20, 60);
+"d.png": (0, 0, 20, 60);
+"b.png": (5, 5, 25, 65);
+"d.png": (5, 5, 25, 65);
+`
+	fname := path.Join(dir, "annot.idl")
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := loadIDL(IDLSpec{File: fname, Skip: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims := dataset.Images()
+	if len(ims) != 2 || ims[0] != "b.png" || ims[1] != "d.png" {
+		t.Fatalf("images: want [b.png d.png], got %v", ims)
+	}
+	// The second line of b.png is not sampled.
+	if n := len(dataset.Annot("b.png").Instances); n != 1 {
+		t.Errorf("b.png: want 1 instance, got %d", n)
+	}
+	if n := len(dataset.Annot("d.png").Instances); n != 2 {
+		t.Errorf("d.png: want 2 instances, got %d", n)
+	}
+}
+
+func TestListDatasets_seq(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, name := range ListDatasets() {
+		registered[name] = true
+	}
+	for _, name := range []string{"eth", "tud-brussels", "daimler", "idl"} {
+		if !registered[name] {
+			t.Errorf("dataset not registered: %s", name)
+		}
+	}
+}
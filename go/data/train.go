@@ -26,6 +26,10 @@ type ExampleOpts struct {
 	FitMode string
 	// Maximum zoom before an example is discarded.
 	MaxScale float64
+	// Random perturbations to add to positive examples.
+	// Used by AugmentedExamples, not PosExampleRects.
+	// Nil for none.
+	Augment *Augment
 }
 
 type ExcludeCount struct {
@@ -74,10 +78,37 @@ func ObjectsToExamples(objs []image.Rectangle, region detect.PadRect, opts Examp
 
 // PosExampleRects produces example rectangles from dataset annotations.
 func PosExampleRects(ims []string, dataset ImageSet, margin feat.Margin, region detect.PadRect, opts ExampleOpts) (map[string][]image.Rectangle, error) {
-	var (
-		valid     int
-		totalExcl ExcludeCount
+	rects, totalExcl, err := posExamples(ims, dataset, margin, region, opts)
+	if err != nil {
+		return nil, err
+	}
+	var valid int
+	for _, examples := range rects {
+		valid += len(examples)
+	}
+	log.Printf(
+		"valid: %d, bad aspect: %d, too small: %d, not inside: %d",
+		valid, totalExcl.BadAspect, totalExcl.TooSmall, totalExcl.NotInside,
 	)
+	return rects, nil
+}
+
+// PosExampleCount returns the number of examples which PosExampleRects
+// would keep and the number which it would exclude for each reason.
+func PosExampleCount(ims []string, dataset ImageSet, margin feat.Margin, region detect.PadRect, opts ExampleOpts) (int, ExcludeCount, error) {
+	rects, excl, err := posExamples(ims, dataset, margin, region, opts)
+	if err != nil {
+		return 0, ExcludeCount{}, err
+	}
+	var valid int
+	for _, examples := range rects {
+		valid += len(examples)
+	}
+	return valid, excl, nil
+}
+
+func posExamples(ims []string, dataset ImageSet, margin feat.Margin, region detect.PadRect, opts ExampleOpts) (map[string][]image.Rectangle, ExcludeCount, error) {
+	var totalExcl ExcludeCount
 	rects := make(map[string][]image.Rectangle)
 	for _, im := range ims {
 		// Get tight object bounding rectangles.
@@ -85,16 +116,15 @@ func PosExampleRects(ims []string, dataset ImageSet, margin feat.Margin, region
 		if len(objs) == 0 {
 			continue
 		}
-		size, err := loadImageSize(dataset.File(im))
+		size, err := CachesOf(dataset).Images.Size(dataset.File(im))
 		if err != nil {
-			return nil, err
+			return nil, ExcludeCount{}, err
 		}
 		examples, excl, err := ObjectsToExamples(objs, region, opts, size, margin)
 		if err != nil {
-			return nil, err
+			return nil, ExcludeCount{}, err
 		}
 		totalExcl = totalExcl.Plus(excl)
-		valid += len(examples)
 		// Do not add empty positive images to the list.
 		if len(examples) == 0 {
 			// No positive windows.
@@ -102,11 +132,7 @@ func PosExampleRects(ims []string, dataset ImageSet, margin feat.Margin, region
 		}
 		rects[im] = examples
 	}
-	log.Printf(
-		"valid: %d, bad aspect: %d, too small: %d, not inside: %d",
-		valid, totalExcl.BadAspect, totalExcl.TooSmall, totalExcl.NotInside,
-	)
-	return rects, nil
+	return rects, totalExcl, nil
 }
 
 func adjustRect(orig image.Rectangle, size image.Point, margin feat.Margin, region detect.PadRect, opts ExampleOpts) (image.Rectangle, error) {
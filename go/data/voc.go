@@ -0,0 +1,188 @@
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jvlmdr/go-file/fileutil"
+)
+
+func init() {
+	RegisterDataset("voc",
+		func() interface{} { return new(VOCSpec) },
+		func(spec interface{}) (ImageSet, error) {
+			return loadVOC(*spec.(*VOCSpec))
+		},
+	)
+}
+
+// VOCSpec describes a PASCAL VOC-style dataset.
+type VOCSpec struct {
+	// Root containing Annotations/, ImageSets/ and JPEGImages/.
+	Dir string
+	// Name of image set in ImageSets/Main/ e.g. "trainval", "test".
+	Set string
+	// Class of object to detect e.g. "person".
+	Class string
+	// Objects of the class which are shorter than this
+	// are added to Ignore (pixels).
+	MinHeight int
+	// Treat truncated objects as instances instead of ignoring them.
+	// Difficult objects are always ignored.
+	KeepTruncated bool
+	// Exclude negative images from the test set?
+	ExclNegTest bool
+}
+
+// vocDataset is a list of images with annotations.
+// The name of an image is its identifier in the image set.
+type vocDataset struct {
+	dir         string
+	ims         []string
+	files       map[string]string
+	annots      map[string]Annot
+	isNeg       map[string]bool
+	exclNegTest bool
+}
+
+// vocAnnot is the XML annotation of a single image.
+type vocAnnot struct {
+	Filename string      `xml:"filename"`
+	Objects  []vocObject `xml:"object"`
+}
+
+type vocObject struct {
+	Name      string `xml:"name"`
+	Truncated int    `xml:"truncated"`
+	Difficult int    `xml:"difficult"`
+	Box       struct {
+		XMin float64 `xml:"xmin"`
+		YMin float64 `xml:"ymin"`
+		XMax float64 `xml:"xmax"`
+		YMax float64 `xml:"ymax"`
+	} `xml:"bndbox"`
+}
+
+// Rect converts the one-based inclusive box to a rectangle.
+func (obj vocObject) Rect() image.Rectangle {
+	return image.Rect(
+		round(obj.Box.XMin)-1, round(obj.Box.YMin)-1,
+		round(obj.Box.XMax), round(obj.Box.YMax),
+	)
+}
+
+func loadVOC(spec VOCSpec) (ImageSet, error) {
+	if spec.Class == "" {
+		return nil, fmt.Errorf("no class specified")
+	}
+	d := new(vocDataset)
+	d.dir = spec.Dir
+	d.exclNegTest = spec.ExclNegTest
+	d.files = make(map[string]string)
+	d.annots = make(map[string]Annot)
+	d.isNeg = make(map[string]bool)
+
+	lines, err := fileutil.LoadLines(path.Join(spec.Dir, "ImageSets", "Main", spec.Set+".txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		name := fields[0]
+		x, err := loadVOCAnnot(path.Join(spec.Dir, "Annotations", name+".xml"))
+		if err != nil {
+			return nil, fmt.Errorf("load annotation: %v", err)
+		}
+		file := x.Filename
+		if file == "" {
+			file = name + ".jpg"
+		}
+		annot, found := annotFromVOC(x, spec)
+		d.ims = append(d.ims, name)
+		d.files[name] = file
+		d.annots[name] = annot
+		d.isNeg[name] = !found
+	}
+	return d, nil
+}
+
+func loadVOCAnnot(fname string) (*vocAnnot, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	x := new(vocAnnot)
+	if err := xml.NewDecoder(file).Decode(x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// annotFromVOC returns the annotation and whether
+// any object of the class was found.
+// Objects of other classes are not included.
+func annotFromVOC(x *vocAnnot, spec VOCSpec) (Annot, bool) {
+	var (
+		y     Annot
+		found bool
+	)
+	for _, obj := range x.Objects {
+		if strings.TrimSpace(obj.Name) != spec.Class {
+			continue
+		}
+		found = true
+		r := obj.Rect()
+		switch {
+		case obj.Difficult != 0:
+			y.Ignore = append(y.Ignore, r)
+		case obj.Truncated != 0 && !spec.KeepTruncated:
+			y.Ignore = append(y.Ignore, r)
+		case r.Dy() < spec.MinHeight:
+			y.Ignore = append(y.Ignore, r)
+		default:
+			y.Instances = append(y.Instances, r)
+		}
+	}
+	return y, found
+}
+
+func (d *vocDataset) Images() []string {
+	return d.ims
+}
+
+func (d *vocDataset) File(name string) string {
+	return path.Join(d.dir, "JPEGImages", d.files[name])
+}
+
+func (d *vocDataset) CanTrain(name string) bool {
+	// Include all images in the training set.
+	return true
+}
+
+func (d *vocDataset) CanTest(name string) bool {
+	if d.exclNegTest && d.isNeg[name] {
+		return false
+	}
+	return true
+}
+
+func (d *vocDataset) IsNeg(name string) bool {
+	return d.isNeg[name]
+}
+
+func (d *vocDataset) Annot(name string) Annot {
+	return d.annots[name]
+}
+
+func round(x float64) int {
+	return int(math.Floor(x + 0.5))
+}
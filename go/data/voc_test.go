@@ -0,0 +1,129 @@
+package data
+
+import (
+	"encoding/json"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+const vocTestAnnot = `<annotation>
+	<filename>000001.jpg</filename>
+	<size><width>353</width><height>500</height><depth>3</depth></size>
+	<object>
+		<name>person</name>
+		<truncated>0</truncated>
+		<difficult>0</difficult>
+		<bndbox><xmin>8</xmin><ymin>12</ymin><xmax>100</xmax><ymax>300</ymax></bndbox>
+	</object>
+	<object>
+		<name>person</name>
+		<truncated>1</truncated>
+		<difficult>0</difficult>
+		<bndbox><xmin>200</xmin><ymin>10</ymin><xmax>300</xmax><ymax>400</ymax></bndbox>
+	</object>
+	<object>
+		<name>person</name>
+		<truncated>0</truncated>
+		<difficult>1</difficult>
+		<bndbox><xmin>1</xmin><ymin>1</ymin><xmax>50</xmax><ymax>150</ymax></bndbox>
+	</object>
+	<object>
+		<name>person</name>
+		<truncated>0</truncated>
+		<difficult>0</difficult>
+		<bndbox><xmin>301</xmin><ymin>401</ymin><xmax>320</xmax><ymax>440</ymax></bndbox>
+	</object>
+	<object>
+		<name>dog</name>
+		<truncated>0</truncated>
+		<difficult>0</difficult>
+		<bndbox><xmin>10</xmin><ymin>10</ymin><xmax>200</xmax><ymax>200</ymax></bndbox>
+	</object>
+</annotation>
+`
+
+const vocTestAnnotNeg = `<annotation>
+	<filename>000002.jpg</filename>
+	<object>
+		<name>dog</name>
+		<truncated>0</truncated>
+		<difficult>0</difficult>
+		<bndbox><xmin>10</xmin><ymin>10</ymin><xmax>200</xmax><ymax>200</ymax></bndbox>
+	</object>
+</annotation>
+`
+
+func TestLoad_voc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "voc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"Annotations/000001.xml":      vocTestAnnot,
+		"Annotations/000002.xml":      vocTestAnnotNeg,
+		"ImageSets/Main/trainval.txt": "000001\n000002\n",
+	}
+	for name, content := range files {
+		fname := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := json.Marshal(VOCSpec{Dir: dir, Set: "trainval", Class: "person", MinHeight: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := Load("voc", string(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ims := dataset.Images(); len(ims) != 2 {
+		t.Fatalf("number of images: want 2, got %d", len(ims))
+	}
+	if want, got := path.Join(dir, "JPEGImages", "000001.jpg"), dataset.File("000001"); want != got {
+		t.Errorf("file: want %s, got %s", want, got)
+	}
+	if dataset.IsNeg("000001") {
+		t.Error("image with person is negative")
+	}
+	if !dataset.IsNeg("000002") {
+		t.Error("image without person is not negative")
+	}
+
+	annot := dataset.Annot("000001")
+	wantInst := []image.Rectangle{image.Rect(7, 11, 100, 300)}
+	wantIgnore := []image.Rectangle{
+		image.Rect(199, 9, 300, 400),
+		image.Rect(0, 0, 50, 150),
+		image.Rect(300, 400, 320, 440),
+	}
+	if !rectsEq(wantInst, annot.Instances) {
+		t.Errorf("instances: want %v, got %v", wantInst, annot.Instances)
+	}
+	if !rectsEq(wantIgnore, annot.Ignore) {
+		t.Errorf("ignore: want %v, got %v", wantIgnore, annot.Ignore)
+	}
+	if annot := dataset.Annot("000002"); len(annot.Instances) > 0 || len(annot.Ignore) > 0 {
+		t.Errorf("other class included in annotation: %+v", annot)
+	}
+}
+
+func rectsEq(want, got []image.Rectangle) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if !want[i].Eq(got[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,262 @@
+package data
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+	"github.com/nfnt/resize"
+)
+
+// WindowIndex identifies a window in the feature pyramid of an image.
+type WindowIndex struct {
+	Image int         // Index into the list of images.
+	Level int         // Index into the scales of the image.
+	Pos   image.Point // Top-left corner in feature pixels.
+}
+
+// PyramidWindows is the set of all windows in the feature pyramids
+// of a collection of images, presented as vectors.
+// The pyramids are computed when a window is requested
+// and kept in a bounded cache rather than all held in memory.
+// Visiting windows in order is much faster than random access
+// if the cache cannot hold every pyramid.
+// Perm gives a random order which is almost as fast.
+//
+// Since At cannot return an error, it panics if an image
+// cannot be loaded or its features have an unexpected size.
+type PyramidWindows struct {
+	// If Bias is non-zero, it is appended to each vector.
+	Bias float64
+
+	files  []string
+	keys   []string
+	scales [][]float64
+	phi    feat.Image
+	pad    feat.Pad
+	interp resize.InterpolationFunction
+	size   image.Point // Window size in feature pixels.
+	stride int
+	cache  *FeatPyrCache
+	images ImageProvider
+
+	blocks []windowBlock
+	cdf    []int
+
+	// The most recent pyramid is kept even if the cache is disabled.
+	mu      sync.Mutex
+	lastIm  int
+	lastPyr *FeatPyr
+}
+
+// windowBlock is the set of windows in one level of one image.
+type windowBlock struct {
+	Image, Level int
+	FeatSize     image.Point // Size of the feature image.
+	Cols, Rows   int         // Number of window positions.
+}
+
+// NewPyramidWindows indexes the windows of the given images.
+// The scales of each image are given by PyrScales with the pixel size of the window.
+// The window size and stride are specified in feature pixels.
+// Pyramids are kept in cache, which is taken from CachesOf(dataset) if nil.
+// Only image dimensions are loaded, no features are computed.
+func NewPyramidWindows(ims []string, dataset ImageSet, phi feat.Image, pad feat.Pad, shape image.Point, maxScale, step float64, stride int, interp resize.InterpolationFunction, cache *FeatPyrCache) (*PyramidWindows, error) {
+	if stride < 1 {
+		return nil, fmt.Errorf("invalid stride: %d", stride)
+	}
+	caches := CachesOf(dataset)
+	if cache == nil {
+		cache = caches.Pyrs
+	}
+	set := &PyramidWindows{
+		phi:    phi,
+		pad:    pad,
+		interp: interp,
+		size:   phi.Size(shape),
+		stride: stride,
+		cache:  cache,
+		images: caches.Images,
+		lastIm: -1,
+	}
+	var elems int64
+	for i, name := range ims {
+		file := dataset.File(name)
+		imSize, err := caches.Images.Size(file)
+		if err != nil {
+			return nil, err
+		}
+		scales := PyrScales(imSize, shape, pad.Margin, maxScale, step)
+		key, err := featPyrKey(file, phi, pad, scales, interp)
+		if err != nil {
+			return nil, err
+		}
+		set.files = append(set.files, file)
+		set.keys = append(set.keys, key)
+		set.scales = append(set.scales, scales)
+		for j, s := range scales {
+			w := round(s*float64(imSize.X)) + pad.Margin.Left + pad.Margin.Right
+			h := round(s*float64(imSize.Y)) + pad.Margin.Top + pad.Margin.Bottom
+			featSize := phi.Size(image.Pt(w, h))
+			elems += int64(featSize.X * featSize.Y * phi.Channels())
+			cols := numPositions(featSize.X, set.size.X, stride)
+			rows := numPositions(featSize.Y, set.size.Y, stride)
+			if cols*rows == 0 {
+				continue
+			}
+			set.blocks = append(set.blocks, windowBlock{i, j, featSize, cols, rows})
+		}
+	}
+	lens := make([]int, len(set.blocks))
+	for i, b := range set.blocks {
+		lens[i] = b.Cols * b.Rows
+	}
+	set.cdf = cumSum(lens)
+	log.Printf(
+		"%d windows in %d levels of %d images: pyramids have %d elements, cache holds %d",
+		set.Len(), len(set.blocks), len(ims), elems, cache.MaxElems(),
+	)
+	return set, nil
+}
+
+// numPositions returns the number of windows of size m
+// with the given stride in an image of size n.
+func numPositions(n, m, stride int) int {
+	if n < m {
+		return 0
+	}
+	return (n-m)/stride + 1
+}
+
+func cumSum(x []int) []int {
+	s := make([]int, len(x)+1)
+	for i, xi := range x {
+		s[i+1] = s[i] + xi
+	}
+	return s
+}
+
+func (set *PyramidWindows) Len() int {
+	return set.cdf[len(set.cdf)-1]
+}
+
+func (set *PyramidWindows) Dim() int {
+	n := set.size.X * set.size.Y * set.phi.Channels()
+	if set.Bias != 0 {
+		n++
+	}
+	return n
+}
+
+// Window returns the image, level and position of the i-th window.
+// Windows are ordered by image, then level, then position
+// in the same order as WindowSets.
+func (set *PyramidWindows) Window(i int) WindowIndex {
+	w, _ := set.locate(i)
+	return w
+}
+
+// locate returns the window and the index of its block.
+func (set *PyramidWindows) locate(i int) (WindowIndex, int) {
+	if i < 0 || i >= set.Len() {
+		panic(fmt.Sprintf("index out of range: %d not in [0, %d)", i, set.Len()))
+	}
+	k := sort.Search(len(set.blocks), func(k int) bool { return i < set.cdf[k+1] })
+	b := set.blocks[k]
+	t := i - set.cdf[k]
+	pos := image.Pt(t/b.Rows*set.stride, t%b.Rows*set.stride)
+	return WindowIndex{Image: b.Image, Level: b.Level, Pos: pos}, k
+}
+
+// Index returns the index of a window.
+// Returns false if the window is not in the set.
+func (set *PyramidWindows) Index(w WindowIndex) (int, bool) {
+	k := sort.Search(len(set.blocks), func(k int) bool {
+		b := set.blocks[k]
+		return b.Image > w.Image || (b.Image == w.Image && b.Level >= w.Level)
+	})
+	if k == len(set.blocks) {
+		return 0, false
+	}
+	b := set.blocks[k]
+	if b.Image != w.Image || b.Level != w.Level {
+		return 0, false
+	}
+	if w.Pos.X%set.stride != 0 || w.Pos.Y%set.stride != 0 {
+		return 0, false
+	}
+	u, v := w.Pos.X/set.stride, w.Pos.Y/set.stride
+	if u < 0 || u >= b.Cols || v < 0 || v >= b.Rows {
+		return 0, false
+	}
+	return set.cdf[k] + u*b.Rows + v, true
+}
+
+// Perm returns a random permutation of the windows
+// in which the windows of each image are contiguous.
+// The images are visited in random order
+// and the windows of each image are shuffled,
+// so that each pyramid is computed once per pass
+// even if the cache is disabled.
+func (set *PyramidWindows) Perm(r *rand.Rand) []int {
+	// Range of windows of each image.
+	var begin, end []int
+	for k, b := range set.blocks {
+		if k > 0 && b.Image == set.blocks[k-1].Image {
+			end[len(end)-1] = set.cdf[k+1]
+			continue
+		}
+		begin = append(begin, set.cdf[k])
+		end = append(end, set.cdf[k+1])
+	}
+	perm := make([]int, 0, set.Len())
+	for _, i := range r.Perm(len(begin)) {
+		for _, j := range r.Perm(end[i] - begin[i]) {
+			perm = append(perm, begin[i]+j)
+		}
+	}
+	return perm
+}
+
+// Scale returns the scale of a level of an image.
+func (set *PyramidWindows) Scale(im, level int) float64 {
+	return set.scales[im][level]
+}
+
+// At returns a copy of the i-th window.
+func (set *PyramidWindows) At(i int) []float64 {
+	w, k := set.locate(i)
+	pyr, err := set.pyramid(w.Image)
+	if err != nil {
+		panic(fmt.Sprintf("feature pyramid of %s: %v", set.files[w.Image], err))
+	}
+	x := pyr.Levels[w.Level]
+	if want := set.blocks[k].FeatSize; !x.Size().Eq(want) {
+		panic(fmt.Sprintf("feature image of %s at scale %g: want size %v, got %v", set.files[w.Image], pyr.Scales[w.Level], want, x.Size()))
+	}
+	win := &vecset.WindowSet{Image: x, Size: set.size, Windows: []image.Point{w.Pos}, Bias: set.Bias}
+	return win.At(0)
+}
+
+func (set *PyramidWindows) pyramid(im int) (*FeatPyr, error) {
+	set.mu.Lock()
+	if set.lastIm == im {
+		pyr := set.lastPyr
+		set.mu.Unlock()
+		return pyr, nil
+	}
+	set.mu.Unlock()
+	pyr, _, _, err := set.cache.get(set.keys[im], set.images, set.files[im], set.phi, set.pad, set.scales[im], set.interp)
+	if err != nil {
+		return nil, err
+	}
+	set.mu.Lock()
+	set.lastIm, set.lastPyr = im, pyr
+	set.mu.Unlock()
+	return pyr, nil
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"image"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/shift-invar/go/imset"
+	"github.com/nfnt/resize"
+)
+
+// Checks that the windows at a single scale are those of WindowSets.
+func TestPyramidWindows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "windows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 4)
+	dataset := &testDataset{ims}
+	shape := image.Pt(8, 16)
+	size := sumFeat{}.Size(shape)
+
+	sets, err := WindowSets(ims, dataset, sumFeat{}, feat.Pad{}, size, 3, resize.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want [][]float64
+	for _, set := range sets {
+		vecs := &imset.VecSet{Set: set, Bias: 1}
+		for i := 0; i < vecs.Len(); i++ {
+			want = append(want, vecs.At(i))
+		}
+	}
+
+	// Only scale one fits with such a large step.
+	got, err := NewPyramidWindows(ims, dataset, sumFeat{}, feat.Pad{}, shape, 1, 1e6, 3, resize.Bilinear, NewFeatPyrCache(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Bias = 1
+	if got.Len() != len(want) {
+		t.Fatalf("len: want %d, got %d", len(want), got.Len())
+	}
+	if got.Dim() != len(want[0]) {
+		t.Fatalf("dim: want %d, got %d", len(want[0]), got.Dim())
+	}
+	for i := range want {
+		if !reflect.DeepEqual(want[i], got.At(i)) {
+			t.Errorf("window %d (%+v): want %v, got %v", i, got.Window(i), want[i], got.At(i))
+		}
+		j, ok := got.Index(got.Window(i))
+		if !ok || j != i {
+			t.Errorf("index of window %d: got %d, %v", i, j, ok)
+		}
+	}
+	if _, ok := got.Index(WindowIndex{Image: 0, Level: 0, Pos: image.Pt(0, 1)}); ok {
+		t.Error("found window which is not on the stride")
+	}
+	if _, ok := got.Index(WindowIndex{Image: 0, Level: 1}); ok {
+		t.Error("found window in level which does not exist")
+	}
+}
+
+// Checks that Perm visits every window once
+// and the windows of each image contiguously.
+func TestPyramidWindows_Perm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "windows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ims := writeExtractTestImages(t, dir, 3)
+	dataset := &testDataset{ims}
+	shape := image.Pt(8, 16)
+
+	set, err := NewPyramidWindows(ims, dataset, sumFeat{}, feat.Pad{}, shape, 1, 2, 3, resize.Bilinear, NewFeatPyrCache(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	perm := set.Perm(rand.New(rand.NewSource(1)))
+	if len(perm) != set.Len() {
+		t.Fatalf("want %d windows, got %d", set.Len(), len(perm))
+	}
+	seen := make(map[int]bool)
+	done := make(map[int]bool)
+	for k, i := range perm {
+		if seen[i] {
+			t.Fatalf("window %d visited twice", i)
+		}
+		seen[i] = true
+		im := set.Window(i).Image
+		if done[im] {
+			t.Fatalf("windows of image %d are not contiguous", im)
+		}
+		if k+1 < len(perm) && set.Window(perm[k+1]).Image != im {
+			done[im] = true
+		}
+	}
+}
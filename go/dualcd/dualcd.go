@@ -0,0 +1,220 @@
+// Package dualcd trains linear SVMs by dual coordinate descent
+// and can start from an approximate primal solution.
+//
+// The problem is to minimize over w
+//
+//	1/2 ||w||^2 + sum_i C[i] max(0, 1 - Y[i] w'X[i])
+//
+// and the algorithm is that of Hsieh et al.,
+// "A dual coordinate descent method for large-scale linear SVM" (ICML 2008).
+package dualcd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-svm/svm"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// Result is the solution and the number of epochs taken to find it.
+type Result struct {
+	W      []float64
+	Alpha  []float64 // Dual variables.
+	Epochs int
+}
+
+// Train solves the SVM problem.
+// The termination function is called after every epoch
+// with the primal and dual objectives, as in svm.Train.
+//
+// If init is not nil, the dual variables are chosen such that
+// the examples violated by the best multiple of init are at their upper bound,
+// which is optimal if init is a multiple of the solution
+// and there are no margin support vectors.
+// This takes two extra passes through the data.
+//
+// If r is nil, the examples are visited in order.
+func Train(x vecset.Set, y, c, init []float64, term svm.TermFunc, r *rand.Rand) (*Result, error) {
+	if r == nil {
+		return TrainPerm(x, y, c, init, term, nil)
+	}
+	order := make([]int, x.Len())
+	for i := range order {
+		order[i] = i
+	}
+	perm := func() []int {
+		for i := range order {
+			j := i + r.Intn(len(order)-i)
+			order[i], order[j] = order[j], order[i]
+		}
+		return order
+	}
+	return TrainPerm(x, y, c, init, term, perm)
+}
+
+// TrainPerm is like Train except that the order in which the examples
+// are visited in each epoch is given by perm.
+// The function is called before every epoch and must return a permutation
+// of the examples, for example one which suits the cache of x.
+// If perm is nil, the examples are visited in order.
+func TrainPerm(x vecset.Set, y, c, init []float64, term svm.TermFunc, perm func() []int) (*Result, error) {
+	n := x.Len()
+	if len(y) != n || len(c) != n {
+		return nil, fmt.Errorf("lengths differ: vectors %d, labels %d, costs %d", n, len(y), len(c))
+	}
+	if init != nil && len(init) != x.Dim() {
+		return nil, fmt.Errorf("dimension: initial solution has %d, want %d", len(init), x.Dim())
+	}
+	// Diagonal of the Gram matrix.
+	q := make([]float64, n)
+	for i := range q {
+		xi := x.At(i)
+		q[i] = floats.Dot(xi, xi)
+	}
+
+	var w, alpha []float64
+	if init != nil {
+		w, alpha = seed(x, y, c, init)
+	} else {
+		w, alpha = make([]float64, x.Dim()), make([]float64, n)
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	for epoch := 1; ; epoch++ {
+		if perm != nil {
+			order = perm()
+			if len(order) != n {
+				return nil, fmt.Errorf("permutation has %d elements, want %d", len(order), n)
+			}
+		}
+		for _, i := range order {
+			if q[i] == 0 {
+				continue
+			}
+			xi := x.At(i)
+			g := y[i]*floats.Dot(w, xi) - 1
+			// Projected gradient.
+			var pg float64
+			switch {
+			case alpha[i] == 0:
+				pg = math.Min(g, 0)
+			case alpha[i] == c[i]:
+				pg = math.Max(g, 0)
+			default:
+				pg = g
+			}
+			if pg == 0 {
+				continue
+			}
+			prev := alpha[i]
+			alpha[i] = math.Min(math.Max(alpha[i]-g/q[i], 0), c[i])
+			floats.AddScaled(w, (alpha[i]-prev)*y[i], xi)
+		}
+
+		f, g := objectives(x, y, c, w, alpha)
+		a := make(map[int]float64)
+		for i, ai := range alpha {
+			if ai != 0 {
+				a[i] = ai
+			}
+		}
+		done, err := term(epoch, f, g, w, a)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return &Result{W: w, Alpha: alpha, Epochs: epoch}, nil
+		}
+	}
+}
+
+// objectives returns the primal and dual objectives.
+func objectives(x vecset.Set, y, c, w, alpha []float64) (f, g float64) {
+	ww := floats.Dot(w, w)
+	f, g = 0.5*ww, -0.5*ww
+	for i := 0; i < x.Len(); i++ {
+		f += c[i] * math.Max(0, 1-y[i]*floats.Dot(w, x.At(i)))
+		g += alpha[i]
+	}
+	return f, g
+}
+
+// seed returns a feasible dual solution and its primal counterpart
+// obtained from an approximate primal solution.
+func seed(x vecset.Set, y, c, init []float64) (w, alpha []float64) {
+	n := x.Len()
+	// Margins of the initial solution.
+	z := make([]float64, n)
+	for i := range z {
+		z[i] = y[i] * floats.Dot(init, x.At(i))
+	}
+	s := fitScale(z, c, floats.Dot(init, init))
+
+	// Put the violated examples at their upper bound.
+	w, alpha = make([]float64, x.Dim()), make([]float64, n)
+	var sum float64
+	var num int
+	for i := range alpha {
+		if s*z[i] < 1 {
+			alpha[i] = c[i]
+			sum += c[i]
+			num++
+			floats.AddScaled(w, alpha[i]*y[i], x.At(i))
+		}
+	}
+	// Scale the dual solution to maximize the dual objective
+	// sum(alpha) - 1/2 ||w||^2 subject to alpha <= c.
+	t := 1.0
+	if ww := floats.Dot(w, w); ww > 0 {
+		t = math.Min(sum/ww, 1)
+	}
+	floats.Scale(t, alpha)
+	floats.Scale(t, w)
+	log.Printf("initial solution: scale %.4g, %d violators, dual scale %.4g", s, num, t)
+	return w, alpha
+}
+
+// fitScale finds s >= 0 which minimizes
+//
+//	1/2 s^2 ww + sum_i c[i] max(0, 1 - s z[i]).
+//
+// The objective is convex and its derivative is found by bisection.
+func fitScale(z, c []float64, ww float64) float64 {
+	if ww == 0 {
+		return 0
+	}
+	deriv := func(s float64) float64 {
+		d := s * ww
+		for i := range z {
+			if s*z[i] < 1 {
+				d -= c[i] * z[i]
+			}
+		}
+		return d
+	}
+	// The derivative is positive beyond this upper bound.
+	var hi float64
+	for i := range z {
+		if z[i] > 0 {
+			hi += c[i] * z[i]
+		}
+	}
+	hi /= ww
+	lo := 0.0
+	for iter := 0; iter < 100 && hi-lo > 1e-12*hi; iter++ {
+		mid := (lo + hi) / 2
+		if deriv(mid) < 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,132 @@
+package dualcd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func randProblem(r *rand.Rand, n, dim int) (vecset.Slice, []float64, []float64) {
+	w := make([]float64, dim)
+	for j := range w {
+		w[j] = r.NormFloat64()
+	}
+	x := make(vecset.Slice, n)
+	y := make([]float64, n)
+	c := make([]float64, n)
+	for i := range x {
+		x[i] = make([]float64, dim)
+		for j := range x[i] {
+			x[i][j] = r.NormFloat64()
+		}
+		y[i] = 1
+		if floats.Dot(w, x[i])+0.5*r.NormFloat64() < 0 {
+			y[i] = -1
+		}
+		c[i] = 10 / float64(n)
+	}
+	return x, y, c
+}
+
+// gapTerm stops when the relative duality gap is small.
+func gapTerm(tol float64) func(int, float64, float64, []float64, map[int]float64) (bool, error) {
+	return func(epoch int, f, g float64, w []float64, a map[int]float64) (bool, error) {
+		return (f-g)/f <= tol || epoch >= 1000, nil
+	}
+}
+
+func TestTrain(t *testing.T) {
+	x, y, c := randProblem(rand.New(rand.NewSource(1)), 300, 10)
+	res, err := Train(x, y, c, nil, gapTerm(1e-6), rand.New(rand.NewSource(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, g := objectives(x, y, c, res.W, res.Alpha)
+	if gap := (f - g) / f; gap > 1e-6 {
+		t.Errorf("relative gap %.3g after %d epochs", gap, res.Epochs)
+	}
+	// Check the optimality conditions.
+	for i := range x {
+		m := y[i] * floats.Dot(res.W, x[i])
+		if m > 1+1e-3 && res.Alpha[i] > 1e-6*c[i] {
+			t.Errorf("example %d: margin %.4g, want alpha zero, got %.4g", i, m, res.Alpha[i])
+		}
+		if m < 1-1e-3 && res.Alpha[i] < c[i]*(1-1e-6) {
+			t.Errorf("example %d: margin %.4g, want alpha %.4g, got %.4g", i, m, c[i], res.Alpha[i])
+		}
+	}
+}
+
+func TestTrain_init(t *testing.T) {
+	x, y, c := randProblem(rand.New(rand.NewSource(1)), 300, 10)
+	ref, err := Train(x, y, c, nil, gapTerm(1e-8), rand.New(rand.NewSource(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := Train(x, y, c, nil, gapTerm(1e-4), rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start from a scaled copy of the solution.
+	init := make([]float64, len(ref.W))
+	floats.AddScaled(init, 3, ref.W)
+	warm, err := Train(x, y, c, init, gapTerm(1e-4), rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warm.Epochs > zero.Epochs {
+		t.Errorf("epochs: from zero %d, from solution %d", zero.Epochs, warm.Epochs)
+	}
+	for j := range ref.W {
+		if math.Abs(warm.W[j]-ref.W[j]) > 1e-2*floats.Norm(ref.W, 2) {
+			t.Errorf("element %d: want %.4g, got %.4g", j, ref.W[j], warm.W[j])
+		}
+	}
+	if _, err := Train(x, y, c, make([]float64, 3), gapTerm(1e-4), nil); err == nil {
+		t.Error("expected error for initial solution of wrong dimension")
+	}
+}
+
+// Checks that the solution is found when examples are visited
+// in blocks whose order is random.
+func TestTrainPerm(t *testing.T) {
+	x, y, c := randProblem(rand.New(rand.NewSource(1)), 300, 10)
+	r := rand.New(rand.NewSource(2))
+	perm := func() []int {
+		var order []int
+		for _, b := range r.Perm(10) {
+			for _, j := range r.Perm(30) {
+				order = append(order, 30*b+j)
+			}
+		}
+		return order
+	}
+	res, err := TrainPerm(x, y, c, nil, gapTerm(1e-6), perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, g := objectives(x, y, c, res.W, res.Alpha)
+	if gap := (f - g) / f; gap > 1e-6 {
+		t.Errorf("relative gap %.3g after %d epochs", gap, res.Epochs)
+	}
+	short := func() []int { return make([]int, 10) }
+	if _, err := TrainPerm(x, y, c, nil, gapTerm(1e-6), short); err == nil {
+		t.Error("expected error for permutation of wrong length")
+	}
+}
+
+func TestFitScale(t *testing.T) {
+	z := []float64{2, 0.5, -1}
+	c := []float64{1, 1, 1}
+	// For s < 0.5, the derivative is s - (2 + 0.5 - 1), which is negative.
+	// For 0.5 < s < 2, the derivative is s - (0.5 - 1), which is positive.
+	if s := fitScale(z, c, 1); math.Abs(s-0.5) > 1e-9 {
+		t.Errorf("want 0.5, got %.6g", s)
+	}
+	if s := fitScale(z, c, 0); s != 0 {
+		t.Errorf("want 0 for zero vector, got %.6g", s)
+	}
+}
@@ -0,0 +1,57 @@
+package exemplar
+
+import (
+	"math"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+)
+
+// Calib is an affine map a*s + b of scores.
+type Calib struct {
+	Scale, Offset float64
+}
+
+// FitCalib returns the map which standardizes the scores
+// of a set of negatives to have zero mean and unit variance.
+// If the scores have zero variance, only the mean is subtracted.
+func FitCalib(neg []float64) Calib {
+	if len(neg) == 0 {
+		return Calib{Scale: 1}
+	}
+	var mean, sqr float64
+	for _, s := range neg {
+		mean += s
+		sqr += s * s
+	}
+	n := float64(len(neg))
+	mean /= n
+	std := math.Sqrt(math.Max(sqr/n-mean*mean, 0))
+	if std == 0 {
+		return Calib{Scale: 1, Offset: -mean}
+	}
+	return Calib{Scale: 1 / std, Offset: -mean / std}
+}
+
+// Apply returns a template whose scores are the calibrated scores of s.
+func (c Calib) Apply(s *slide.AffineScorer) *slide.AffineScorer {
+	tmpl := &rimg64.Multi{
+		Width:    s.Tmpl.Width,
+		Height:   s.Tmpl.Height,
+		Channels: s.Tmpl.Channels,
+		Elems:    make([]float64, len(s.Tmpl.Elems)),
+	}
+	floats.AddScaled(tmpl.Elems, c.Scale, s.Tmpl.Elems)
+	return &slide.AffineScorer{Tmpl: tmpl, Bias: c.Scale*s.Bias + c.Offset}
+}
+
+// Scores computes the score of every example under a template.
+// The examples must have the same size as the template.
+func Scores(s *slide.AffineScorer, xs []*rimg64.Multi) []float64 {
+	y := make([]float64, len(xs))
+	for i, x := range xs {
+		y[i] = floats.Dot(s.Tmpl.Elems, x.Elems) + s.Bias
+	}
+	return y
+}
@@ -0,0 +1,120 @@
+package exemplar
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+)
+
+// Cluster partitions the examples into k clusters by k-means
+// with k-means++ initialization.
+// Returns the index of the cluster of each example.
+// Every cluster is non-empty.
+func Cluster(xs []*rimg64.Multi, k, maxIter int, r *rand.Rand) ([]int, error) {
+	if k < 1 || k > len(xs) {
+		return nil, fmt.Errorf("invalid number of clusters: %d (examples %d)", k, len(xs))
+	}
+	centers := seedCenters(xs, k, r)
+	assign := make([]int, len(xs))
+	for iter := 0; maxIter <= 0 || iter < maxIter; iter++ {
+		var changed int
+		for i, x := range xs {
+			j := nearest(centers, x.Elems)
+			if iter == 0 || j != assign[i] {
+				changed++
+			}
+			assign[i] = j
+		}
+		if iter > 0 && changed == 0 {
+			break
+		}
+		centers = Means(xs, assign, k)
+		// Move empty clusters to the example furthest from its center.
+		for j := range centers {
+			if centers[j] != nil {
+				continue
+			}
+			far, dist := -1, -1.0
+			for i, x := range xs {
+				if d := sqrDist(centers[assign[i]], x.Elems); d > dist {
+					far, dist = i, d
+				}
+			}
+			centers[j] = append([]float64(nil), xs[far].Elems...)
+			assign[far] = j
+		}
+	}
+	return assign, nil
+}
+
+// Means returns the mean of the examples in each cluster.
+// The mean of an empty cluster is nil.
+func Means(xs []*rimg64.Multi, assign []int, k int) [][]float64 {
+	means := make([][]float64, k)
+	counts := make([]int, k)
+	for i, x := range xs {
+		j := assign[i]
+		if means[j] == nil {
+			means[j] = make([]float64, len(x.Elems))
+		}
+		floats.Add(means[j], x.Elems)
+		counts[j]++
+	}
+	for j := range means {
+		if counts[j] > 0 {
+			floats.Scale(1/float64(counts[j]), means[j])
+		}
+	}
+	return means
+}
+
+// seedCenters chooses k examples by k-means++.
+func seedCenters(xs []*rimg64.Multi, k int, r *rand.Rand) [][]float64 {
+	centers := [][]float64{xs[r.Intn(len(xs))].Elems}
+	dist := make([]float64, len(xs))
+	for len(centers) < k {
+		var total float64
+		for i, x := range xs {
+			dist[i] = sqrDist(centers[nearest(centers, x.Elems)], x.Elems)
+			total += dist[i]
+		}
+		// Choose with probability proportional to squared distance.
+		next := r.Intn(len(xs))
+		if total > 0 {
+			t := r.Float64() * total
+			for i, d := range dist {
+				if t < d {
+					next = i
+					break
+				}
+				t -= d
+			}
+		}
+		centers = append(centers, xs[next].Elems)
+	}
+	// Copy to avoid modifying the examples.
+	for j := range centers {
+		centers[j] = append([]float64(nil), centers[j]...)
+	}
+	return centers
+}
+
+func nearest(centers [][]float64, x []float64) int {
+	arg, min := -1, 0.0
+	for j, c := range centers {
+		if d := sqrDist(c, x); arg < 0 || d < min {
+			arg, min = j, d
+		}
+	}
+	return arg
+}
+
+func sqrDist(a, b []float64) float64 {
+	var d float64
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return d
+}
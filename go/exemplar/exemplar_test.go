@@ -0,0 +1,106 @@
+package exemplar
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+)
+
+func randImage(r *rand.Rand, offset float64) *rimg64.Multi {
+	x := rimg64.NewMulti(2, 3, 2)
+	for i := range x.Elems {
+		x.Elems[i] = offset + r.NormFloat64()
+	}
+	return x
+}
+
+func TestCalib(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := &slide.AffineScorer{Tmpl: randImage(r, 0), Bias: 3}
+	orig := append([]float64(nil), s.Tmpl.Elems...)
+	var neg []*rimg64.Multi
+	for i := 0; i < 100; i++ {
+		neg = append(neg, randImage(r, 1))
+	}
+	c := FitCalib(Scores(s, neg))
+	calib := c.Apply(s)
+	for i := range orig {
+		if s.Tmpl.Elems[i] != orig[i] {
+			t.Fatal("calibration modified the template")
+		}
+	}
+	var mean, sqr float64
+	for _, y := range Scores(calib, neg) {
+		mean += y
+		sqr += y * y
+	}
+	mean /= float64(len(neg))
+	variance := sqr/float64(len(neg)) - mean*mean
+	if math.Abs(mean) > 1e-9 || math.Abs(variance-1) > 1e-9 {
+		t.Errorf("calibrated scores: mean %.3g, variance %.3g", mean, variance)
+	}
+	if c := FitCalib([]float64{2, 2}); c.Scale != 1 || c.Offset != -2 {
+		t.Errorf("constant scores: got %+v", c)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var xs []*rimg64.Multi
+	for i := 0; i < 30; i++ {
+		xs = append(xs, randImage(r, float64(10*(i%3))))
+	}
+	assign, err := Cluster(xs, 3, 100, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Examples with the same offset must be in the same cluster.
+	for i := range xs {
+		for j := range xs {
+			if (i%3 == j%3) != (assign[i] == assign[j]) {
+				t.Fatalf("examples %d and %d: clusters %d and %d", i, j, assign[i], assign[j])
+			}
+		}
+	}
+	means := Means(xs, assign, 3)
+	for j, m := range means {
+		if m == nil {
+			t.Errorf("cluster %d is empty", j)
+		}
+	}
+	if _, err := Cluster(xs, 31, 100, r); err == nil {
+		t.Error("expected error for more clusters than examples")
+	}
+}
+
+func TestMaxScorer_gob(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var in struct{ Scorer slide.Scorer }
+	in.Scorer = &MaxScorer{Members: []*slide.AffineScorer{
+		{Tmpl: randImage(r, 0), Bias: 1},
+		{Tmpl: randImage(r, 0), Bias: 2},
+	}}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out struct{ Scorer slide.Scorer }
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	s, ok := out.Scorer.(*MaxScorer)
+	if !ok {
+		t.Fatalf("decoded type %T", out.Scorer)
+	}
+	if len(s.Members) != 2 || s.Members[1].Bias != 2 || !s.Size().Eq(in.Scorer.Size()) {
+		t.Errorf("decoded scorer differs")
+	}
+	if _, err := (&MaxScorer{}).Score(randImage(r, 0)); err == nil {
+		t.Error("expected error for empty ensemble")
+	}
+}
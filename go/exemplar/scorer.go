@@ -0,0 +1,46 @@
+// Package exemplar provides ensembles of linear templates,
+// one per positive example or cluster of examples,
+// which score a window by the maximum of their calibrated scores.
+package exemplar
+
+import (
+	"encoding/gob"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+)
+
+func init() {
+	// Enable a MaxScorer to be saved in a detect.FeatTmpl.
+	gob.Register(&MaxScorer{})
+}
+
+// MaxScorer is a slide.Scorer which takes the maximum score
+// of a set of templates of the same size.
+// The calibration of each template is included in its weights and bias.
+type MaxScorer struct {
+	Members []*slide.AffineScorer
+}
+
+func (s *MaxScorer) Size() image.Point {
+	return s.Members[0].Size()
+}
+
+// Score returns the maximum score of the members.
+func (s *MaxScorer) Score(x *rimg64.Multi) (float64, error) {
+	if len(s.Members) == 0 {
+		return 0, fmt.Errorf("empty ensemble")
+	}
+	max := math.Inf(-1)
+	for i, m := range s.Members {
+		y, err := m.Score(x)
+		if err != nil {
+			return 0, fmt.Errorf("member %d: %v", i, err)
+		}
+		max = math.Max(max, y)
+	}
+	return max, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/data"
+	"github.com/jvlmdr/shift-invar/go/exemplar"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/nfnt/resize"
+)
+
+// ExemplarTrainer trains one LDA template per positive example
+// or per cluster of positive examples.
+// The templates share the circulant factorization of the background covariance.
+// A window is scored by the maximum over the calibrated templates.
+type ExemplarTrainer struct {
+	Lambda float64
+	// Number of clusters of positive examples (k-means).
+	// Zero means one template per example.
+	Clusters int
+	// Calibration of the templates against each other.
+	// "none" or "std", which standardizes the scores
+	// of random windows from the negative images.
+	Calib string
+	// Number of random negative windows for calibration.
+	CalibNeg int
+}
+
+func (t *ExemplarTrainer) Field(name string) string {
+	value := reflect.ValueOf(t).Elem().FieldByName(name)
+	if !value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// ExemplarTrainerSet provides a mechanism to specify a set of ExemplarTrainers.
+type ExemplarTrainerSet struct {
+	Lambda   []float64
+	Clusters []int
+	Calib    []string
+	// Only used if Calib is not "none".
+	CalibNeg []int
+}
+
+func (set *ExemplarTrainerSet) Fields() []string {
+	return []string{"Lambda", "Clusters", "Calib", "CalibNeg"}
+}
+
+func (set *ExemplarTrainerSet) Enumerate() []Trainer {
+	var ts []Trainer
+	for _, lambda := range set.Lambda {
+		for _, clusters := range set.Clusters {
+			for _, calib := range set.Calib {
+				if calib == "none" {
+					// CalibNeg will have no effect.
+					ts = append(ts, &ExemplarTrainer{Lambda: lambda, Clusters: clusters, Calib: calib})
+					continue
+				}
+				for _, calibNeg := range set.CalibNeg {
+					t := &ExemplarTrainer{Lambda: lambda, Clusters: clusters, Calib: calib, CalibNeg: calibNeg}
+					ts = append(ts, t)
+				}
+			}
+		}
+	}
+	return ts
+}
+
+func (t *ExemplarTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
+	switch t.Calib {
+	case "none", "std":
+	default:
+		return nil, fmt.Errorf("unknown calibration: %q", t.Calib)
+	}
+	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
+	if err != nil {
+		return nil, err
+	}
+	if len(pos) == 0 {
+		return nil, fmt.Errorf("empty positive set")
+	}
+
+	// Take the exemplars to be the examples or the means of clusters.
+	featsize, channels := phi.Size(region.Size), phi.Channels()
+	exemplars := pos
+	if t.Clusters > 0 {
+		// The number of examples depends on the subset,
+		// so fewer examples than clusters does not stop the other subsets.
+		if t.Clusters > len(pos) {
+			err := fmt.Errorf("%d clusters but only %d examples", t.Clusters, len(pos))
+			return &SolveResult{Error: err.Error()}, nil
+		}
+		assign, err := exemplar.Cluster(pos, t.Clusters, 100, r)
+		if err != nil {
+			return nil, err
+		}
+		exemplars = nil
+		for _, mean := range exemplar.Means(pos, assign, t.Clusters) {
+			exemplars = append(exemplars, &rimg64.Multi{
+				Width:    featsize.X,
+				Height:   featsize.Y,
+				Channels: channels,
+				Elems:    mean,
+			})
+		}
+	}
+	log.Printf("train %d templates from %d examples", len(exemplars), len(pos))
+
+	// Sample held-out negatives for calibration.
+	var calibNeg []*rimg64.Multi
+	if t.Calib != "none" {
+		rects, err := data.RandomWindows(t.CalibNeg, negIms, dataset, searchOpts.Pad.Margin, region.Size, r)
+		if err != nil {
+			return nil, err
+		}
+		calibNeg, err = data.Examples(negIms, rects, dataset, phi, searchOpts.Pad.Extend, region, false, interp)
+		if err != nil {
+			return nil, err
+		}
+		if len(calibNeg) == 0 {
+			return nil, fmt.Errorf("empty calibration set")
+		}
+	}
+
+	total, err := toepcov.LoadTotalExt(statsFile)
+	if err != nil {
+		return nil, err
+	}
+	distr := toepcov.Normalize(total, true)
+	distr.Covar.AddLambdaI(t.Lambda)
+
+	var dur SolveDuration
+	start := time.Now()
+	// Factorize once for all templates.
+	muler := new(circcov.InvMuler)
+	if err := muler.Init(distr.Covar, featsize.X, featsize.Y); err != nil {
+		return &SolveResult{Error: err.Error()}, nil
+	}
+	scorer := new(exemplar.MaxScorer)
+	for _, x := range exemplars {
+		substStart := time.Now()
+		w := muler.Mul(toepcov.SubMean(x, distr.Mean))
+		dur.Subst += time.Since(substStart)
+		// Give the background mean a score of zero.
+		member := &slide.AffineScorer{Tmpl: w, Bias: -meanDot(w, distr.Mean)}
+		if t.Calib == "std" {
+			member = exemplar.FitCalib(exemplar.Scores(member, calibNeg)).Apply(member)
+		}
+		scorer.Members = append(scorer.Members, member)
+	}
+	dur.Total = time.Since(start)
+
+	tmpl := &detect.FeatTmpl{Scorer: scorer, PixelShape: region}
+	return &SolveResult{Tmpl: tmpl, Dur: dur}, nil
+}
@@ -45,6 +45,16 @@ func (x DetectorKey) TmplFile() string {
 	return fmt.Sprintf("tmpl-%s.gob", x.Ident())
 }
 
+// PoolFile is where a PoolKeeper stores its negatives.
+func (x DetectorKey) PoolFile() string {
+	return fmt.Sprintf("pool-%s.bin", x.Ident())
+}
+
+// MiningFile is where a PoolKeeper stores the state of mining.
+func (x DetectorKey) MiningFile() string {
+	return fmt.Sprintf("mining-%s.json", x.Ident())
+}
+
 type ResultsKey struct {
 	DetectorKey
 	TestSet Set
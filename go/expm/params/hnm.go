@@ -4,15 +4,14 @@ import (
 	"fmt"
 	"image"
 	"log"
+	"math/rand"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/jvlmdr/go-cv/detect"
 	"github.com/jvlmdr/go-cv/feat"
 	"github.com/jvlmdr/go-cv/rimg64"
 	"github.com/jvlmdr/go-cv/slide"
-	"github.com/jvlmdr/go-svm/svm"
 	"github.com/jvlmdr/shift-invar/go/data"
 	"github.com/jvlmdr/shift-invar/go/imset"
 	"github.com/jvlmdr/shift-invar/go/vecset"
@@ -29,8 +28,31 @@ type HardNegTrainer struct {
 	PerRound   int  // Maximum number to add in each round.
 	RequirePos bool // Hard negatives must have at least MinScore.
 	MinScore   float64
+	// Remove negatives whose margin exceeds EvictMargin
+	// from the pool before each round of mining.
+	// Only has an effect if NegBehav.Accum is true.
+	Evict       bool    `json:",omitempty"`
+	EvictMargin float64 `json:",omitempty"`
 	// SVM options.
+	SVMInit
 	Term SVMTerm
+
+	// Files in which to persist the pool of negatives.
+	// Not part of the configuration.
+	poolFile, stateFile string
+}
+
+// SetPoolFiles enables the pool to be saved after every round
+// and mining to resume from a saved pool.
+func (t *HardNegTrainer) SetPoolFiles(pool, state string) {
+	t.poolFile, t.stateFile = pool, state
+}
+
+// WithInit returns a copy of the trainer with a different initialization.
+func (t *HardNegTrainer) WithInit(init string) Trainer {
+	u := *t
+	u.SVMInit = t.SVMInit.withInit(init)
+	return &u
 }
 
 type NegBehavior struct {
@@ -83,6 +105,12 @@ func (t *HardNegTrainer) Field(name string) string {
 		return fmt.Sprint(t.RequirePos)
 	case "MinScore":
 		return fmt.Sprint(t.MinScore)
+	case "Evict":
+		return fmt.Sprint(t.Evict)
+	case "EvictMargin":
+		return fmt.Sprint(t.EvictMargin)
+	case "Init", "CovarLambda", "Whiten":
+		return t.SVMInit.Field(name)
 	default:
 		return ""
 	}
@@ -103,7 +131,12 @@ type HardNegTrainerSet struct {
 	PerRound     []int    // Maximum number to add in each round.
 	RequirePos   []bool   // Check that score is positive.
 	MinScore     []float64
+	// Evict easy negatives from the pool?
+	// Empty means false.
+	Evict       []bool
+	EvictMargin []float64
 	// SVM options.
+	SVMInitSet
 	Term []SVMTermSet
 }
 
@@ -111,7 +144,8 @@ func (set *HardNegTrainerSet) Fields() []string {
 	return []string{
 		"Gamma", "Lambda",
 		"IsolateInit", "InitNegCost", "Rounds", "NormalizeNeg", "Accum",
-		"InitNeg", "PerRound", "RequirePos", "MinScore",
+		"InitNeg", "PerRound", "RequirePos", "MinScore", "Evict", "EvictMargin",
+		"Init", "CovarLambda", "Whiten",
 		"Term.Epochs", "Term.RelGap", "Term.AbsGap",
 	}
 }
@@ -163,6 +197,20 @@ func (set *HardNegTrainerSet) Enumerate() []Trainer {
 		}
 	}
 
+	evicts := []ScoreThreshold{{Enforce: false}}
+	if len(set.Evict) > 0 {
+		evicts = nil
+	}
+	for _, evict := range set.Evict {
+		if !evict {
+			evicts = append(evicts, ScoreThreshold{Enforce: false})
+			continue
+		}
+		for _, margin := range set.EvictMargin {
+			evicts = append(evicts, ScoreThreshold{Enforce: true, Value: margin})
+		}
+	}
+
 	var terms []SVMTerm
 	for _, term := range set.Term {
 		terms = append(terms, term.Enumerate()...)
@@ -176,18 +224,25 @@ func (set *HardNegTrainerSet) Enumerate() []Trainer {
 					for _, initNeg := range set.InitNeg {
 						for _, perRound := range set.PerRound {
 							for _, thresh := range thresholds {
-								t := &HardNegTrainer{
-									Gamma:      gamma,
-									Lambda:     lambda,
-									Bias:       set.Bias,
-									Term:       term,
-									NegBehav:   behav,
-									InitNeg:    initNeg,
-									PerRound:   perRound,
-									RequirePos: thresh.Enforce,
-									MinScore:   thresh.Value,
+								for _, evict := range evicts {
+									for _, init := range set.SVMInitSet.Enumerate() {
+										t := &HardNegTrainer{
+											Gamma:       gamma,
+											Lambda:      lambda,
+											Bias:        set.Bias,
+											Term:        term,
+											NegBehav:    behav,
+											InitNeg:     initNeg,
+											PerRound:    perRound,
+											RequirePos:  thresh.Enforce,
+											MinScore:    thresh.Value,
+											Evict:       evict.Enforce,
+											EvictMargin: evict.Value,
+											SVMInit:     init,
+										}
+										ts = append(ts, t)
+									}
 								}
-								ts = append(ts, t)
 							}
 						}
 					}
@@ -209,7 +264,7 @@ func (xs byScore) Len() int           { return len(xs) }
 func (xs byScore) Less(i, j int) bool { return xs[i].Score < xs[j].Score }
 func (xs byScore) Swap(i, j int)      { xs[i], xs[j] = xs[j], xs[i] }
 
-func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error) {
+func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
 	// Over-ride MinScore in searchOpts.
 	if t.RequirePos {
 		searchOpts.DetFilter.MinScore = t.MinScore
@@ -221,7 +276,7 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 	// Positive examples are extracted and stored as vectors.
 	// TODO: Check dataset.CanTrain()?
 	log.Print("sample positive examples")
-	pos, err := data.Examples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, interp)
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
 	if err != nil {
 		return nil, err
 	}
@@ -229,53 +284,97 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 		return nil, fmt.Errorf("empty positive set")
 	}
 
+	featsize := phi.Size(region.Size)
+	channels := phi.Channels()
+	dim := featsize.X * featsize.Y * channels
+	if t.Bias != 0 {
+		dim++
+	}
+	// Resume from a saved pool of negatives if possible.
+	pool, state, err := loadMining(t.poolFile, t.stateFile, dim)
+	if err != nil {
+		return nil, err
+	}
+	resumed := pool != nil
+	if resumed {
+		log.Printf("resume after round %d with %d negatives", state.Round, pool.Len())
+	}
+
 	// Choose an initial set of random negatives.
+	// The windows are chosen even when resuming so that
+	// the isolated initial negatives are the same.
 	// TODO: Check dataset.CanTrain()?
 	log.Print("choose initial negative examples")
-	negRects, err := data.RandomWindows(t.InitNeg, negIms, dataset, searchOpts.Pad.Margin, region.Size)
+	negRects, err := data.RandomWindows(t.InitNeg, negIms, dataset, searchOpts.Pad.Margin, region.Size, r)
 	if err != nil {
 		return nil, err
 	}
-	log.Print("sample initial negative examples")
-	initNeg, err := data.Examples(negIms, negRects, dataset, phi, searchOpts.Pad.Extend, region, false, interp)
+	// Each round has its own random source so that a resumed round
+	// visits the examples in the same order as it would have originally.
+	// The solver is still initialized afresh when resuming,
+	// so the weights are not guaranteed to be identical,
+	// and the epochs of earlier rounds are not counted.
+	seed := r.Int63()
+	// Initial negatives are kept in the pool unless isolated.
+	var initNeg []*rimg64.Multi
+	if t.NegBehav.Init.Isolate || pool == nil {
+		log.Print("sample initial negative examples")
+		initNeg, err = data.Examples(negIms, negRects, dataset, phi, searchOpts.Pad.Extend, region, false, interp)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("number of negatives:", len(initNeg))
+	}
+	if pool == nil {
+		pool, state = vecset.NewPool(dim), new(MiningState)
+		stats := MiningStats{Round: 0}
+		if !t.NegBehav.Init.Isolate {
+			stats.Added, err = addToPool(pool, negIms, negRects, initNeg, t.Bias)
+			if err != nil {
+				return nil, err
+			}
+			stats.Duplicate = len(initNeg) - stats.Added
+		}
+		stats.Size = pool.Len()
+		state.Stats = append(state.Stats, stats)
+		if err := saveMining(t.poolFile, t.stateFile, pool, state); err != nil {
+			return nil, err
+		}
+	}
+
+	solver, err := newSVMSolver(t.SVMInit, statsFile, featsize, channels, t.Bias)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("number of negatives:", len(initNeg))
-
 	var (
-		hardNeg []*rimg64.Multi
-		tmpl    *detect.FeatTmpl
+		// Weights in the original space, including the bias element,
+		// and the offset which is not represented by the bias element.
+		weights []float64
+		offset  float64
+		// Weights in the space of the solver, to initialize the next round.
+		solverWeights []float64
+		tmpl          *detect.FeatTmpl
+		epochs        int
 	)
-	if !t.NegBehav.Init.Isolate {
-		hardNeg = initNeg
-	}
-
-	for round := 0; round <= t.NegBehav.Rounds; round++ {
-		if round > 0 {
+	for round := state.Round; round <= t.NegBehav.Rounds; round++ {
+		if round > state.Round {
+			stats := MiningStats{Round: round}
 			// Search all negative images to obtain new hard negatives.
 			var dets []Det
 			for i, name := range negIms {
 				log.Printf("search image %d / %d: %s", i+1, len(negIms), name)
-				// Load image.
-				file := dataset.File(name)
-				s := time.Now()
-				im, err := loadImage(file)
+				// Pyramids are re-used from previous rounds if they are in the cache.
+				imDets, durSearch, err := data.MultiScale(dataset, name, tmpl.Scorer, tmpl.PixelShape, searchOpts)
 				if err != nil {
-					log.Printf("load test image: %s, error: %v", file, err)
+					log.Printf("search image: %s, error: %v", dataset.File(name), err)
 					continue
 				}
-				durLoad := time.Since(s)
-				imDets, durSearch, err := detect.MultiScale(im, tmpl.Scorer, tmpl.PixelShape, searchOpts)
-				if err != nil {
-					return nil, err
-				}
 				for _, det := range imDets {
 					dets = append(dets, Det{Image: name, Det: det})
 				}
 				log.Printf(
-					"load %v, resize %v, feat %v, slide %v, suppr %v",
-					durLoad, durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
+					"resize %v, feat %v, slide %v, suppr %v",
+					durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
 				)
 			}
 			// Sort detections decreasing by score.
@@ -284,6 +383,7 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 				dets = dets[:t.PerRound]
 			}
 			log.Println("found hard negatives:", len(dets))
+			stats.Found = len(dets)
 
 			// Group by image, discard score.
 			objRects := make(map[string][]image.Rectangle)
@@ -295,7 +395,7 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 			var count int
 			var totalExcl data.ExcludeCount
 			for im, objs := range objRects {
-				size, err := loadImageSize(dataset.File(im))
+				size, err := data.CachesOf(dataset).Images.Size(dataset.File(im))
 				if err != nil {
 					return nil, err
 				}
@@ -311,19 +411,36 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 				"valid: %d, bad aspect: %d, too small: %d, not inside: %d",
 				count, totalExcl.BadAspect, totalExcl.TooSmall, totalExcl.NotInside,
 			)
-			// Extract vectors.
+
+			if !t.NegBehav.Accum {
+				// Replace the negatives of the previous round.
+				pool = vecset.NewPool(dim)
+			} else if t.Evict {
+				// Shrink the pool before adding to it.
+				stats.Evicted = evictEasy(pool, weights, offset, t.EvictMargin)
+				log.Println("evicted easy negatives:", stats.Evicted)
+			}
+			// Only extract vectors which are not already in the pool.
+			stats.Duplicate = dedupeRects(negIms, exampleRects, pool)
 			nextHardNeg, err := data.Examples(negIms, exampleRects, dataset, phi, searchOpts.Pad.Extend, region, false, interp)
 			if err != nil {
 				return nil, err
 			}
 			log.Println("new hard negatives:", len(nextHardNeg))
-
-			if t.NegBehav.Accum {
-				hardNeg = append(hardNeg, nextHardNeg...)
-			} else {
-				hardNeg = nextHardNeg
+			stats.Added, err = addToPool(pool, negIms, exampleRects, nextHardNeg, t.Bias)
+			if err != nil {
+				return nil, err
+			}
+			stats.Size = pool.Len()
+			log.Printf(
+				"round %d: found %d, duplicate %d, added %d, evicted %d, total %d",
+				stats.Round, stats.Found, stats.Duplicate, stats.Added, stats.Evicted, stats.Size,
+			)
+			state.Round = round
+			state.Stats = append(state.Stats, stats)
+			if err := saveMining(t.poolFile, t.stateFile, pool, state); err != nil {
+				return nil, err
 			}
-			log.Println("total hard negatives:", len(hardNeg))
 		}
 
 		// Train an SVM, update template.
@@ -349,7 +466,7 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 			}
 		}
 		// Add other negatives.
-		if len(hardNeg) > 0 {
+		if pool.Len() > 0 {
 			hardNegCost := (1 - t.Gamma) / t.Lambda
 			if t.NegBehav.Init.Isolate {
 				hardNegCost *= (1 - t.NegBehav.Init.Cost)
@@ -367,43 +484,42 @@ func (t *HardNegTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 				}
 				hardNegCost /= float64(len(negIms)) * float64(mult)
 			case "examples":
-				hardNegCost /= float64(len(hardNeg))
+				hardNegCost /= float64(pool.Len())
 			}
 
-			x = append(x, &imset.VecSet{Set: imset.Slice(hardNeg), Bias: t.Bias})
-			for _ = range hardNeg {
+			x = append(x, pool)
+			for i := 0; i < pool.Len(); i++ {
 				y = append(y, -1)
 				c = append(c, hardNegCost)
 			}
 		}
 
-		weights, err := svm.Train(vecset.NewUnion(x), y, c, t.Term.Terminate)
+		// Start from the previous round if possible.
+		init := solverWeights
+		if init == nil {
+			init, err = solver.initial(pos)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var roundEpochs int
+		roundRand := rand.New(rand.NewSource(seed + int64(round)))
+		solverWeights, roundEpochs, err = solver.solve(solver.wrap(vecset.NewUnion(x)), y, c, init, t.Term, roundRand, nil)
 		if err != nil {
 			return nil, err
 		}
+		epochs += roundEpochs
 
-		featsize := phi.Size(region.Size)
-		channels := phi.Channels()
-		// Extract bias.
-		var bias float64
-		if t.Bias != 0 {
-			bias = weights[featsize.X*featsize.Y*channels] * t.Bias
-		}
-		// Exclude bias if present.
-		weights = weights[:featsize.X*featsize.Y*channels]
 		// Pack weights into image in detection template.
+		weightsIm, bias := solver.template(solverWeights)
+		weights, offset = solver.vector(weightsIm, bias)
 		tmpl = &detect.FeatTmpl{
 			Scorer: &slide.AffineScorer{
-				Tmpl: &rimg64.Multi{
-					Width:    featsize.X,
-					Height:   featsize.Y,
-					Channels: channels,
-					Elems:    weights,
-				},
+				Tmpl: weightsIm,
 				Bias: bias,
 			},
 			PixelShape: region,
 		}
 	}
-	return &SolveResult{Tmpl: tmpl}, nil
+	return &SolveResult{Tmpl: tmpl, Mining: state.Stats, Epochs: epochs, Resumed: resumed}, nil
 }
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/data"
+	"github.com/jvlmdr/shift-invar/go/imset"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/jvlmdr/shift-invar/go/tron"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+	"github.com/nfnt/resize"
+)
+
+// LinearTrainer fits a linear model with a smooth loss
+// ("logistic" or "sq-hinge") using trust-region Newton.
+// The examples and costs are the same as SVMTrainer.
+type LinearTrainer struct {
+	Loss         string
+	Bias         float64
+	Lambda       float64
+	Gamma        float64
+	WindowStride int
+	AllScales    bool `json:",omitempty"`
+	// Precondition the Newton systems using the stationary covariance
+	// of the background from the stats file.
+	Precond bool
+	Term    LinearTerm
+}
+
+func (t *LinearTrainer) Field(name string) string {
+	if strings.HasPrefix(name, "Term.") {
+		return t.Term.Field(strings.TrimPrefix(name, "Term."))
+	}
+	value := reflect.ValueOf(t).Elem().FieldByName(name)
+	if !value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// LinearTrainerSet provides a mechanism to specify a set of LinearTrainers.
+type LinearTrainerSet struct {
+	Loss         []string
+	Bias         float64
+	Lambda       []float64
+	Gamma        []float64
+	WindowStride []int
+	AllScales    []bool // Empty means false.
+	Precond      []bool // Empty means false.
+	Term         []LinearTermSet
+}
+
+func (set *LinearTrainerSet) Fields() []string {
+	return []string{"Loss", "Lambda", "Gamma", "WindowStride", "AllScales", "Precond", "Term.Eps", "Term.MaxIter"}
+}
+
+func (set *LinearTrainerSet) Enumerate() []Trainer {
+	var terms []LinearTerm
+	for _, term := range set.Term {
+		terms = append(terms, term.Enumerate()...)
+	}
+	allScales := set.AllScales
+	if len(allScales) == 0 {
+		allScales = []bool{false}
+	}
+	preconds := set.Precond
+	if len(preconds) == 0 {
+		preconds = []bool{false}
+	}
+
+	var ts []Trainer
+	for _, loss := range set.Loss {
+		for _, lambda := range set.Lambda {
+			for _, gamma := range set.Gamma {
+				for _, stride := range set.WindowStride {
+					for _, all := range allScales {
+						for _, precond := range preconds {
+							for _, term := range terms {
+								t := &LinearTrainer{
+									Loss:         loss,
+									Bias:         set.Bias,
+									Lambda:       lambda,
+									Gamma:        gamma,
+									WindowStride: stride,
+									AllScales:    all,
+									Precond:      precond,
+									Term:         term,
+								}
+								ts = append(ts, t)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return ts
+}
+
+// LinearTerm specifies when to stop the trust-region Newton solver.
+type LinearTerm struct {
+	// Relative reduction of gradient norm.
+	Eps float64
+	// Zero or less means no limit.
+	MaxIter int
+}
+
+func (term LinearTerm) Field(name string) string {
+	value := reflect.ValueOf(term).FieldByName(name)
+	if !value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+type LinearTermSet struct {
+	Eps     []float64
+	MaxIter []int
+}
+
+func (set *LinearTermSet) Enumerate() []LinearTerm {
+	var x []LinearTerm
+	for _, eps := range set.Eps {
+		for _, maxIter := range set.MaxIter {
+			x = append(x, LinearTerm{Eps: eps, MaxIter: maxIter})
+		}
+	}
+	return x
+}
+
+func (t *LinearTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
+	loss, err := tron.NewLoss(t.Loss)
+	if err != nil {
+		return nil, err
+	}
+	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
+	if err != nil {
+		return nil, err
+	}
+	if len(pos) == 0 {
+		return nil, fmt.Errorf("empty positive set")
+	}
+	stride := ceilDiv(t.WindowStride, phi.Rate())
+	neg, numNegWindows, err := negativeSets(negIms, dataset, phi, region, searchOpts, stride, t.AllScales, t.Bias, interp)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		x []vecset.Set
+		y []float64
+		c []float64
+	)
+	x = append(x, &imset.VecSet{Set: imset.Slice(pos), Bias: t.Bias})
+	for _ = range pos {
+		y = append(y, 1)
+		c = append(c, t.Gamma/t.Lambda/float64(len(pos)))
+	}
+	for i := range neg {
+		x = append(x, neg[i])
+		for j := 0; j < neg[i].Len(); j++ {
+			y = append(y, -1)
+			c = append(c, (1-t.Gamma)/t.Lambda/float64(numNegWindows))
+		}
+	}
+
+	featsize, channels := phi.Size(region.Size), phi.Channels()
+	opts := tron.Opts{Eps: t.Term.Eps, MaxIter: t.Term.MaxIter}
+	if t.Precond {
+		// The negatives dominate the Hessian, which is approximately
+		// I + kappa E[x x'] where x is a background window with the bias element.
+		kappa := (1 - t.Gamma) / t.Lambda * loss.Deriv2(0)
+		if kappa > 0 {
+			opts.Precond, err = covarPrecond(statsFile, kappa, featsize, channels, t.Bias)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			log.Printf("no preconditioning: negatives do not contribute to hessian (kappa %g)", kappa)
+		}
+	}
+
+	start := time.Now()
+	res, err := tron.Solve(&tron.Problem{X: vecset.NewUnion(x), Y: y, C: c, Loss: loss}, opts)
+	if err != nil {
+		return nil, err
+	}
+	dur := time.Since(start)
+	log.Printf("trust-region newton: %d iterations, %d cg iterations, objective %.6g", res.Iters, res.CGIters, res.F)
+
+	weights := res.W
+	// Extract bias.
+	var bias float64
+	if t.Bias != 0 {
+		bias = weights[featsize.X*featsize.Y*channels] * t.Bias
+	}
+	// Pack weights into image in detection template.
+	tmpl := &detect.FeatTmpl{
+		Scorer: &slide.AffineScorer{
+			Tmpl: &rimg64.Multi{
+				Width:    featsize.X,
+				Height:   featsize.Y,
+				Channels: channels,
+				// Exclude bias if present.
+				Elems: weights[:featsize.X*featsize.Y*channels],
+			},
+			Bias: bias,
+		},
+		PixelShape: region,
+	}
+	return &SolveResult{Tmpl: tmpl, Dur: SolveDuration{Total: dur}}, nil
+}
+
+// covarPrecond returns a function which multiplies by the inverse of
+// I + kappa E[x x'], where x is a background window with the bias element appended.
+// The second moment is M + u u' with u = (mu, bias) and M = diag(S, 0),
+// where S is the circulant approximation to the covariance in the stats file
+// and mu is the stationary mean.
+// The inverse of I + kappa M is obtained in the Fourier domain
+// and the rank-one term is added using the Sherman-Morrison formula.
+// kappa must be positive.
+func covarPrecond(statsFile string, kappa float64, featsize image.Point, channels int, bias float64) (func([]float64) []float64, error) {
+	if kappa <= 0 {
+		return nil, fmt.Errorf("preconditioner needs positive kappa: %g", kappa)
+	}
+	total, err := toepcov.LoadTotalExt(statsFile)
+	if err != nil {
+		return nil, err
+	}
+	distr := toepcov.Normalize(total, true)
+	if distr.Covar.Channels != channels {
+		return nil, fmt.Errorf("covariance has %d channels, features have %d", distr.Covar.Channels, channels)
+	}
+	// (I + kappa S)^-1 = 1/kappa (S + 1/kappa I)^-1
+	distr.Covar.AddLambdaI(1 / kappa)
+	op := new(circcov.InvMuler)
+	if err := op.Init(distr.Covar, featsize.X, featsize.Y); err != nil {
+		return nil, err
+	}
+	n := featsize.X * featsize.Y * channels
+	// Multiplies by the inverse of I + kappa M.
+	inv := func(v []float64) []float64 {
+		im := &rimg64.Multi{
+			Width:    featsize.X,
+			Height:   featsize.Y,
+			Channels: channels,
+			Elems:    append([]float64(nil), v[:n]...),
+		}
+		z := op.Mul(im).Elems
+		floats.Scale(1/kappa, z)
+		if bias != 0 {
+			z = append(z, v[n])
+		}
+		return z
+	}
+	u := toepcov.ConstImage(featsize.X, featsize.Y, distr.Mean).Elems
+	if bias != 0 {
+		u = append(u, bias)
+	}
+	return rankOneInv(inv, u, kappa), nil
+}
+
+// rankOneInv returns a function which multiplies by the inverse of
+// A + kappa u u' given one which multiplies by the inverse of A.
+// A must be symmetric.
+func rankOneInv(inv func([]float64) []float64, u []float64, kappa float64) func([]float64) []float64 {
+	// (A + k u u')^-1 v = A^-1 v - k A^-1 u (u' A^-1 v) / (1 + k u' A^-1 u)
+	w := inv(u)
+	denom := 1 + kappa*floats.Dot(u, w)
+	return func(v []float64) []float64 {
+		z := inv(v)
+		floats.AddScaled(z, -kappa*floats.Dot(w, v)/denom, w)
+		return z
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+// Checks that rankOneInv solves (A + kappa u u') z = v for diagonal A.
+func TestRankOneInv(t *testing.T) {
+	const kappa = 0.7
+	a := []float64{1, 2, 0.5, 4}
+	u := []float64{1, -2, 3, 0.5}
+	v := []float64{0.3, 1, -1, 2}
+	inv := func(x []float64) []float64 {
+		y := make([]float64, len(x))
+		for i := range x {
+			y[i] = x[i] / a[i]
+		}
+		return y
+	}
+	z := rankOneInv(inv, u, kappa)(v)
+	// Multiply by A + kappa u u'.
+	got := make([]float64, len(z))
+	for i := range z {
+		got[i] = a[i]*z[i] + kappa*u[i]*floats.Dot(u, z)
+	}
+	for i := range v {
+		if math.Abs(got[i]-v[i]) > 1e-9 {
+			t.Errorf("at %d: want %.6g, got %.6g", i, v[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math/rand"
+	"path"
+	"time"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/detect"
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-cv/slide"
+	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/data"
+	"github.com/jvlmdr/shift-invar/go/exactcov"
+	"github.com/jvlmdr/shift-invar/go/lowrankcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/nfnt/resize"
+)
+
+// LowRankTrainer is like ToeplitzTrainer with the covariance
+// corrected by a low-rank matrix.
+// The correction is estimated from the residual between the Toeplitz model
+// and either the empirical covariance of random negative windows
+// or the exact covariance in ExactFile.
+type LowRankTrainer struct {
+	Lambda float64
+	// Rank of the correction.
+	Rank int
+	// Number of random negative windows from which to estimate the correction.
+	// Ignored if ExactFile is set.
+	NumNeg int
+	// File containing exactcov.Total for windows at least the size of the template,
+	// relative to the directory of the stationary statistics.
+	// If empty, the correction is estimated from NumNeg random windows.
+	ExactFile string `json:",omitempty"`
+	// Algorithm for the Toeplitz part.
+	// Can be "chol", "cg" or "pcg".
+	Algo string
+	// Tolerance for when Algo is "cg" or "pcg".
+	Tol float64
+}
+
+func (t *LowRankTrainer) Field(name string) string {
+	switch name {
+	case "Lambda":
+		return fmt.Sprint(t.Lambda)
+	case "Rank":
+		return fmt.Sprint(t.Rank)
+	case "NumNeg":
+		return fmt.Sprint(t.NumNeg)
+	case "Algo":
+		return t.Algo
+	case "Tol":
+		return fmt.Sprint(t.Tol)
+	case "ExactFile":
+		return t.ExactFile
+	default:
+		return ""
+	}
+}
+
+// LowRankTrainerSet specifies a set of LowRankTrainers.
+type LowRankTrainerSet struct {
+	Lambda []float64
+	Rank   []int
+	NumNeg []int
+	Algo   []string
+	Tol    []float64
+	// Exact statistics to use for all trainers (optional).
+	ExactFile string `json:",omitempty"`
+}
+
+func (set *LowRankTrainerSet) Fields() []string {
+	return []string{"Lambda", "Rank", "NumNeg", "Algo", "Tol"}
+}
+
+func (set *LowRankTrainerSet) Enumerate() []Trainer {
+	var ts []Trainer
+	for _, lambda := range set.Lambda {
+		for _, rank := range set.Rank {
+			for _, numNeg := range set.NumNeg {
+				for _, algo := range set.Algo {
+					tols := []float64{0}
+					if algo == "cg" || algo == "pcg" {
+						tols = set.Tol
+					}
+					for _, tol := range tols {
+						t := &LowRankTrainer{Lambda: lambda, Rank: rank, NumNeg: numNeg, Algo: algo, Tol: tol, ExactFile: set.ExactFile}
+						ts = append(ts, t)
+					}
+				}
+			}
+		}
+	}
+	return ts
+}
+
+func (t *LowRankTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
+	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
+	if err != nil {
+		return nil, err
+	}
+	// Extract positive examples.
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
+	if err != nil {
+		return nil, err
+	}
+	if len(pos) == 0 {
+		return nil, fmt.Errorf("empty positive set")
+	}
+
+	// Compute mean of positive examples.
+	featsize, channels := phi.Size(region.Size), phi.Channels()
+	meanPos := rimg64.NewMulti(featsize.X, featsize.Y, channels)
+	for _, x := range pos {
+		floats.Add(meanPos.Elems, x.Elems)
+	}
+	floats.Scale(1/float64(len(pos)), meanPos.Elems)
+
+	// Load covariance from file.
+	total, err := toepcov.LoadTotalExt(statsFile)
+	if err != nil {
+		return nil, err
+	}
+	// Obtain covariance and mean from sums.
+	distr := toepcov.Normalize(total, true)
+
+	cov, err := t.correction(negIms, dataset, phi, statsFile, region, interp, searchOpts, distr, featsize, r)
+	if err != nil {
+		return nil, err
+	}
+	// Regularize after estimating the residual.
+	distr.Covar.AddLambdaI(t.Lambda)
+
+	// Subtract negative mean from positive example.
+	delta := toepcov.SubMean(meanPos, distr.Mean)
+	var dur SolveDuration
+	start := time.Now()
+	var invmuler lowrankcov.InvMuler
+	if err := invmuler.Init(cov, lowrankcov.SolveOpts{Algo: t.Algo, Tol: t.Tol}); err != nil {
+		return &SolveResult{Error: err.Error()}, nil
+	}
+	startSubst := time.Now()
+	weights, err := invmuler.Mul(delta)
+	if err != nil {
+		return &SolveResult{Error: err.Error()}, nil
+	}
+	dur.Subst = time.Since(startSubst)
+	dur.Total = time.Since(start)
+
+	// Pack weights into image in detection template.
+	tmpl := &detect.FeatTmpl{
+		Scorer:     &slide.AffineScorer{Tmpl: weights},
+		PixelShape: region,
+	}
+	return &SolveResult{Tmpl: tmpl, Dur: dur}, nil
+}
+
+// correction estimates the low-rank correction to the Toeplitz covariance
+// from the exact statistics if they are given,
+// otherwise from randomly sampled negative windows.
+func (t *LowRankTrainer) correction(negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, distr *toepcov.Distr, featsize image.Point, r *rand.Rand) (*lowrankcov.Covar, error) {
+	if t.ExactFile != "" {
+		fname := path.Join(path.Dir(statsFile), t.ExactFile)
+		total := new(exactcov.Total)
+		if err := fileutil.LoadExt(fname, total); err != nil {
+			return nil, err
+		}
+		log.Printf("estimate rank-%d correction from exact statistics: %s", t.Rank, fname)
+		return lowrankcov.FromTotal(total, distr.Mean, distr.Covar, featsize.X, featsize.Y, t.Rank)
+	}
+
+	// Sample negative windows to estimate correction.
+	negRects, err := data.RandomWindows(t.NumNeg, negIms, dataset, searchOpts.Pad.Margin, region.Size, r)
+	if err != nil {
+		return nil, err
+	}
+	log.Print("sample negative examples for low-rank correction")
+	neg, err := data.Examples(negIms, negRects, dataset, phi, searchOpts.Pad.Extend, region, false, interp)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("estimate rank-%d correction from %d windows", t.Rank, len(neg))
+	return lowrankcov.FromExamples(neg, distr.Mean, distr.Covar, t.Rank)
+}
@@ -30,13 +30,17 @@ type DatasetMessage struct {
 
 func main() {
 	var trainDatasetMessage, testDatasetMessage DatasetMessage
-	flag.StringVar(&trainDatasetMessage.Name, "train-dataset", "", "{inria, caltech}")
+	flag.StringVar(&trainDatasetMessage.Name, "train-dataset", "", fmt.Sprint(data.ListDatasets()))
 	flag.StringVar(&trainDatasetMessage.Spec, "train-dataset-spec", "", "Dataset parameters (JSON)")
-	flag.StringVar(&testDatasetMessage.Name, "test-dataset", "", "{inria, caltech}")
+	flag.StringVar(&testDatasetMessage.Name, "test-dataset", "", fmt.Sprint(data.ListDatasets()))
 	flag.StringVar(&testDatasetMessage.Spec, "test-dataset-spec", "", "Dataset parameters (JSON)")
 
 	var (
 		numFolds = flag.Int("folds", 5, "Cross-validation folds")
+		seed     = flag.Int64("seed", 0, "Seed for splitting folds and for training if params do not specify Seed")
+		strategy = flag.String("split", RandomSplit, fmt.Sprintf("Strategy for dividing training images into folds %v", SplitStrategies))
+		groupKey = flag.String("group-key", "", "Regexp whose first submatch gives the group of an image for grouped splits (e.g. \"^(set\\d+/V\\d+)/\" for Caltech videos)")
+		order    = flag.String("sweep-order", DefaultOrder, "Order in which to train configurations {default, warm-start}")
 		covarDir = flag.String("covar-dir", "", "Directory to which StatsFile is relative")
 		// Positive example configuration.
 		flip        = flag.Bool("flip", false, "Incorporate horizontally mirrored examples?")
@@ -49,6 +53,12 @@ func main() {
 		minIgnore  = flag.Float64("min-ignore", 0.5, "Minimum that a region can be covered to be ignored")
 		doTest     = flag.Bool("do-test", false, "Run experiments to measure performance on test set?")
 		doTestVar  = flag.Bool("do-test-var", false, "Run experiments to measure variance of performance on test set?")
+		// Cache configuration.
+		imageCache = flag.Int64("image-cache", 1<<27, "Maximum number of decoded pixels to keep in memory (0 to disable)")
+		pyrCache   = flag.Int64("pyr-cache", 0, "Maximum number of feature pyramid elements to keep in memory (0 to disable)")
+		sizeCache  = flag.String("size-cache", "image-sizes.txt", "File in which to cache image dimensions (empty to disable)")
+		workers    = flag.Int("workers", 0, "Number of goroutines for example extraction (0 for number of CPUs)")
+		poolDir    = flag.String("pool-dir", "", "Directory in which to save pools of hard negatives so that mining can resume (empty to disable)")
 	)
 	flag.Parse()
 	dstrfn.ExecIfSlave()
@@ -58,11 +68,19 @@ func main() {
 		os.Exit(1)
 	}
 	paramsFile := flag.Arg(0)
+	switch *order {
+	case DefaultOrder, WarmStartOrder:
+	default:
+		log.Fatalf("unknown sweep order: %s", *order)
+	}
 
 	paramset := new(ParamSet)
 	if err := fileutil.LoadExt(paramsFile, paramset); err != nil {
 		log.Fatal(err)
 	}
+	if len(paramset.Seed) == 0 {
+		paramset.Seed = []int64{*seed}
+	}
 
 	// FPPIs at which to compute miss rate.
 	fppis := make([]float64, 9)
@@ -77,6 +95,13 @@ func main() {
 			MinScore: 0, // Ignored; later set to -inf.
 		},
 		SupprMaxNum: *detsPerIm,
+		Cache: data.CacheOpts{
+			ImagePixels: *imageCache,
+			PyrElems:    *pyrCache,
+			SizeFile:    *sizeCache,
+			Workers:     *workers,
+		},
+		PoolDir: *poolDir,
 	}
 
 	params := paramset.Enumerate()
@@ -96,10 +121,8 @@ func main() {
 	}
 	// Split images into folds.
 	// Cache splits due to their randomness.
-	var trainSplits [][]string
-	err = fileutil.Cache(&trainSplits, "folds.json", func() [][]string {
-		return split(trainIms, *numFolds)
-	})
+	splitOpts := SplitOpts{Strategy: *strategy, Folds: *numFolds, Seed: *seed, GroupKey: *groupKey}
+	trainSplits, err := loadOrSplit("folds.json", trainIms, trainDataset, splitOpts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -114,10 +137,10 @@ func main() {
 		log.Fatal("testing dataset is empty")
 	}
 	// Split testing data into folds too.
-	var testSplits [][]string
-	err = fileutil.Cache(&testSplits, "test-folds.json", func() [][]string {
-		return split(testIms, *numFolds)
-	})
+	// The split strategy applies only to cross-validation,
+	// the testing folds are always random.
+	testSplitOpts := SplitOpts{Strategy: RandomSplit, Folds: *numFolds, Seed: *seed + 1}
+	testSplits, err := loadOrSplit("test-folds.json", testIms, testDataset, testSplitOpts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -142,7 +165,7 @@ func main() {
 		sets["test"][fmt.Sprintf("fold-%d", i)] = testSplits[i]
 	}
 
-	crossVal := Experiment{TrainDataset: "train", TestDataset: "train", SubsetPairs: make([]SubsetPair, *numFolds)}
+	crossVal := Experiment{TrainDataset: "train", TestDataset: "train", SubsetPairs: make([]SubsetPair, len(trainSplits))}
 	for i := range crossVal.SubsetPairs {
 		crossVal.SubsetPairs[i] = SubsetPair{
 			Train: fmt.Sprintf("excl-fold-%d", i),
@@ -150,7 +173,8 @@ func main() {
 		}
 	}
 	full := Experiment{TrainDataset: "train", TestDataset: "test", SubsetPairs: []SubsetPair{{Train: "all", Test: "all"}}}
-	testVar := Experiment{TrainDataset: "train", TestDataset: "test", SubsetPairs: make([]SubsetPair, *numFolds)}
+	// Number of folds may differ for leave-one-group-out.
+	testVar := Experiment{TrainDataset: "train", TestDataset: "test", SubsetPairs: make([]SubsetPair, min(len(trainSplits), len(testSplits)))}
 	for i := range testVar.SubsetPairs {
 		testVar.SubsetPairs[i] = SubsetPair{
 			Train: fmt.Sprintf("excl-fold-%d", i),
@@ -172,7 +196,13 @@ func main() {
 	expmResults := make(map[string]ExperimentResult)
 
 	trainMapFunc := func(trainInputs []TrainInput, trainDataset DatasetMessage) error {
-		return trainMap(trainInputs, trainDataset, *covarDir, *flip, resize.InterpolationFunction(*trainInterp), searchOpts)
+		trainFunc := func(inputs []TrainInput) error {
+			return trainMap(inputs, trainDataset, *covarDir, *flip, resize.InterpolationFunction(*trainInterp), searchOpts)
+		}
+		if *order == WarmStartOrder {
+			return trainWaves(warmStartWaves(trainInputs), trainFunc)
+		}
+		return trainFunc(trainInputs)
 	}
 
 	testMapFunc := func(testInputs []TestInput, testDataset DatasetMessage) (ExperimentResult, error) {
@@ -340,10 +370,10 @@ func printResults(paramset *ParamSet, params []Param, expmNames []string, expms
 	for _, expmName := range expmNames {
 		expm := expms[expmName]
 		for _ = range expm.SubsetPairs {
-			fmt.Fprintf(buf, "\t\t\t\t\t")
+			fmt.Fprintf(buf, "\t\t\t\t\t\t")
 		}
 		if len(expm.SubsetPairs) > 1 {
-			fmt.Fprintf(buf, "\tTotal\t\t\t\t\t\t\t")
+			fmt.Fprintf(buf, "\tTotal\t\t\t\t\t\t\t\t\t")
 		}
 	}
 	fmt.Fprintln(buf)
@@ -356,10 +386,10 @@ func printResults(paramset *ParamSet, params []Param, expmNames []string, expms
 		for _, subsets := range expm.SubsetPairs {
 			trainSetName := Set{Dataset: expm.TrainDataset, Subset: subsets.Train}
 			testSetName := Set{Dataset: expm.TestDataset, Subset: subsets.Test}
-			fmt.Fprintf(buf, "\t%s-%s\t\t\t\t", trainSetName.Ident(), testSetName.Ident())
+			fmt.Fprintf(buf, "\t%s-%s\t\t\t\t\t", trainSetName.Ident(), testSetName.Ident())
 		}
 		if len(expm.SubsetPairs) > 1 {
-			fmt.Fprintf(buf, "\tPerf\t\tTrainDur\t\tSolveDur\t\tSubstDur\t")
+			fmt.Fprintf(buf, "\tPerf\t\tTrainDur\t\tSolveDur\t\tSubstDur\t\tEpochs\t")
 		}
 	}
 	fmt.Fprintln(buf)
@@ -371,10 +401,10 @@ func printResults(paramset *ParamSet, params []Param, expmNames []string, expms
 	for _, expmName := range expmNames {
 		expm := expms[expmName]
 		for _ = range expm.SubsetPairs {
-			fmt.Fprintf(buf, "\tPerf\tError\tTrainDur\tSolveDur\tSubstDur")
+			fmt.Fprintf(buf, "\tPerf\tError\tTrainDur\tSolveDur\tSubstDur\tEpochs")
 		}
 		if len(expm.SubsetPairs) > 1 {
-			fmt.Fprintf(buf, "\tMean\tVar\tMean\tVar\tMean\tVar\tMean\tVar")
+			fmt.Fprintf(buf, "\tMean\tVar\tMean\tVar\tMean\tVar\tMean\tVar\tMean\tVar")
 		}
 	}
 	fmt.Fprintln(buf)
@@ -401,12 +431,13 @@ func printResults(paramset *ParamSet, params []Param, expmNames []string, expms
 				results = append(results, result)
 				if result.TrainReport.Error != "" {
 					fail = true
-					fmt.Fprintf(buf, "\t\t%s\t\t\t", result.TrainReport.Error)
+					fmt.Fprintf(buf, "\t\t%s\t\t\t\t", result.TrainReport.Error)
 				} else {
-					fmt.Fprintf(buf, "\t%.6g\t\t%.6g\t%.6g\t%.6g", result.Perf,
+					fmt.Fprintf(buf, "\t%.6g\t\t%.6g\t%.6g\t%.6g\t%d", result.Perf,
 						result.TrainReport.TotalDur.Seconds(),
 						result.TrainReport.SolveDur.Total.Seconds(),
 						result.TrainReport.SolveDur.Subst.Seconds(),
+						result.TrainReport.Epochs,
 					)
 				}
 			}
@@ -418,19 +449,96 @@ func printResults(paramset *ParamSet, params []Param, expmNames []string, expms
 			}
 			if fail {
 				// At least one error occurred.
-				fmt.Fprint(buf, "\t\t\t\t\t\t\t\t")
+				fmt.Fprint(buf, "\t\t\t\t\t\t\t\t\t\t")
 				continue
 			}
 			stats := EstimateExperimentStats(results)
-			fmt.Fprintf(buf, "\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g",
+			fmt.Fprintf(buf, "\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g\t%.6g",
 				stats.Perf.Mean, stats.Perf.Var,
 				stats.TrainDur.Mean, stats.TrainDur.Var,
 				stats.SolveDur.Mean, stats.SolveDur.Var,
 				stats.SubstDur.Mean, stats.SubstDur.Var,
+				stats.Epochs.Mean, stats.Epochs.Var,
 			)
 		}
 		fmt.Fprintln(buf)
 	}
+	return printSpeedup(params, expmNames, expms, expmResults)
+}
+
+// initPairs finds the configurations which are initialized from the LDA template
+// and pairs each with the same configuration initialized from zero.
+func initPairs(params []Param) [][2]Param {
+	idents := make(map[string]bool)
+	for _, p := range params {
+		idents[p.Ident()] = true
+	}
+	var pairs [][2]Param
+	for _, p := range params {
+		initer, ok := p.Trainer.Spec.(SVMIniter)
+		if !ok || p.Trainer.Spec.Field("Init") != "lda" {
+			continue
+		}
+		q := p
+		q.Trainer = TrainerMessage{Type: p.Trainer.Type, Spec: initer.WithInit("zero")}
+		if !idents[q.Ident()] {
+			continue
+		}
+		pairs = append(pairs, [2]Param{p, q})
+	}
+	return pairs
+}
+
+// printSpeedup writes the number of epochs and the solve duration
+// of every configuration initialized from the LDA template
+// relative to the same configuration initialized from zero.
+// Does nothing if there are no such pairs.
+func printSpeedup(params []Param, expmNames []string, expms map[string]Experiment, expmResults map[string]ExperimentResult) error {
+	pairs := initPairs(params)
+	if len(pairs) == 0 {
+		return nil
+	}
+	out, err := os.Create("speedup.txt")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	buf := bufio.NewWriter(out)
+	defer buf.Flush()
+
+	fmt.Fprintln(buf, "LDA\tZero\tExperiment\tSubset\tEpochsLDA\tEpochsZero\tEpochRatio\tSolveDurLDA\tSolveDurZero\tDurRatio")
+	for _, pair := range pairs {
+		for _, expmName := range expmNames {
+			expm := expms[expmName]
+			for _, subsets := range expm.SubsetPairs {
+				var reports [2]*TrainReport
+				for i, p := range pair {
+					key := ResultsKey{
+						DetectorKey: DetectorKey{
+							Param:    p,
+							TrainSet: Set{Dataset: expm.TrainDataset, Subset: subsets.Train},
+						},
+						TestSet: Set{Dataset: expm.TestDataset, Subset: subsets.Test},
+					}
+					reports[i] = expmResults[expmName][key.Ident()].TrainReport
+				}
+				if reports[0].Error != "" || reports[1].Error != "" {
+					continue
+				}
+				// Resumed runs do not count the earlier rounds.
+				if reports[0].Resumed || reports[1].Resumed {
+					continue
+				}
+				lda, zero := reports[0], reports[1]
+				fmt.Fprintf(buf, "%s\t%s\t%s\t%s\t%d\t%d\t%.4g\t%.6g\t%.6g\t%.4g\n",
+					pair[0].Ident(), pair[1].Ident(), expmName, subsets.Train,
+					lda.Epochs, zero.Epochs, float64(zero.Epochs)/float64(lda.Epochs),
+					lda.SolveDur.Total.Seconds(), zero.SolveDur.Total.Seconds(),
+					zero.SolveDur.Total.Seconds()/lda.SolveDur.Total.Seconds(),
+				)
+			}
+		}
+	}
 	return nil
 }
 
@@ -439,7 +547,7 @@ type Stats struct {
 }
 
 type ExperimentStats struct {
-	Perf, TrainDur, SolveDur, SubstDur Stats
+	Perf, TrainDur, SolveDur, SubstDur, Epochs Stats
 }
 
 func EstimateExperimentStats(results []*TestResult) *ExperimentStats {
@@ -450,23 +558,28 @@ func EstimateExperimentStats(results []*TestResult) *ExperimentStats {
 		stats.TrainDur.Mean += r.TrainReport.TotalDur.Seconds()
 		stats.SolveDur.Mean += r.TrainReport.SolveDur.Total.Seconds()
 		stats.SubstDur.Mean += r.TrainReport.SolveDur.Subst.Seconds()
+		stats.Epochs.Mean += float64(r.TrainReport.Epochs)
 		stats.Perf.Var += square(r.Perf)
 		stats.TrainDur.Var += square(r.TrainReport.TotalDur.Seconds())
 		stats.SolveDur.Var += square(r.TrainReport.SolveDur.Total.Seconds())
 		stats.SubstDur.Var += square(r.TrainReport.SolveDur.Subst.Seconds())
+		stats.Epochs.Var += square(float64(r.TrainReport.Epochs))
 	}
 	stats.Perf.Mean /= float64(len(results))
 	stats.TrainDur.Mean /= float64(len(results))
 	stats.SolveDur.Mean /= float64(len(results))
 	stats.SubstDur.Mean /= float64(len(results))
+	stats.Epochs.Mean /= float64(len(results))
 	stats.Perf.Var /= float64(len(results))
 	stats.TrainDur.Var /= float64(len(results))
 	stats.SolveDur.Var /= float64(len(results))
 	stats.SubstDur.Var /= float64(len(results))
+	stats.Epochs.Var /= float64(len(results))
 	stats.Perf.Var = math.Sqrt(stats.Perf.Var - square(stats.Perf.Mean))
 	stats.TrainDur.Var = math.Sqrt(stats.TrainDur.Var - square(stats.TrainDur.Mean))
 	stats.SolveDur.Var = math.Sqrt(stats.SolveDur.Var - square(stats.SolveDur.Mean))
 	stats.SubstDur.Var = math.Sqrt(stats.SubstDur.Var - square(stats.SubstDur.Mean))
+	stats.Epochs.Var = math.Sqrt(stats.Epochs.Var - square(stats.Epochs.Mean))
 	return &stats
 }
 
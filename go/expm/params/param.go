@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/jvlmdr/go-cv/featset"
+	"github.com/jvlmdr/shift-invar/go/data"
 )
 
 // Param specifies options for training and testing a detector.
@@ -26,6 +27,12 @@ type Param struct {
 	PyrStep       float64
 	MaxTestScale  float64
 	TestMargin    int
+	// Seed for all random choices in training.
+	// Omitted when zero so that existing identifiers are unchanged.
+	Seed int64 `json:",omitempty"`
+	// Augmentation of positive examples.
+	// Omitted when nil so that existing identifiers are unchanged.
+	Augment *data.Augment `json:",omitempty"`
 }
 
 // Serialize is a representation of Param as a string.
@@ -64,6 +71,11 @@ func (p Param) Field(name string) string {
 			panic(fmt.Sprintf("encode feature: %v", err))
 		}
 		return string(repr)
+	case "Augment":
+		if p.Augment == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%+v", *p.Augment)
 	}
 	fieldValue := reflect.ValueOf(p).FieldByName(name)
 	if !fieldValue.IsValid() {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Checks that options added to existing trainers are omitted
+// when they have their default values, so that the identifiers
+// of existing configurations are unchanged.
+func TestTrainer_omitNewFields(t *testing.T) {
+	cases := []struct {
+		Trainer interface{}
+		Fields  []string
+	}{
+		{&ToeplitzTrainer{Lambda: 1, Method: ToeplitzMethod{Circ: true}}, []string{"Pad", "ZeroBoundary"}},
+		{&ToepInvTrainer{Lambda: 1, Res: 64}, []string{"Embed"}},
+	}
+	for _, c := range cases {
+		buf, err := json.Marshal(c.Trainer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range c.Fields {
+			if strings.Contains(string(buf), `"`+field+`"`) {
+				t.Errorf("%T: field %s present in %s", c.Trainer, field, buf)
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "image"
+import (
+	"image"
+
+	"github.com/jvlmdr/shift-invar/go/data"
+)
 
 type ParamSet struct {
 	TrainerSets []TrainerSetMessage
@@ -16,6 +20,14 @@ type ParamSet struct {
 	PyrStep       []float64
 	MaxTestScale  []float64
 	TestMargin    []int
+	// Seeds for random choices in training.
+	// Empty for seed zero.
+	// The command replaces an empty list with its -seed flag.
+	Seed []int64
+	// Augmentation of positive examples.
+	// Empty for no augmentation.
+	// A null element also gives no augmentation.
+	Augment []*data.Augment
 }
 
 func (set *ParamSet) Fields() []string {
@@ -28,11 +40,19 @@ func (set *ParamSet) Fields() []string {
 		fields = append(fields, "Trainer."+field)
 	}
 	fields = append(fields, "TrainPad", "AspectReject", "ResizeFor", "MaxTrainScale")
-	fields = append(fields, "PyrStep", "MaxTestScale", "TestMargin")
+	fields = append(fields, "PyrStep", "MaxTestScale", "TestMargin", "Seed", "Augment")
 	return fields
 }
 
 func (set *ParamSet) Enumerate() []Param {
+	seeds := set.Seed
+	if len(seeds) == 0 {
+		seeds = []int64{0}
+	}
+	augments := set.Augment
+	if len(augments) == 0 {
+		augments = []*data.Augment{nil}
+	}
 	var ps []Param
 	// TODO: Not this.
 	for _, trainerSet := range set.TrainerSets {
@@ -50,21 +70,27 @@ func (set *ParamSet) Enumerate() []Param {
 											for _, pyrStep := range set.PyrStep {
 												for _, maxTestScale := range set.MaxTestScale {
 													for _, testMargin := range set.TestMargin {
-														p := Param{
-															Trainer:       trainer,
-															NegFrac:       negFrac,
-															Overlap:       overlap,
-															Size:          size,
-															Feat:          feat,
-															TrainPad:      trainPad,
-															AspectReject:  aspectReject,
-															ResizeFor:     resizeFor,
-															MaxTrainScale: maxTrainScale,
-															PyrStep:       pyrStep,
-															MaxTestScale:  maxTestScale,
-															TestMargin:    testMargin,
+														for _, seed := range seeds {
+															for _, augment := range augments {
+																p := Param{
+																	Trainer:       trainer,
+																	NegFrac:       negFrac,
+																	Overlap:       overlap,
+																	Size:          size,
+																	Feat:          feat,
+																	TrainPad:      trainPad,
+																	AspectReject:  aspectReject,
+																	ResizeFor:     resizeFor,
+																	MaxTrainScale: maxTrainScale,
+																	PyrStep:       pyrStep,
+																	MaxTestScale:  maxTestScale,
+																	TestMargin:    testMargin,
+																	Seed:          seed,
+																	Augment:       augment,
+																}
+																ps = append(ps, p)
+															}
 														}
-														ps = append(ps, p)
 													}
 												}
 											}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/imset"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// MiningStats describes one round of hard negative mining.
+// Round zero describes the initial negatives.
+type MiningStats struct {
+	Round     int
+	Found     int // Number of detections kept from search.
+	Duplicate int // Number of examples already in the pool.
+	Added     int // Number of examples added to the pool.
+	Evicted   int // Number of easy examples removed from the pool.
+	Size      int // Size of the pool at the end of the round.
+}
+
+// MiningState is stored alongside the pool of negatives.
+type MiningState struct {
+	// Number of rounds of mining whose negatives are in the pool.
+	Round int
+	// Number of vectors in the pool, to detect an inconsistent pair of files.
+	Size  int
+	Stats []MiningStats
+}
+
+// exampleKey identifies a window in an image.
+// Windows with equal keys are considered duplicates.
+func exampleKey(im string, rect image.Rectangle) string {
+	return fmt.Sprintf("%s %d %d %d %d", im, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+}
+
+// dedupeRects removes the rectangles whose keys are in the pool
+// or which occur earlier in the list.
+// Returns the number of rectangles removed.
+func dedupeRects(ims []string, rects map[string][]image.Rectangle, pool *vecset.Pool) int {
+	var n int
+	seen := make(map[string]bool)
+	for _, im := range ims {
+		var keep []image.Rectangle
+		for _, rect := range rects[im] {
+			key := exampleKey(im, rect)
+			if seen[key] || pool.Has(key) {
+				n++
+				continue
+			}
+			seen[key] = true
+			keep = append(keep, rect)
+		}
+		rects[im] = keep
+	}
+	return n
+}
+
+// addToPool adds the examples, with the bias appended, to the pool.
+// The examples must be in the order given by ims and rects.
+// Returns the number of examples which were added.
+func addToPool(pool *vecset.Pool, ims []string, rects map[string][]image.Rectangle, examples []*rimg64.Multi, bias float64) (int, error) {
+	set := &imset.VecSet{Set: imset.Slice(examples), Bias: bias}
+	var i, n int
+	for _, im := range ims {
+		for _, rect := range rects[im] {
+			if i >= len(examples) {
+				return 0, fmt.Errorf("more rectangles than examples (%d)", len(examples))
+			}
+			if pool.Add(exampleKey(im, rect), set.At(i)) {
+				n++
+			}
+			i++
+		}
+	}
+	if i != len(examples) {
+		return 0, fmt.Errorf("number of examples: rectangles %d, examples %d", i, len(examples))
+	}
+	return n, nil
+}
+
+// evictEasy removes the negatives whose margin -(w'x + offset) exceeds the threshold.
+// The offset is the part of the bias which is not in the vector of weights.
+// Returns the number of negatives removed.
+func evictEasy(pool *vecset.Pool, weights []float64, offset, margin float64) int {
+	return pool.Filter(func(i int) bool {
+		return -(floats.Dot(weights, pool.At(i)) + offset) <= margin
+	})
+}
+
+// loadMining loads a pool and the state of mining.
+// Returns nil if either file does not exist
+// or if the files are not consistent (e.g. an interrupted save).
+func loadMining(poolFile, stateFile string, dim int) (*vecset.Pool, *MiningState, error) {
+	for _, fname := range []string{poolFile, stateFile} {
+		if _, err := os.Stat(fname); os.IsNotExist(err) {
+			return nil, nil, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+	state := new(MiningState)
+	if err := fileutil.LoadJSON(stateFile, state); err != nil {
+		return nil, nil, err
+	}
+	pool, err := vecset.LoadPool(poolFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pool.Len() != state.Size {
+		log.Printf("ignore pool: has %d vectors, state has %d", pool.Len(), state.Size)
+		return nil, nil, nil
+	}
+	if pool.Dim() != dim {
+		return nil, nil, fmt.Errorf("pool has dimension %d, want %d", pool.Dim(), dim)
+	}
+	return pool, state, nil
+}
+
+// saveMining saves the pool and then the state of mining.
+// Does nothing if the file names are empty.
+func saveMining(poolFile, stateFile string, pool *vecset.Pool, state *MiningState) error {
+	if poolFile == "" || stateFile == "" {
+		return nil
+	}
+	state.Size = pool.Len()
+	if err := vecset.SavePool(poolFile, pool); err != nil {
+		return err
+	}
+	if err := fileutil.SaveJSON(stateFile, state); err != nil {
+		return err
+	}
+	log.Printf("saved pool of %d negatives after round %d: %s", pool.Len(), state.Round, poolFile)
+	return nil
+}
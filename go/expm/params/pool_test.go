@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func TestDedupeRects(t *testing.T) {
+	pool := vecset.NewPool(1)
+	pool.Add(exampleKey("a", image.Rect(0, 0, 2, 4)), []float64{0})
+	ims := []string{"a", "b"}
+	rects := map[string][]image.Rectangle{
+		"a": {image.Rect(0, 0, 2, 4), image.Rect(1, 0, 3, 4)},
+		"b": {image.Rect(0, 0, 2, 4), image.Rect(0, 0, 2, 4)},
+	}
+	if n := dedupeRects(ims, rects, pool); n != 2 {
+		t.Errorf("duplicates: want 2, got %d", n)
+	}
+	if len(rects["a"]) != 1 || !rects["a"][0].Eq(image.Rect(1, 0, 3, 4)) {
+		t.Errorf("image a: got %v", rects["a"])
+	}
+	if len(rects["b"]) != 1 {
+		t.Errorf("image b: got %v", rects["b"])
+	}
+}
+
+func TestAddToPool_evictEasy(t *testing.T) {
+	ims := []string{"a", "b"}
+	rects := map[string][]image.Rectangle{
+		"a": {image.Rect(0, 0, 1, 1), image.Rect(1, 0, 2, 1)},
+		"b": {image.Rect(0, 0, 1, 1)},
+	}
+	var examples []*rimg64.Multi
+	for _, v := range []float64{-3, 1, 2} {
+		x := rimg64.NewMulti(1, 1, 1)
+		x.Set(0, 0, 0, v)
+		examples = append(examples, x)
+	}
+	pool := vecset.NewPool(2)
+	n, err := addToPool(pool, ims, rects, examples, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || pool.Len() != 3 {
+		t.Fatalf("added: want 3, got %d (pool has %d)", n, pool.Len())
+	}
+	if !pool.Has(exampleKey("b", image.Rect(0, 0, 1, 1))) {
+		t.Error("key of image b not found")
+	}
+	// Score is x + 0.5 and margin is its negative.
+	weights := []float64{1, 0.5}
+	if n := evictEasy(pool, weights, 0, 1); n != 1 {
+		t.Errorf("evicted: want 1, got %d", n)
+	}
+	if pool.Has(exampleKey("a", image.Rect(0, 0, 1, 1))) {
+		t.Error("easy negative was not evicted")
+	}
+	// Score is x + 0.5 - 2 with an offset.
+	if n := evictEasy(pool, weights, -2, 0.4); n != 1 {
+		t.Errorf("evicted with offset: want 1, got %d", n)
+	}
+	if pool.Has(exampleKey("a", image.Rect(1, 0, 2, 1))) {
+		t.Error("easy negative was not evicted with offset")
+	}
+	if _, err := addToPool(pool, ims, rects, examples[:2], 1); err == nil {
+		t.Error("expected error for too few examples")
+	}
+}
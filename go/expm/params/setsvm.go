@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"reflect"
 	"strings"
 
@@ -121,13 +122,13 @@ func (set *SetSVMTermList) Enumerate() []SetSVMTerm {
 	return x
 }
 
-func (t *SetSVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error) {
+func (t *SetSVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
 	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
 	if err != nil {
 		return nil, err
 	}
 	// Positive examples are extracted and stored as vectors.
-	pos, err := data.Examples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, interp)
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/jvlmdr/go-file/fileutil"
+	"github.com/jvlmdr/shift-invar/go/data"
+)
+
+// Strategies for dividing images into folds.
+const (
+	// Assign each image to a fold independently.
+	// Folds may be empty.
+	RandomSplit = "random"
+	// Shuffle images and divide them into folds
+	// whose sizes differ by at most one.
+	BalancedSplit = "balanced"
+	// Like balanced, but also balance the number of
+	// positive and negative images in each fold.
+	StratifiedSplit = "stratified"
+	// Keep images with the same group key in the same fold
+	// and balance the number of images in each fold.
+	GroupedSplit = "grouped"
+	// One fold for each group.
+	// The number of folds is ignored.
+	LeaveOneGroupOutSplit = "leave-one-group-out"
+)
+
+// SplitStrategies lists the valid values of SplitOpts.Strategy.
+var SplitStrategies = []string{
+	RandomSplit, BalancedSplit, StratifiedSplit, GroupedSplit, LeaveOneGroupOutSplit,
+}
+
+// SplitOpts specifies how images are divided into folds.
+type SplitOpts struct {
+	Strategy string
+	Folds    int
+	Seed     int64
+	// Regular expression which gives the group of an image
+	// for the grouped strategies.
+	// The group is the first submatch if there is one,
+	// otherwise the whole match.
+	// For example, `^(set\d+/V\d+)/` groups Caltech images by video.
+	GroupKey string
+}
+
+// Folds is the content of the cached folds file.
+// The options are recorded so that a change can be detected.
+type Folds struct {
+	Split SplitOpts
+	Folds [][]string
+}
+
+// splitImages divides images into folds.
+// The dataset is used to identify negative images.
+func splitImages(ims []string, dataset data.ImageSet, opts SplitOpts) ([][]string, error) {
+	r := rand.New(rand.NewSource(opts.Seed))
+	switch opts.Strategy {
+	case RandomSplit:
+		return split(ims, opts.Folds, r), nil
+	case BalancedSplit:
+		return deal(shuffled(ims, r), make([][]string, opts.Folds), 0), nil
+	case StratifiedSplit:
+		var pos, neg []string
+		for _, im := range ims {
+			if dataset.IsNeg(im) {
+				neg = append(neg, im)
+			} else {
+				pos = append(pos, im)
+			}
+		}
+		folds := deal(shuffled(pos, r), make([][]string, opts.Folds), 0)
+		// Continue from the next fold so that sizes remain balanced.
+		return deal(shuffled(neg, r), folds, len(pos)), nil
+	case GroupedSplit, LeaveOneGroupOutSplit:
+		groups, keys, err := groupImages(ims, opts.GroupKey)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Strategy == LeaveOneGroupOutSplit {
+			folds := make([][]string, len(keys))
+			for i, key := range keys {
+				folds[i] = groups[key]
+			}
+			return folds, nil
+		}
+		return groupedSplit(groups, keys, opts.Folds, r), nil
+	default:
+		return nil, fmt.Errorf("unknown split strategy: %s", opts.Strategy)
+	}
+}
+
+func shuffled(x []string, r *rand.Rand) []string {
+	y := make([]string, len(x))
+	for i, j := range r.Perm(len(x)) {
+		y[i] = x[j]
+	}
+	return y
+}
+
+// deal assigns x to folds in turn, starting from fold offset mod n.
+func deal(x []string, folds [][]string, offset int) [][]string {
+	for i, xi := range x {
+		k := (i + offset) % len(folds)
+		folds[k] = append(folds[k], xi)
+	}
+	return folds
+}
+
+// groupImages partitions images by the group key.
+// Returns the groups and their keys in sorted order.
+// Images within a group remain in their original order.
+func groupImages(ims []string, expr string) (map[string][]string, []string, error) {
+	if expr == "" {
+		return nil, nil, fmt.Errorf("group key is empty")
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[string][]string)
+	var keys []string
+	for _, im := range ims {
+		m := re.FindStringSubmatch(im)
+		if m == nil {
+			return nil, nil, fmt.Errorf("group key %q does not match image: %s", expr, im)
+		}
+		key := m[0]
+		if len(m) > 1 {
+			key = m[1]
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], im)
+	}
+	sort.Strings(keys)
+	return groups, keys, nil
+}
+
+// groupedSplit assigns each group to the fold with the fewest images.
+// Groups are visited in decreasing order of size, with ties in random order.
+func groupedSplit(groups map[string][]string, keys []string, n int, r *rand.Rand) [][]string {
+	order := shuffled(keys, r)
+	sort.Stable(sort.Reverse(byGroupSize{order, groups}))
+	folds := make([][]string, n)
+	for _, key := range order {
+		k := 0
+		for i := range folds {
+			if len(folds[i]) < len(folds[k]) {
+				k = i
+			}
+		}
+		folds[k] = append(folds[k], groups[key]...)
+	}
+	return folds
+}
+
+type byGroupSize struct {
+	keys   []string
+	groups map[string][]string
+}
+
+func (s byGroupSize) Len() int { return len(s.keys) }
+func (s byGroupSize) Less(i, j int) bool {
+	return len(s.groups[s.keys[i]]) < len(s.groups[s.keys[j]])
+}
+func (s byGroupSize) Swap(i, j int) { s.keys[i], s.keys[j] = s.keys[j], s.keys[i] }
+
+// loadOrSplit loads folds from a file if it exists,
+// otherwise it divides the images and saves the folds to the file.
+// Returns an error if the file was created with different options.
+func loadOrSplit(fname string, ims []string, dataset data.ImageSet, opts SplitOpts) ([][]string, error) {
+	var folds Folds
+	if _, err := os.Stat(fname); err == nil {
+		folds, err = loadFolds(fname, opts)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %v", fname, err)
+		}
+	} else {
+		x, err := splitImages(ims, dataset, opts)
+		if err != nil {
+			return nil, err
+		}
+		folds = Folds{Split: opts, Folds: x}
+		if err := fileutil.SaveExt(fname, folds); err != nil {
+			return nil, fmt.Errorf("save %s: %v", fname, err)
+		}
+	}
+	if folds.Split != opts {
+		return nil, fmt.Errorf("%s was created with %+v, not %+v", fname, folds.Split, opts)
+	}
+	for i, fold := range folds.Folds {
+		if len(fold) == 0 {
+			log.Printf("warning: fold %d in %s is empty", i, fname)
+		}
+	}
+	return folds.Folds, nil
+}
+
+// loadFolds reads a folds file.
+// Files which predate split options contain only the folds.
+// These were split at random with an unrecorded seed,
+// therefore they are taken to be random splits with the seed in opts.
+func loadFolds(fname string, opts SplitOpts) (Folds, error) {
+	var folds Folds
+	err := fileutil.LoadExt(fname, &folds)
+	if err == nil {
+		return folds, nil
+	}
+	var legacy [][]string
+	if fileutil.LoadExt(fname, &legacy) != nil {
+		return Folds{}, err
+	}
+	log.Printf("%s predates split options: assume random split into %d folds", fname, len(legacy))
+	split := SplitOpts{Strategy: RandomSplit, Folds: len(legacy), Seed: opts.Seed}
+	return Folds{Split: split, Folds: legacy}, nil
+}
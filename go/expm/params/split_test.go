@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jvlmdr/shift-invar/go/data"
+)
+
+type splitTestDataset struct {
+	ims []string
+	neg map[string]bool
+}
+
+func (d *splitTestDataset) Images() []string        { return d.ims }
+func (d *splitTestDataset) File(im string) string   { return im }
+func (d *splitTestDataset) CanTrain(im string) bool { return true }
+func (d *splitTestDataset) CanTest(im string) bool  { return true }
+func (d *splitTestDataset) IsNeg(im string) bool    { return d.neg[im] }
+func (d *splitTestDataset) Annot(im string) data.Annot {
+	return data.Annot{}
+}
+
+// Caltech-style names: 3 sets with 2 videos of varying length.
+// Every fifth image is negative.
+func newSplitTestDataset() *splitTestDataset {
+	d := &splitTestDataset{neg: make(map[string]bool)}
+	for set := 0; set < 3; set++ {
+		for vid := 0; vid < 2; vid++ {
+			for i := 0; i < 3+set+vid; i++ {
+				name := fmt.Sprintf("set%02d/V%03d/I%05d", set, vid, i)
+				d.ims = append(d.ims, name)
+				if len(d.ims)%5 == 0 {
+					d.neg[name] = true
+				}
+			}
+		}
+	}
+	return d
+}
+
+// Checks that folds form a partition of the images.
+func checkPartition(t *testing.T, ims []string, folds [][]string) {
+	var all []string
+	for _, fold := range folds {
+		all = append(all, fold...)
+	}
+	want := append([]string(nil), ims...)
+	sort.Strings(want)
+	sort.Strings(all)
+	if !reflect.DeepEqual(want, all) {
+		t.Errorf("folds are not a partition of images")
+	}
+}
+
+func TestSplitImages_balanced(t *testing.T) {
+	d := newSplitTestDataset()
+	folds, err := splitImages(d.ims, d, SplitOpts{Strategy: BalancedSplit, Folds: 4, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, d.ims, folds)
+	for i, fold := range folds {
+		if n := len(fold); n < len(d.ims)/4 || n > (len(d.ims)+3)/4 {
+			t.Errorf("fold %d has %d images", i, n)
+		}
+	}
+}
+
+func TestSplitImages_stratified(t *testing.T) {
+	d := newSplitTestDataset()
+	folds, err := splitImages(d.ims, d, SplitOpts{Strategy: StratifiedSplit, Folds: 3, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, d.ims, folds)
+	var minNeg, maxNeg, minLen, maxLen int
+	for i, fold := range folds {
+		var neg int
+		for _, im := range fold {
+			if d.IsNeg(im) {
+				neg++
+			}
+		}
+		if i == 0 || neg < minNeg {
+			minNeg = neg
+		}
+		if i == 0 || neg > maxNeg {
+			maxNeg = neg
+		}
+		if i == 0 || len(fold) < minLen {
+			minLen = len(fold)
+		}
+		if i == 0 || len(fold) > maxLen {
+			maxLen = len(fold)
+		}
+	}
+	if maxNeg-minNeg > 1 {
+		t.Errorf("negatives per fold between %d and %d", minNeg, maxNeg)
+	}
+	if maxLen-minLen > 1 {
+		t.Errorf("images per fold between %d and %d", minLen, maxLen)
+	}
+}
+
+func TestSplitImages_grouped(t *testing.T) {
+	d := newSplitTestDataset()
+	opts := SplitOpts{Strategy: GroupedSplit, Folds: 3, Seed: 1, GroupKey: `^(set\d+/V\d+)/`}
+	folds, err := splitImages(d.ims, d, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, d.ims, folds)
+	// Each video should be in exactly one fold.
+	foldOf := make(map[string]int)
+	for i, fold := range folds {
+		if len(fold) == 0 {
+			t.Errorf("fold %d is empty", i)
+		}
+		for _, im := range fold {
+			key := im[:len("set00/V000")]
+			if j, ok := foldOf[key]; ok && j != i {
+				t.Errorf("video %s in folds %d and %d", key, i, j)
+			}
+			foldOf[key] = i
+		}
+	}
+}
+
+func TestSplitImages_leaveOneGroupOut(t *testing.T) {
+	d := newSplitTestDataset()
+	opts := SplitOpts{Strategy: LeaveOneGroupOutSplit, GroupKey: `^set\d+`}
+	folds, err := splitImages(d.ims, d, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, d.ims, folds)
+	if len(folds) != 3 {
+		t.Fatalf("want 3 folds, got %d", len(folds))
+	}
+	for i, fold := range folds {
+		want := fmt.Sprintf("set%02d", i)
+		for _, im := range fold {
+			if im[:5] != want {
+				t.Errorf("fold %d contains %s", i, im)
+			}
+		}
+	}
+}
+
+func TestSplitImages_groupKeyMismatch(t *testing.T) {
+	d := newSplitTestDataset()
+	opts := SplitOpts{Strategy: GroupedSplit, Folds: 2, GroupKey: `^video\d+`}
+	if _, err := splitImages(d.ims, d, opts); err == nil {
+		t.Error("expected error")
+	}
+}
+
+// Checks that folds files which predate split options are read as random splits.
+func TestLoadOrSplit_legacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := path.Join(dir, "folds.json")
+	content := `[["a","c"],null,["b"]]`
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := &splitTestDataset{ims: []string{"a", "b", "c"}}
+
+	folds, err := loadOrSplit(fname, d.ims, d, SplitOpts{Strategy: RandomSplit, Folds: 3, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a", "c"}, nil, {"b"}}
+	if !reflect.DeepEqual(folds, want) {
+		t.Errorf("want %v, got %v", want, folds)
+	}
+	if _, err := loadOrSplit(fname, d.ims, d, SplitOpts{Strategy: RandomSplit, Folds: 4}); err == nil {
+		t.Error("expect error for different number of folds")
+	}
+	if _, err := loadOrSplit(fname, d.ims, d, SplitOpts{Strategy: BalancedSplit, Folds: 3}); err == nil {
+		t.Error("expect error for different strategy")
+	}
+}
@@ -3,15 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/jvlmdr/go-cv/detect"
 	"github.com/jvlmdr/go-cv/feat"
-	"github.com/jvlmdr/go-cv/rimg64"
 	"github.com/jvlmdr/go-cv/slide"
-	"github.com/jvlmdr/go-svm/svm"
 	"github.com/jvlmdr/shift-invar/go/data"
 	"github.com/jvlmdr/shift-invar/go/imset"
 	"github.com/jvlmdr/shift-invar/go/vecset"
@@ -23,7 +22,21 @@ type SVMTrainer struct {
 	Lambda       float64
 	Gamma        float64
 	WindowStride int
-	Term         SVMTerm
+	// Use the windows at every scale of the search pyramid as negatives.
+	// Pyramids are computed on demand rather than kept in memory.
+	// The windows of each image are visited together,
+	// therefore Init must not be empty (see SVMInit).
+	AllScales bool `json:",omitempty"`
+	// Initialization and space of the solver.
+	SVMInit
+	Term SVMTerm
+}
+
+// WithInit returns a copy of the trainer with a different initialization.
+func (t *SVMTrainer) WithInit(init string) Trainer {
+	u := *t
+	u.SVMInit = t.SVMInit.withInit(init)
+	return &u
 }
 
 func (t *SVMTrainer) Field(name string) string {
@@ -43,11 +56,13 @@ type SVMTrainerSet struct {
 	Lambda       []float64
 	Gamma        []float64
 	WindowStride []int
-	Term         []SVMTermSet
+	AllScales    []bool // Empty means false.
+	SVMInitSet
+	Term []SVMTermSet
 }
 
 func (set *SVMTrainerSet) Fields() []string {
-	return []string{"Lambda", "Gamma", "WindowStride", "Term.Epochs", "Term.RelGap", "Term.AbsGap"}
+	return []string{"Lambda", "Gamma", "WindowStride", "AllScales", "Init", "CovarLambda", "Whiten", "Term.Epochs", "Term.RelGap", "Term.AbsGap"}
 }
 
 func (set *SVMTrainerSet) Enumerate() []Trainer {
@@ -56,19 +71,30 @@ func (set *SVMTrainerSet) Enumerate() []Trainer {
 		terms = append(terms, term.Enumerate()...)
 	}
 
+	allScales := set.AllScales
+	if len(allScales) == 0 {
+		allScales = []bool{false}
+	}
+
 	var ts []Trainer
 	for _, lambda := range set.Lambda {
 		for _, gamma := range set.Gamma {
 			for _, stride := range set.WindowStride {
-				for _, term := range terms {
-					t := &SVMTrainer{
-						Bias:         set.Bias,
-						Lambda:       lambda,
-						Gamma:        gamma,
-						WindowStride: stride,
-						Term:         term,
+				for _, all := range allScales {
+					for _, init := range set.SVMInitSet.Enumerate() {
+						for _, term := range terms {
+							t := &SVMTrainer{
+								Bias:         set.Bias,
+								Lambda:       lambda,
+								Gamma:        gamma,
+								WindowStride: stride,
+								AllScales:    all,
+								SVMInit:      init,
+								Term:         term,
+							}
+							ts = append(ts, t)
+						}
 					}
-					ts = append(ts, t)
 				}
 			}
 		}
@@ -128,13 +154,16 @@ func (set *SVMTermSet) Enumerate() []SVMTerm {
 	return x
 }
 
-func (t *SVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error) {
+func (t *SVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
+	if t.AllScales && t.Init == "" {
+		return nil, fmt.Errorf("windows at all scales require Init for the order of the examples")
+	}
 	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
 	if err != nil {
 		return nil, err
 	}
 	// Positive examples are extracted and stored as vectors.
-	pos, err := data.Examples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, interp)
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
 	if err != nil {
 		return nil, err
 	}
@@ -148,18 +177,10 @@ func (t *SVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi f
 	// featStride = ceil(minPixStride / rate)
 	stride := ceilDiv(t.WindowStride, phi.Rate())
 	// Negative examples are represented as indices into an image.
-	neg, err := data.WindowSets(negIms, dataset, phi, searchOpts.Pad, phi.Size(region.Size), stride, interp)
+	neg, numNegWindows, err := negativeSets(negIms, dataset, phi, region, searchOpts, stride, t.AllScales, t.Bias, interp)
 	if err != nil {
 		return nil, err
 	}
-	if len(neg) == 0 {
-		return nil, fmt.Errorf("empty negative set")
-	}
-	// Count number of examples for cost normalization.
-	var numNegWindows int
-	for i := range neg {
-		numNegWindows += neg[i].Len()
-	}
 
 	var (
 		x []vecset.Set
@@ -174,7 +195,7 @@ func (t *SVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi f
 	}
 	// Add each set of negative vectors.
 	for i := range neg {
-		x = append(x, &imset.VecSet{Set: neg[i], Bias: t.Bias})
+		x = append(x, neg[i])
 		ni := neg[i].Len()
 		// Labels and costs for every positive and negative example.
 		for j := 0; j < ni; j++ {
@@ -183,30 +204,69 @@ func (t *SVMTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi f
 		}
 	}
 
+	solver, err := newSVMSolver(t.SVMInit, statsFile, phi.Size(region.Size), phi.Channels(), t.Bias)
+	if err != nil {
+		return nil, err
+	}
+	// The initial solution is included in the duration.
 	start := time.Now()
-	weights, err := svm.Train(vecset.NewUnion(x), y, c, t.Term.Terminate)
+	init, err := solver.initial(pos)
+	if err != nil {
+		return nil, err
+	}
+	// The pyramid windows are shuffled within each image
+	// so that every pyramid is computed once per epoch.
+	var perm func() []int
+	if t.AllScales {
+		windows := neg[0].(*data.PyramidWindows)
+		perm = func() []int { return mergePerm(len(pos), windows.Perm(r), r) }
+	}
+	weights, epochs, err := solver.solve(solver.wrap(vecset.NewUnion(x)), y, c, init, t.Term, r, perm)
 	if err != nil {
 		return nil, err
 	}
 	dur := time.Since(start)
 
-	featsize := phi.Size(region.Size)
-	channels := phi.Channels()
-	// Exclude bias term if present.
-	weights = weights[:featsize.X*featsize.Y*channels]
 	// Pack weights into image in detection template.
+	weightsIm, _ := solver.template(weights)
 	tmpl := &detect.FeatTmpl{
-		Scorer: &slide.AffineScorer{
-			Tmpl: &rimg64.Multi{
-				Width:    featsize.X,
-				Height:   featsize.Y,
-				Channels: channels,
-				Elems:    weights,
-			},
-		},
+		Scorer:     &slide.AffineScorer{Tmpl: weightsIm},
 		PixelShape: region,
 	}
-	return &SolveResult{Tmpl: tmpl, Dur: SolveDuration{Total: dur}}, nil
+	return &SolveResult{Tmpl: tmpl, Dur: SolveDuration{Total: dur}, Epochs: epochs}, nil
+}
+
+// negativeSets returns the windows of the negative images as sets of vectors
+// and the total number of windows.
+// If allScales is true, the windows are taken from every level of the search pyramid,
+// otherwise they are taken from the images at their original scale.
+func negativeSets(negIms []string, dataset data.ImageSet, phi feat.Image, region detect.PadRect, searchOpts detect.MultiScaleOpts, stride int, allScales bool, bias float64, interp resize.InterpolationFunction) ([]vecset.Set, int, error) {
+	var neg []vecset.Set
+	if allScales {
+		set, err := data.NewPyramidWindows(negIms, dataset, phi, searchOpts.Pad, region.Size, searchOpts.MaxScale, searchOpts.PyrStep, stride, interp, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		set.Bias = bias
+		neg = append(neg, set)
+	} else {
+		sets, err := data.WindowSets(negIms, dataset, phi, searchOpts.Pad, phi.Size(region.Size), stride, interp)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, set := range sets {
+			neg = append(neg, &imset.VecSet{Set: set, Bias: bias})
+		}
+	}
+	// Count number of examples for cost normalization.
+	var n int
+	for i := range neg {
+		n += neg[i].Len()
+	}
+	if n == 0 {
+		return nil, 0, fmt.Errorf("empty negative set")
+	}
+	return neg, n, nil
 }
 
 func ceilDiv(a, b int) int {
@@ -218,3 +278,22 @@ func ceilDiv(a, b int) int {
 	}
 	return (a + b - 1) / b
 }
+
+// mergePerm returns a permutation of m examples followed by the windows
+// in which the windows remain in the order of perm
+// and the examples are shuffled and spread among them at random.
+func mergePerm(m int, perm []int, r *rand.Rand) []int {
+	ex := r.Perm(m)
+	order := make([]int, 0, m+len(perm))
+	var i, j int
+	for i < len(ex) || j < len(perm) {
+		if r.Intn(len(ex)-i+len(perm)-j) < len(ex)-i {
+			order = append(order, ex[i])
+			i++
+		} else {
+			order = append(order, m+perm[j])
+			j++
+		}
+	}
+	return order
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math/rand"
+	"reflect"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/go-svm/svm"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/dualcd"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// SVMInit specifies how the SVM solver is initialized
+// and whether the SVM is trained in a whitened feature space.
+// The zero value trains with svm.Train in the original space.
+type SVMInit struct {
+	// Init is empty to use svm.Train,
+	// or "zero" or "lda" to use dual coordinate descent
+	// starting from zero or from the circulant LDA template.
+	// Rounds of hard negative mining after the first
+	// start from the previous solution unless Init is empty.
+	Init string `json:",omitempty"`
+	// Regularization added to the background covariance
+	// for the LDA template and the whitening filters.
+	CovarLambda float64 `json:",omitempty"`
+	// Train in the space whitened by the background statistics.
+	// The template is mapped back to the original space.
+	Whiten bool `json:",omitempty"`
+}
+
+func (opts SVMInit) Field(name string) string {
+	value := reflect.ValueOf(opts).FieldByName(name)
+	if !value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// withInit replaces Init and clears CovarLambda if it would have no effect,
+// as in SVMInitSet.Enumerate.
+func (opts SVMInit) withInit(init string) SVMInit {
+	opts.Init = init
+	if init != "lda" && !opts.Whiten {
+		opts.CovarLambda = 0
+	}
+	return opts
+}
+
+// SVMInitSet provides a mechanism to specify a set of SVMInits.
+type SVMInitSet struct {
+	Init []string // Empty means svm.Train.
+	// Only used if Init is "lda" or Whiten is true.
+	CovarLambda []float64
+	Whiten      []bool // Empty means false.
+}
+
+func (set SVMInitSet) Enumerate() []SVMInit {
+	inits := set.Init
+	if len(inits) == 0 {
+		inits = []string{""}
+	}
+	whitens := set.Whiten
+	if len(whitens) == 0 {
+		whitens = []bool{false}
+	}
+	var x []SVMInit
+	for _, init := range inits {
+		for _, whiten := range whitens {
+			if init != "lda" && !whiten {
+				// CovarLambda will have no effect.
+				x = append(x, SVMInit{Init: init})
+				continue
+			}
+			for _, lambda := range set.CovarLambda {
+				x = append(x, SVMInit{Init: init, CovarLambda: lambda, Whiten: whiten})
+			}
+		}
+	}
+	return x
+}
+
+// SVMIniter is a Trainer whose SVM solver can be initialized
+// in different ways, so that the speed of each can be compared.
+type SVMIniter interface {
+	// WithInit returns a copy of the trainer with Init replaced.
+	WithInit(init string) Trainer
+}
+
+// svmSolver trains an SVM as specified by SVMInit
+// on vectors which are feature images with an optional bias element.
+type svmSolver struct {
+	SVMInit
+	size     image.Point
+	channels int
+	bias     float64
+
+	// Background statistics, if required.
+	distr    *toepcov.Distr
+	whitener *circcov.Whitener
+}
+
+func newSVMSolver(opts SVMInit, statsFile string, size image.Point, channels int, bias float64) (*svmSolver, error) {
+	switch opts.Init {
+	case "", "zero", "lda":
+	default:
+		return nil, fmt.Errorf("unknown initialization: %q", opts.Init)
+	}
+	s := &svmSolver{SVMInit: opts, size: size, channels: channels, bias: bias}
+	if opts.Init != "lda" && !opts.Whiten {
+		return s, nil
+	}
+	total, err := toepcov.LoadTotalExt(statsFile)
+	if err != nil {
+		return nil, err
+	}
+	s.distr = toepcov.Normalize(total, true)
+	if s.distr.Covar.Channels != channels {
+		return nil, fmt.Errorf("covariance has %d channels, features have %d", s.distr.Covar.Channels, channels)
+	}
+	if opts.Whiten {
+		// A grid of twice the template size captures every displacement.
+		s.whitener, err = circcov.NewWhitener(s.distr, circcov.InvApproxOpts{
+			Width:  2 * size.X,
+			Height: 2 * size.Y,
+			Band:   s.distr.Covar.Bandwidth,
+			Lambda: opts.CovarLambda,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// imageDim is the number of elements in the feature image.
+func (s *svmSolver) imageDim() int {
+	return s.size.X * s.size.Y * s.channels
+}
+
+// wrap returns the set of vectors in which the SVM is trained.
+func (s *svmSolver) wrap(set vecset.Set) vecset.Set {
+	if s.whitener == nil {
+		return set
+	}
+	return &whitenedSet{Set: set, Whitener: s.whitener, Size: s.size, Channels: s.channels}
+}
+
+// initial returns the initial solution for the first SVM,
+// or nil if Init is not "lda".
+// In the whitened space, the LDA template is the whitened mean of the positives.
+func (s *svmSolver) initial(pos []*rimg64.Multi) ([]float64, error) {
+	if s.Init != "lda" {
+		return nil, nil
+	}
+	meanPos := rimg64.NewMulti(s.size.X, s.size.Y, s.channels)
+	for _, x := range pos {
+		floats.Add(meanPos.Elems, x.Elems)
+	}
+	floats.Scale(1/float64(len(pos)), meanPos.Elems)
+
+	var (
+		w    *rimg64.Multi
+		bias float64
+	)
+	if s.whitener != nil {
+		w = s.whitener.Apply(meanPos)
+	} else {
+		cov := s.distr.Covar.Clone()
+		cov.AddLambdaI(s.CovarLambda)
+		delta := toepcov.SubMean(meanPos, s.distr.Mean)
+		var err error
+		w, _, err = solveCirculant(cov, delta, ToeplitzMethod{Circ: true}.Embed(delta.Width, delta.Height))
+		if err != nil {
+			return nil, err
+		}
+		// Give the background a score of zero.
+		bias = -meanDot(w, s.distr.Mean)
+	}
+	// The solver cannot represent the offset without a bias element.
+	init, _ := s.vector(w, bias)
+	return init, nil
+}
+
+// solve trains the SVM from an initial solution, which may be nil.
+// The examples are shuffled using r unless perm is not nil,
+// in which case it gives the order of the examples in each epoch
+// (see dualcd.TrainPerm).
+// Returns the weights and the number of epochs.
+func (s *svmSolver) solve(x vecset.Set, y, c, init []float64, term SVMTerm, r *rand.Rand, perm func() []int) ([]float64, int, error) {
+	var epochs int
+	termFunc := func(epoch int, f, g float64, w []float64, a map[int]float64) (bool, error) {
+		epochs = epoch
+		return term.Terminate(epoch, f, g, w, a)
+	}
+	if s.Init == "" {
+		if perm != nil {
+			return nil, 0, fmt.Errorf("svm.Train cannot visit the examples in a given order")
+		}
+		weights, err := svm.Train(x, y, c, termFunc)
+		if err != nil {
+			return nil, 0, err
+		}
+		return weights, epochs, nil
+	}
+	var res *dualcd.Result
+	var err error
+	if perm != nil {
+		res, err = dualcd.TrainPerm(x, y, c, init, termFunc, perm)
+	} else {
+		res, err = dualcd.Train(x, y, c, init, termFunc, r)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	log.Printf("dual coordinate descent: %d epochs", res.Epochs)
+	return res.W, res.Epochs, nil
+}
+
+// template maps the weights from the solver space to a template and bias
+// in the original space.
+func (s *svmSolver) template(weights []float64) (*rimg64.Multi, float64) {
+	n := s.imageDim()
+	tmpl := &rimg64.Multi{
+		Width:    s.size.X,
+		Height:   s.size.Y,
+		Channels: s.channels,
+		// Exclude bias if present.
+		Elems: weights[:n],
+	}
+	var bias float64
+	if s.bias != 0 {
+		bias = weights[n] * s.bias
+	}
+	if s.whitener != nil {
+		// The score is w' W (x - mu) where W is symmetric.
+		tmpl = toepcov.MulFFT(s.whitener.Filter, tmpl)
+		bias -= meanDot(tmpl, s.whitener.Mean)
+	}
+	return tmpl, bias
+}
+
+// vector is the inverse of template for the original space.
+// Its result is the vector of weights including the bias element
+// and the offset which is not represented in the vector.
+// The offset is non-zero if there is no bias element,
+// for example the offset due to whitening.
+func (s *svmSolver) vector(tmpl *rimg64.Multi, bias float64) ([]float64, float64) {
+	w := append([]float64(nil), tmpl.Elems...)
+	if s.bias == 0 {
+		return w, bias
+	}
+	return append(w, bias/s.bias), 0
+}
+
+// meanDot returns the inner product of an image
+// with the image which has the vector mu at every pixel.
+func meanDot(f *rimg64.Multi, mu []float64) float64 {
+	var y float64
+	for i := 0; i < f.Width; i++ {
+		for j := 0; j < f.Height; j++ {
+			for k := 0; k < f.Channels; k++ {
+				y += f.At(i, j, k) * mu[k]
+			}
+		}
+	}
+	return y
+}
+
+// whitenedSet applies a whitening filter to the feature image in each vector.
+// The image is treated as zero-mean beyond the window.
+// Elements after the image (e.g. a bias) are unchanged.
+type whitenedSet struct {
+	vecset.Set
+	Whitener *circcov.Whitener
+	Size     image.Point
+	Channels int
+}
+
+func (set *whitenedSet) At(i int) []float64 {
+	x := set.Set.At(i)
+	n := set.Size.X * set.Size.Y * set.Channels
+	f := &rimg64.Multi{Width: set.Size.X, Height: set.Size.Y, Channels: set.Channels, Elems: x[:n]}
+	return append(set.Whitener.Apply(f).Elems, x[n:]...)
+}
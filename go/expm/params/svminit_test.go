@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func TestSVMInitSet_Enumerate(t *testing.T) {
+	set := SVMInitSet{Init: []string{"zero", "lda"}, CovarLambda: []float64{1, 2}}
+	want := []SVMInit{{Init: "zero"}, {Init: "lda", CovarLambda: 1}, {Init: "lda", CovarLambda: 2}}
+	got := set.Enumerate()
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("element %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if got := (SVMInitSet{}).Enumerate(); len(got) != 1 || got[0] != (SVMInit{}) {
+		t.Errorf("empty set: got %v", got)
+	}
+}
+
+func TestInitPairs(t *testing.T) {
+	set := &SVMTrainerSet{
+		Bias:         1,
+		Lambda:       []float64{1},
+		Gamma:        []float64{0.5},
+		WindowStride: []int{8},
+		SVMInitSet:   SVMInitSet{Init: []string{"", "zero", "lda"}, CovarLambda: []float64{0.1}},
+		Term:         []SVMTermSet{{Epochs: []int{10}, RelGap: []float64{0}, AbsGap: []float64{0}}},
+	}
+	var params []Param
+	for _, trainer := range set.Enumerate() {
+		params = append(params, Param{Trainer: TrainerMessage{Type: "svm", Spec: trainer}})
+	}
+	if len(params) != 3 {
+		t.Fatalf("want 3 configurations, got %d", len(params))
+	}
+	pairs := initPairs(params)
+	if len(pairs) != 1 {
+		t.Fatalf("want 1 pair, got %d", len(pairs))
+	}
+	if pairs[0][0].Field("Trainer.Init") != "lda" || pairs[0][1].Field("Trainer.Init") != "zero" {
+		t.Errorf("got pair %s, %s", pairs[0][0].Serialize(), pairs[0][1].Serialize())
+	}
+	// The original configuration is unchanged.
+	if params[2].Field("Trainer.CovarLambda") != "0.1" {
+		t.Errorf("modified configuration: %s", params[2].Serialize())
+	}
+}
+
+// The score of a whitened vector under the whitened weights
+// must equal the score of the original vector under the template.
+func TestSVMSolver_template(t *testing.T) {
+	const channels, band = 2, 2
+	r := rand.New(rand.NewSource(1))
+	filter := toepcov.NewCovar(channels, band)
+	for u := -band; u <= band; u++ {
+		for v := -band; v <= band; v++ {
+			for p := 0; p < channels; p++ {
+				for q := 0; q < channels; q++ {
+					if filter.At(u, v, p, q) != 0 {
+						continue
+					}
+					// Toeplitz matrix is symmetric.
+					a := r.NormFloat64()
+					filter.Set(u, v, p, q, a)
+					filter.Set(-u, -v, q, p, a)
+				}
+			}
+		}
+	}
+	size := image.Pt(3, 4)
+	s := &svmSolver{
+		size:     size,
+		channels: channels,
+		bias:     10,
+		whitener: &circcov.Whitener{Mean: []float64{0.5, -1}, Filter: filter},
+	}
+	n := size.X * size.Y * channels
+	x := make(vecset.Slice, 3)
+	for i := range x {
+		x[i] = make([]float64, n+1)
+		for j := 0; j < n; j++ {
+			x[i][j] = r.NormFloat64()
+		}
+		x[i][n] = s.bias
+	}
+	w := make([]float64, n+1)
+	for j := range w {
+		w[j] = r.NormFloat64()
+	}
+	tmpl, bias := s.template(w)
+	set := s.wrap(x)
+	for i := range x {
+		want := floats.Dot(w, set.At(i))
+		im := &rimg64.Multi{Width: size.X, Height: size.Y, Channels: channels, Elems: x[i][:n]}
+		got := floats.Dot(tmpl.Elems, im.Elems) + bias
+		if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			t.Errorf("vector %d: want %.6g, got %.6g", i, want, got)
+		}
+	}
+}
+
+// Without a bias element, the offset due to whitening
+// must be returned by vector rather than discarded.
+func TestSVMSolver_vectorOffset(t *testing.T) {
+	const channels = 2
+	filter := toepcov.NewCovar(channels, 0)
+	filter.Set(0, 0, 0, 0, 2)
+	filter.Set(0, 0, 1, 1, 3)
+	size := image.Pt(2, 1)
+	s := &svmSolver{
+		size:     size,
+		channels: channels,
+		whitener: &circcov.Whitener{Mean: []float64{0.5, -1}, Filter: filter},
+	}
+	tmpl, bias := s.template([]float64{1, 2, -1, 1})
+	if bias == 0 {
+		t.Fatal("expected non-zero offset due to whitening")
+	}
+	weights, offset := s.vector(tmpl, bias)
+	if len(weights) != len(tmpl.Elems) {
+		t.Fatalf("length: want %d, got %d", len(tmpl.Elems), len(weights))
+	}
+	if offset != bias {
+		t.Errorf("offset: want %.6g, got %.6g", bias, offset)
+	}
+}
+
+// Checks that mergePerm keeps the order of the windows.
+func TestMergePerm(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	perm := []int{3, 0, 4, 1, 2}
+	order := mergePerm(4, perm, r)
+	if len(order) != 9 {
+		t.Fatalf("want 9 elements, got %d", len(order))
+	}
+	seen := make(map[int]bool)
+	var windows []int
+	for _, i := range order {
+		if seen[i] || i < 0 || i >= 9 {
+			t.Fatalf("not a permutation: %v", order)
+		}
+		seen[i] = true
+		if i >= 4 {
+			windows = append(windows, i-4)
+		}
+	}
+	for k := range perm {
+		if windows[k] != perm[k] {
+			t.Fatalf("windows: want %v, got %v", perm, windows)
+		}
+	}
+}
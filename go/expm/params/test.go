@@ -6,7 +6,6 @@ import (
 	_ "image/png"
 	"log"
 	"math"
-	"time"
 
 	"github.com/jvlmdr/go-cv/detect"
 	"github.com/jvlmdr/go-cv/feat"
@@ -28,6 +27,11 @@ type MultiScaleOptsMessage struct {
 	detect.DetFilter
 	// Replace SupprFilter with SupprMaxNum due to functional member.
 	SupprMaxNum int
+	// Image and feature caches and number of goroutines
+	// for example extraction to use on the execution host.
+	Cache data.CacheOpts
+	// Directory in which trainers persist their negatives (empty to disable).
+	PoolDir string
 }
 
 // Content combines MultiScaleOptsMessage, Param, and other parameters into MultiScaleOpts.
@@ -56,6 +60,11 @@ type TestInput struct {
 func test(x TestInput, datasetMessage DatasetMessage, optsMsg MultiScaleOptsMessage, minMatchIOU, minIgnoreCover float64, fppis []float64) (float64, error) {
 	fmt.Printf("%s\t%s\n", x.Param.Ident(), x.Param.Serialize())
 	opts := optsMsg.Content(x.Param, imsamp.Continue, x.Param.Overlap.Spec.Eval)
+	caches, err := optsMsg.Cache.New()
+	if err != nil {
+		return 0, err
+	}
+	defer caches.Close()
 	// Load template from disk.
 	trainResult := new(TrainResult)
 	if err := fileutil.LoadExt(x.TmplFile(), trainResult); err != nil {
@@ -72,6 +81,7 @@ func test(x TestInput, datasetMessage DatasetMessage, optsMsg MultiScaleOptsMess
 	if err != nil {
 		return 0, err
 	}
+	dataset = data.WithCaches(dataset, caches)
 	// Remove images from list which should not be used for testing.
 	var ims []string
 	for _, name := range x.Images {
@@ -88,15 +98,7 @@ func test(x TestInput, datasetMessage DatasetMessage, optsMsg MultiScaleOptsMess
 		var imvals []*detect.ValSet // Shadow variable in parent scope.
 		for i, name := range ims {
 			log.Printf("test image %d / %d: %s", i+1, len(ims), name)
-			// Load image.
-			file := dataset.File(name)
-			t := time.Now()
-			im, err := loadImage(file)
-			if err != nil {
-				return nil, err
-			}
-			durLoad := time.Since(t)
-			dets, durSearch, err := detect.MultiScale(im, tmpl.Scorer, tmpl.PixelShape, opts)
+			dets, durSearch, err := data.MultiScale(dataset, name, tmpl.Scorer, tmpl.PixelShape, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -106,8 +108,8 @@ func test(x TestInput, datasetMessage DatasetMessage, optsMsg MultiScaleOptsMess
 			imval := detect.Validate(dets, annot.Instances, annot.Ignore, minMatchIOU, minIgnoreCover)
 			imvals = append(imvals, imval.Set())
 			log.Printf(
-				"load %v, resize %v, feat %v, slide %v, suppr %v",
-				durLoad, durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
+				"resize %v, feat %v, slide %v, suppr %v",
+				durSearch.Resize, durSearch.Feat, durSearch.Slide, durSearch.Suppr,
 			)
 		}
 		// Also save (un-validated) detections.
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"math"
+	"math/rand"
 	"os"
 	"reflect"
 	"time"
@@ -30,6 +32,31 @@ type ToeplitzTrainer struct {
 	// Maximum bandwidth of Toeplitz matrix (-2*Band+1, ..., 2*Band-1).
 	// Zero means full bandwidth.
 	Band int
+
+	// Initial solution for iterative methods.
+	// Not part of the configuration.
+	init *rimg64.Multi
+}
+
+// SetInit takes the weights of tmpl as the initial guess.
+func (t *ToeplitzTrainer) SetInit(tmpl *detect.FeatTmpl) {
+	scorer, ok := tmpl.Scorer.(*slide.AffineScorer)
+	if !ok {
+		return
+	}
+	t.init = scorer.Tmpl
+}
+
+// SweepKey clears Lambda.
+// Only the iterative algorithms can use an initial solution.
+func (t *ToeplitzTrainer) SweepKey() (Trainer, float64, bool) {
+	if t.Method.Circ || t.Method.Algo == "chol" {
+		return nil, 0, false
+	}
+	key := *t
+	key.Lambda = 0
+	key.init = nil
+	return &key, t.Lambda, true
 }
 
 func (t *ToeplitzTrainer) Field(name string) string {
@@ -40,6 +67,10 @@ func (t *ToeplitzTrainer) Field(name string) string {
 		return t.Method.Algo
 	case "Tol":
 		return fmt.Sprint(t.Method.Tol)
+	case "Pad":
+		return fmt.Sprint(t.Method.Pad)
+	case "ZeroBoundary":
+		return fmt.Sprint(t.Method.ZeroBoundary)
 	}
 	value := reflect.ValueOf(t).Elem().FieldByName(name)
 	if !value.IsValid() {
@@ -56,6 +87,10 @@ type ToeplitzTrainerSet struct {
 	Tol    []float64
 	Sigma  []float64
 	Band   []int
+	// Embedding of circulant approximation.
+	// If empty, no padding and no zero boundary.
+	Pad          []int
+	ZeroBoundary []bool
 }
 
 type ToeplitzMethod struct {
@@ -65,17 +100,44 @@ type ToeplitzMethod struct {
 	Algo string
 	// Tolerance for when Algo is "cg" or "pcg".
 	Tol float64
+	// Number of feature pixels by which to enlarge
+	// the circulant grid when Circ is true.
+	Pad int `json:",omitempty"`
+	// Use circcov.Embed.ZeroBoundary when Circ is true.
+	ZeroBoundary bool `json:",omitempty"`
+}
+
+// Embed returns the circulant embedding for a w x h template.
+func (m ToeplitzMethod) Embed(w, h int) circcov.Embed {
+	return circcov.Embed{
+		Width:        w + m.Pad,
+		Height:       h + m.Pad,
+		ZeroBoundary: m.ZeroBoundary,
+	}
 }
 
 func (set *ToeplitzTrainerSet) Fields() []string {
-	return []string{"Lambda", "Circ", "Sigma", "Band", "Algo", "Tol"}
+	return []string{"Lambda", "Circ", "Sigma", "Band", "Algo", "Tol", "Pad", "ZeroBoundary"}
 }
 
 func (set *ToeplitzTrainerSet) Enumerate() []Trainer {
+	pads := set.Pad
+	if len(pads) == 0 {
+		pads = []int{0}
+	}
+	zeroBoundaries := set.ZeroBoundary
+	if len(zeroBoundaries) == 0 {
+		zeroBoundaries = []bool{false}
+	}
+
 	var methods []ToeplitzMethod
 	for _, circ := range set.Circ {
 		if circ {
-			methods = append(methods, ToeplitzMethod{Circ: true})
+			for _, pad := range pads {
+				for _, zero := range zeroBoundaries {
+					methods = append(methods, ToeplitzMethod{Circ: true, Pad: pad, ZeroBoundary: zero})
+				}
+			}
 			continue
 		}
 		for _, algo := range set.Algo {
@@ -104,13 +166,13 @@ func (set *ToeplitzTrainerSet) Enumerate() []Trainer {
 	return ts
 }
 
-func (t *ToeplitzTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error) {
+func (t *ToeplitzTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
 	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
 	if err != nil {
 		return nil, err
 	}
 	// Extract positive examples.
-	pos, err := data.Examples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, interp)
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, region, flip, exampleOpts.Augment, interp)
 	if err != nil {
 		return nil, err
 	}
@@ -158,11 +220,20 @@ func (t *ToeplitzTrainer) Train(posIms, negIms []string, dataset data.ImageSet,
 		weights *rimg64.Multi
 		dur     SolveDuration
 	)
+	// Use initial solution if it has the same dimension.
+	var init *rimg64.Multi
+	if t.init != nil {
+		if t.init.Size().Eq(delta.Size()) && t.init.Channels == delta.Channels {
+			init = t.init
+		} else {
+			log.Printf("ignore initial solution: size %v, want %v", t.init.Size(), delta.Size())
+		}
+	}
 	start := time.Now()
 	if t.Method.Circ {
-		weights, dur.Subst, err = solveCirculant(distr.Covar, delta)
+		weights, dur.Subst, err = solveCirculant(distr.Covar, delta, t.Method.Embed(delta.Width, delta.Height))
 	} else {
-		weights, dur.Subst, err = solveToeplitz(distr.Covar, delta, t.Method.Algo, t.Method.Tol)
+		weights, dur.Subst, err = solveToeplitz(distr.Covar, delta, t.Method.Algo, t.Method.Tol, init)
 	}
 	if err != nil {
 		return &SolveResult{Error: err.Error()}, nil
@@ -174,10 +245,13 @@ func (t *ToeplitzTrainer) Train(posIms, negIms []string, dataset data.ImageSet,
 		Scorer:     &slide.AffineScorer{Tmpl: weights},
 		PixelShape: region,
 	}
-	return &SolveResult{Tmpl: tmpl, Dur: dur}, nil
+	warm := init != nil && !t.Method.Circ && t.Method.Algo != "chol"
+	return &SolveResult{Tmpl: tmpl, Dur: dur, WarmStart: warm}, nil
 }
 
-func solveToeplitz(cov *toepcov.Covar, r *rimg64.Multi, algo string, tol float64) (*rimg64.Multi, time.Duration, error) {
+// solveToeplitz solves for w in S w = r.
+// If init is not nil, it is the initial guess for iterative methods.
+func solveToeplitz(cov *toepcov.Covar, r *rimg64.Multi, algo string, tol float64, init *rimg64.Multi) (*rimg64.Multi, time.Duration, error) {
 	switch algo {
 	case "chol":
 		// Instantiate full covariance matrix.
@@ -203,7 +277,7 @@ func solveToeplitz(cov *toepcov.Covar, r *rimg64.Multi, algo string, tol float64
 			g := muler.Mul(f)
 			return g.Elems
 		}
-		guess := make([]float64, r.Width*r.Height*r.Channels)
+		guess := initGuess(r, init)
 		start := time.Now()
 		x, err := cg.Solve(a, r.Elems, guess, tol, 0, os.Stderr)
 		if err != nil {
@@ -230,7 +304,7 @@ func solveToeplitz(cov *toepcov.Covar, r *rimg64.Multi, algo string, tol float64
 			g := invmuler.Mul(f)
 			return g.Elems
 		}
-		guess := make([]float64, r.Width*r.Height*r.Channels)
+		guess := initGuess(r, init)
 		start := time.Now()
 		x, err := pcg.Solve(a, r.Elems, cinv, guess, tol, 0, os.Stderr)
 		if err != nil {
@@ -245,9 +319,18 @@ func solveToeplitz(cov *toepcov.Covar, r *rimg64.Multi, algo string, tol float64
 	}
 }
 
-func solveCirculant(cov *toepcov.Covar, r *rimg64.Multi) (*rimg64.Multi, time.Duration, error) {
+// initGuess returns a copy of init, or zero if init is nil.
+func initGuess(r, init *rimg64.Multi) []float64 {
+	guess := make([]float64, r.Width*r.Height*r.Channels)
+	if init != nil {
+		copy(guess, init.Elems)
+	}
+	return guess
+}
+
+func solveCirculant(cov *toepcov.Covar, r *rimg64.Multi, e circcov.Embed) (*rimg64.Multi, time.Duration, error) {
 	muler := new(circcov.InvMuler)
-	if err := muler.Init(cov, r.Width, r.Height); err != nil {
+	if err := muler.InitEmbed(cov, r.Width, r.Height, e); err != nil {
 		return nil, 0, err
 	}
 	start := time.Now()
@@ -5,6 +5,7 @@ import (
 	"image"
 	"log"
 	"math"
+	"math/rand"
 	"time"
 
 	"github.com/gonum/floats"
@@ -12,19 +13,30 @@ import (
 	"github.com/jvlmdr/go-cv/feat"
 	"github.com/jvlmdr/go-cv/rimg64"
 	"github.com/jvlmdr/go-cv/slide"
-	"github.com/jvlmdr/go-fftw/fftw"
-	"github.com/jvlmdr/lin-go/clap"
-	"github.com/jvlmdr/lin-go/cmat"
+	"github.com/jvlmdr/shift-invar/go/circcov"
 	"github.com/jvlmdr/shift-invar/go/data"
 	"github.com/jvlmdr/shift-invar/go/toepcov"
 	"github.com/nfnt/resize"
 )
 
+// ToepInvTrainer computes the template using an approximate inverse
+// of the covariance, obtained on a circulant grid (see circcov.InvApprox).
+// If Crop is non-zero, the positive examples are extracted with a margin
+// which is removed from the solution.
+//
+// If Embed is true, the template is instead solved exactly
+// using the inverse of the circulant covariance on the grid
+// (see circcov.Embed).
+// The mean of the positive examples is padded with zeros to the size of the grid,
+// or with the margin of context if Crop is non-zero,
+// and the solution is cropped back to the template.
 type ToepInvTrainer struct {
 	Lambda float64
 	Res    int     // Resolution of the approximation (pixels).
 	Sigma  float64 // Size of Gaussian covar mask (pixels).
 	Crop   int     // Margin to add and then remove (pixels).
+	// Solve using the circulant embedding instead of the approximate inverse.
+	Embed bool `json:",omitempty"`
 }
 
 func (t *ToepInvTrainer) Field(name string) string {
@@ -37,6 +49,8 @@ func (t *ToepInvTrainer) Field(name string) string {
 		return fmt.Sprint(t.Sigma)
 	case "Crop":
 		return fmt.Sprint(t.Crop)
+	case "Embed":
+		return fmt.Sprint(t.Embed)
 	default:
 		return ""
 	}
@@ -48,20 +62,27 @@ type ToepInvTrainerSet struct {
 	Res    []int
 	Sigma  []float64
 	Crop   []int
+	Embed  []bool // Empty means false.
 }
 
 func (set *ToepInvTrainerSet) Fields() []string {
-	return []string{"Lambda", "Res", "Sigma", "Crop"}
+	return []string{"Lambda", "Res", "Sigma", "Crop", "Embed"}
 }
 
 func (set *ToepInvTrainerSet) Enumerate() []Trainer {
+	embeds := set.Embed
+	if len(embeds) == 0 {
+		embeds = []bool{false}
+	}
 	var ts []Trainer
 	for _, lambda := range set.Lambda {
 		for _, res := range set.Res {
 			for _, sigma := range set.Sigma {
 				for _, crop := range set.Crop {
-					t := &ToepInvTrainer{Lambda: lambda, Res: res, Sigma: sigma, Crop: crop}
-					ts = append(ts, t)
+					for _, embed := range embeds {
+						t := &ToepInvTrainer{Lambda: lambda, Res: res, Sigma: sigma, Crop: crop, Embed: embed}
+						ts = append(ts, t)
+					}
 				}
 			}
 		}
@@ -69,7 +90,7 @@ func (set *ToepInvTrainerSet) Enumerate() []Trainer {
 	return ts
 }
 
-func (t *ToepInvTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error) {
+func (t *ToepInvTrainer) Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error) {
 	posRects, err := data.PosExampleRects(posIms, dataset, searchOpts.Pad.Margin, region, exampleOpts)
 	if err != nil {
 		return nil, err
@@ -89,7 +110,7 @@ func (t *ToepInvTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 		}
 	}
 	// Extract positive examples.
-	pos, err := data.Examples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, dilatedRegion, flip, interp)
+	pos, err := data.AugmentedExamples(posIms, posRects, dataset, phi, searchOpts.Pad.Extend, dilatedRegion, flip, exampleOpts.Augment, interp)
 	if err != nil {
 		return nil, err
 	}
@@ -98,8 +119,9 @@ func (t *ToepInvTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 	}
 
 	// Compute mean of positive examples.
-	featsize, channels := phi.Size(dilatedRegion.Size), phi.Channels()
-	meanPos := rimg64.NewMulti(featsize.X, featsize.Y, channels)
+	featsize, channels := phi.Size(region.Size), phi.Channels()
+	dilatedSize := phi.Size(dilatedRegion.Size)
+	meanPos := rimg64.NewMulti(dilatedSize.X, dilatedSize.Y, channels)
 	for _, x := range pos {
 		floats.Add(meanPos.Elems, x.Elems)
 	}
@@ -145,25 +167,48 @@ func (t *ToepInvTrainer) Train(posIms, negIms []string, dataset data.ImageSet, p
 	}
 
 	startTotal := time.Now()
-
-	// Obtain approximate inverse.
 	res := t.Res / phi.Rate()
-	outBand := max(featsize.X, featsize.Y) - 1
-	prec, err := invertApprox(covar, image.Pt(res, res), outBand, t.Lambda)
-	if err != nil {
-		return nil, err
-	}
-
 	// Subtract negative mean from positive example.
 	delta := toepcov.SubMean(meanPos, distr.Mean)
-	weights := toepcov.MulFFT(prec, delta)
 
-	startSubst := time.Now()
-
-	// Set boundary to zero.
-	if pixCrop > 0 {
-		interior := image.Rect(0, 0, weights.Width, weights.Height).Inset(featCrop)
-		weights = weights.SubImage(interior)
+	var (
+		weights    *rimg64.Multi
+		startSubst time.Time
+	)
+	if t.Embed {
+		// Embed the template in a grid of the given resolution,
+		// which must be large enough to contain the context.
+		embed := circcov.Embed{
+			Width:  max(res, featsize.X+2*featCrop),
+			Height: max(res, featsize.Y+2*featCrop),
+		}
+		covar.AddLambdaI(t.Lambda)
+		var muler circcov.InvMuler
+		if err := muler.InitEmbed(covar, featsize.X, featsize.Y, embed); err != nil {
+			return &SolveResult{Error: err.Error()}, nil
+		}
+		startSubst = time.Now()
+		// The context replaces the zero padding and is removed from the solution.
+		weights = muler.MulContext(delta, featCrop)
+	} else {
+		// Obtain approximate inverse.
+		// Negative eigenvalues are set to zero before adding lambda.
+		prec, err := circcov.InvApprox(covar, circcov.InvApproxOpts{
+			Width:  res,
+			Height: res,
+			Band:   max(dilatedSize.X, dilatedSize.Y) - 1,
+			Lambda: t.Lambda,
+		})
+		if err != nil {
+			return nil, err
+		}
+		startSubst = time.Now()
+		weights = toepcov.MulFFT(prec, delta)
+		// Set boundary to zero.
+		if featCrop > 0 {
+			interior := image.Rect(0, 0, weights.Width, weights.Height).Inset(featCrop)
+			weights = weights.SubImage(interior)
+		}
 	}
 
 	// Pack weights into image in detection template.
@@ -185,85 +230,3 @@ func insetPadRect(r detect.PadRect, crop int) detect.PadRect {
 		Int:  r.Int.Sub(d),
 	}
 }
-
-// size and bandOut are in feature pixels.
-func invertApprox(cov *toepcov.Covar, size image.Point, bandOut int, lambda float64) (*toepcov.Covar, error) {
-	bandIn := cov.Bandwidth
-	if 2*bandIn+1 > size.X || 2*bandIn+1 > size.Y {
-		//	panic("input bandwidth greater than size")
-		bandIn = min(size.X, size.Y) / 2
-	}
-	if 2*bandOut+1 > size.X || 2*bandOut+1 > size.Y {
-		//	panic("output bandwidth greater than size")
-		bandOut = min(size.X, size.Y) / 2
-	}
-	num := float64(size.X * size.Y)
-	// Take Fourier transform of each channel pair.
-	a := make([][]*fftw.Array2, cov.Channels)
-	for p := range a {
-		a[p] = make([]*fftw.Array2, cov.Channels)
-		for q := range a[p] {
-			apq := fftw.NewArray2(size.X, size.Y)
-			a[p][q] = apq
-			for u := -bandIn; u <= bandIn; u++ {
-				for v := -bandIn; v <= bandIn; v++ {
-					umod, vmod := mod(u, size.X), mod(v, size.Y)
-					apq.Set(umod, vmod, apq.At(umod, vmod)+complex(cov.At(u, v, p, q), 0))
-				}
-			}
-			//	if p == q {
-			//		apq.Set(0, 0, apq.At(0, 0)+complex(lambda, 0))
-			//	}
-			fftw.FFT2To(apq, apq)
-		}
-	}
-	// Take inverse of each channels x channels block.
-	// TODO: Re-use other memory so that this is not futile?
-	auv := cmat.New(cov.Channels, cov.Channels)
-	d := cmat.New(cov.Channels, cov.Channels)
-	for u := 0; u < size.X; u++ {
-		for v := 0; v < size.Y; v++ {
-			// Over-write matrix.
-			for p := 0; p < cov.Channels; p++ {
-				for q := 0; q < cov.Channels; q++ {
-					auv.Set(p, q, a[p][q].At(u, v))
-				}
-			}
-			vecs, vals, err := clap.EigHerm(auv)
-			if err != nil {
-				return nil, err
-			}
-			for i := range vals {
-				if vals[i] < 0 {
-					//log.Printf("threshold: u %d, v %d, i %d", u, v, i)
-					vals[i] = 0
-				}
-				vals[i] += lambda
-				d.Set(i, i, complex(1/vals[i], 0))
-			}
-			inv := cmat.Mul(cmat.Mul(vecs, d), cmat.H(vecs))
-			for p := 0; p < cov.Channels; p++ {
-				for q := 0; q < cov.Channels; q++ {
-					a[p][q].Set(u, v, inv.At(p, q))
-				}
-			}
-		}
-	}
-	// Take inverse FFT of each channel pair.
-	prec := toepcov.NewCovar(cov.Channels, bandOut)
-	for p := range a {
-		for q := range a[p] {
-			apq := a[p][q]
-			fftw.IFFT2To(apq, apq)
-			for u := -bandOut; u <= bandOut; u++ {
-				for v := -bandOut; v <= bandOut; v++ {
-					umod, vmod := mod(u, size.X), mod(v, size.Y)
-					x := apq.At(umod, vmod)
-					// TODO: Check real.
-					prec.Set(u, v, p, q, real(x)/num)
-				}
-			}
-		}
-	}
-	return prec, nil
-}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"log"
+	"math/rand"
 	"path"
 	"time"
 
@@ -23,6 +24,10 @@ func init() {
 type TrainInput struct {
 	DetectorKey
 	Images []string
+	// Template file of a neighbouring configuration
+	// from which to initialize training.
+	// Empty for no initialization.
+	InitTmplFile string
 }
 
 func train(u TrainInput, datasetMessage DatasetMessage, covarDir string, addFlip bool, interp resize.InterpolationFunction, searchOptsMsg MultiScaleOptsMessage) (string, error) {
@@ -32,6 +37,7 @@ func train(u TrainInput, datasetMessage DatasetMessage, covarDir string, addFlip
 		AspectReject: u.Param.AspectReject,
 		FitMode:      u.Param.ResizeFor,
 		MaxScale:     u.Param.MaxTrainScale,
+		Augment:      u.Param.Augment,
 	}
 	// Determine dimensions of template.
 	phi := u.Feat.Transform.Transform()
@@ -41,12 +47,19 @@ func train(u TrainInput, datasetMessage DatasetMessage, covarDir string, addFlip
 	// Supply training algorithm with search options.
 	// TODO: phi will be decoded twice. Is this an issue?
 	searchOpts := searchOptsMsg.Content(u.Param, imsamp.Continue, u.Overlap.Spec.Eval)
+	caches, err := searchOptsMsg.Cache.New()
+	if err != nil {
+		return "", err
+	}
+	defer caches.Close()
 
 	// Re-load dataset on execution host.
 	dataset, err := data.Load(datasetMessage.Name, datasetMessage.Spec)
 	if err != nil {
 		return "", err
 	}
+	// Load images and compute pyramids using the caches of this task.
+	dataset = data.WithCaches(dataset, caches)
 	// Split images into positive and negative.
 	var posIms, negIms []string
 	for _, im := range u.Images {
@@ -58,12 +71,30 @@ func train(u TrainInput, datasetMessage DatasetMessage, covarDir string, addFlip
 	}
 	// Take subset of negative images.
 	numNegIms := int(u.NegFrac * float64(len(negIms)))
-	negIms = selectSubset(negIms, randSubset(len(negIms), numNegIms))
+	// All random choices in training are determined by the seed.
+	r := rand.New(rand.NewSource(u.Param.Seed))
+	negIms = selectSubset(negIms, randSubset(len(negIms), numNegIms, r))
 	log.Println("number of negative images:", len(negIms))
 
+	// Initialize from neighbouring configuration if possible.
+	if starter, ok := u.Trainer.Spec.(WarmStarter); ok && u.InitTmplFile != "" {
+		init, err := loadInitTmpl(u.InitTmplFile)
+		if err != nil {
+			return "", err
+		}
+		if init != nil {
+			log.Println("initialize from template:", u.InitTmplFile)
+			starter.SetInit(init.Tmpl)
+		}
+	}
+
+	if keeper, ok := u.Trainer.Spec.(PoolKeeper); ok && searchOptsMsg.PoolDir != "" {
+		keeper.SetPoolFiles(path.Join(searchOptsMsg.PoolDir, u.PoolFile()), path.Join(searchOptsMsg.PoolDir, u.MiningFile()))
+	}
+
 	statsFile := path.Join(covarDir, u.Feat.StatsFile)
 	start := time.Now()
-	solveResult, err := u.Trainer.Spec.Train(posIms, negIms, dataset, phi, statsFile, region, exampleOpts, addFlip, interp, searchOpts)
+	solveResult, err := u.Trainer.Spec.Train(posIms, negIms, dataset, phi, statsFile, region, exampleOpts, addFlip, interp, searchOpts, r)
 	if err != nil {
 		return "", err
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"math/rand"
 	"time"
 
 	"github.com/jvlmdr/go-cv/detect"
@@ -13,7 +14,7 @@ import (
 // Trainer takes a training set which was extracted
 // using some configuration for training examples.
 type Trainer interface {
-	Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts) (*SolveResult, error)
+	Train(posIms, negIms []string, dataset data.ImageSet, phi feat.Image, statsFile string, region detect.PadRect, exampleOpts data.ExampleOpts, flip bool, interp resize.InterpolationFunction, searchOpts detect.MultiScaleOpts, r *rand.Rand) (*SolveResult, error)
 	Field(string) string
 }
 
@@ -27,15 +28,28 @@ type TrainReport struct {
 	TotalDur time.Duration
 	SolveDur SolveDuration
 	Error    string
+	// Was the solver initialized from another template?
+	WarmStart bool
+	// Statistics of each round of hard negative mining.
+	Mining []MiningStats
+	// Number of epochs taken by the SVM solver (summed over rounds).
+	Epochs int `json:",omitempty"`
+	// Did hard negative mining resume from a saved pool?
+	// If so, the durations and epochs exclude the rounds before resuming.
+	Resumed bool `json:",omitempty"`
 }
 
 // SolveResult is the result of trying to solve the training problem.
 // These are bundled together to avoid having multiple returns to Trainer.Train(),
 // especially since some Trainers may not report a duration.
 type SolveResult struct {
-	Tmpl  *detect.FeatTmpl
-	Dur   SolveDuration
-	Error string
+	Tmpl      *detect.FeatTmpl
+	Dur       SolveDuration
+	Error     string
+	WarmStart bool
+	Mining    []MiningStats
+	Epochs    int
+	Resumed   bool
 }
 
 // Fail returns false iff Error is empty.
@@ -50,8 +64,15 @@ func NewTrainResult(solveResult *SolveResult, total time.Duration) *TrainResult
 		}
 	}
 	return &TrainResult{
-		Tmpl:   solveResult.Tmpl,
-		Report: &TrainReport{TotalDur: total, SolveDur: solveResult.Dur},
+		Tmpl: solveResult.Tmpl,
+		Report: &TrainReport{
+			TotalDur:  total,
+			SolveDur:  solveResult.Dur,
+			WarmStart: solveResult.WarmStart,
+			Mining:    solveResult.Mining,
+			Epochs:    solveResult.Epochs,
+			Resumed:   solveResult.Resumed,
+		},
 	}
 }
 
@@ -59,6 +80,27 @@ type SolveDuration struct {
 	Total, Subst time.Duration
 }
 
+// WarmStarter is a Trainer which can take an initial solution
+// from the template of a neighbouring configuration.
+type WarmStarter interface {
+	// SetInit provides a template from which to start.
+	// The trainer may ignore it, for example if its size differs.
+	SetInit(tmpl *detect.FeatTmpl)
+	// SweepKey returns a copy of the trainer with its swept parameter cleared,
+	// and the value of that parameter.
+	// Configurations with equal keys can initialize one another.
+	// Returns false if the trainer would not make use of an initial solution.
+	SweepKey() (key Trainer, value float64, ok bool)
+}
+
+// PoolKeeper is a Trainer which can persist its pool of negatives
+// so that an interrupted job can resume mining.
+type PoolKeeper interface {
+	// SetPoolFiles specifies where to store the pool
+	// and the state of mining.
+	SetPoolFiles(pool, state string)
+}
+
 // TrainerSet describes a set of Trainers of the same type.
 type TrainerSet interface {
 	// Trainers can use the search options.
@@ -73,6 +115,10 @@ func init() {
 		func() (Trainer, error) { return new(SVMTrainer), nil },
 		func() (TrainerSet, error) { return new(SVMTrainerSet), nil },
 	)
+	DefaultTrainers.Register("linear",
+		func() (Trainer, error) { return new(LinearTrainer), nil },
+		func() (TrainerSet, error) { return new(LinearTrainerSet), nil },
+	)
 	DefaultTrainers.Register("set-svm",
 		func() (Trainer, error) { return new(SetSVMTrainer), nil },
 		func() (TrainerSet, error) { return new(SetSVMTrainerSet), nil },
@@ -89,6 +135,14 @@ func init() {
 		func() (Trainer, error) { return new(ToepInvTrainer), nil },
 		func() (TrainerSet, error) { return new(ToepInvTrainerSet), nil },
 	)
+	DefaultTrainers.Register("exemplar",
+		func() (Trainer, error) { return new(ExemplarTrainer), nil },
+		func() (TrainerSet, error) { return new(ExemplarTrainerSet), nil },
+	)
+	DefaultTrainers.Register("low-rank",
+		func() (Trainer, error) { return new(LowRankTrainer), nil },
+		func() (TrainerSet, error) { return new(LowRankTrainerSet), nil },
+	)
 }
 
 type trainerType struct {
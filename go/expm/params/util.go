@@ -3,7 +3,6 @@ package main
 import (
 	"image"
 	"math/rand"
-	"os"
 	"sort"
 )
 
@@ -38,11 +37,11 @@ func area(r image.Rectangle) int {
 
 // Split divides x randomly into n groups.
 // It is possible that one of the groups is empty.
-func split(x []string, n int) [][]string {
+func split(x []string, n int, r *rand.Rand) [][]string {
 	y := make([][]string, n)
 	for _, xi := range x {
-		r := rand.Intn(n)
-		y[r] = append(y[r], xi)
+		k := r.Intn(n)
+		y[k] = append(y[k], xi)
 	}
 	return y
 }
@@ -60,8 +59,8 @@ func mergeExcept(x [][]string, j int) []string {
 
 // Subset returns a random length-m subset of 0..n-1.
 // Elements are ordered.
-func randSubset(n, m int) []int {
-	p := rand.Perm(n)[:m]
+func randSubset(n, m int, r *rand.Rand) []int {
+	p := r.Perm(n)[:m]
 	sort.Ints(p)
 	return p
 }
@@ -88,29 +87,3 @@ func union(x ...[]string) []string {
 	sort.Strings(s)
 	return s
 }
-
-func loadImage(fname string) (image.Image, error) {
-	file, err := os.Open(fname)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	im, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-	return im, nil
-}
-
-func loadImageSize(name string) (image.Point, error) {
-	file, err := os.Open(name)
-	if err != nil {
-		return image.ZP, err
-	}
-	defer file.Close()
-	cfg, _, err := image.DecodeConfig(file)
-	if err != nil {
-		return image.ZP, err
-	}
-	return image.Pt(cfg.Width, cfg.Height), nil
-}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/jvlmdr/go-file/fileutil"
+)
+
+// Sweep orders in which to train configurations.
+const (
+	// Train all configurations at once from scratch.
+	DefaultOrder = "default"
+	// Train configurations in waves such that each can be
+	// initialized from a neighbouring configuration in an earlier wave.
+	WarmStartOrder = "warm-start"
+)
+
+// warmStartWaves partitions the inputs into a sequence of waves.
+// Each input in a wave may have an InitTmplFile set
+// to the template of an input in an earlier wave.
+//
+// Inputs which are identical except for the swept parameter
+// and the training set form a grid.
+// The swept parameter is visited in decreasing order
+// (e.g. strongest regularization first) and the training sets
+// in the order in which they first appear in the list.
+// Element (set i, value j) is initialized from (i, j-1) if j > 0
+// and otherwise from (i-1, 0).
+// This gives a tree whose depth is the number of sets
+// plus the number of values minus one.
+// Inputs whose trainer is not a WarmStarter are put in the first wave.
+func warmStartWaves(inputs []TrainInput) [][]TrainInput {
+	type node struct {
+		input TrainInput
+		value float64
+		set   int
+	}
+	var (
+		waves  [][]TrainInput
+		groups = make(map[string][]node)
+		keys   []string
+		sets   = make(map[Set]int)
+	)
+	add := func(level int, x TrainInput) {
+		for len(waves) <= level {
+			waves = append(waves, nil)
+		}
+		waves[level] = append(waves[level], x)
+	}
+
+	for _, x := range inputs {
+		if _, ok := sets[x.TrainSet]; !ok {
+			sets[x.TrainSet] = len(sets)
+		}
+		starter, ok := x.Trainer.Spec.(WarmStarter)
+		if !ok {
+			add(0, x)
+			continue
+		}
+		trainer, value, ok := starter.SweepKey()
+		if !ok {
+			add(0, x)
+			continue
+		}
+		// Identify the group by the parameters without the swept value.
+		p := x.Param
+		p.Trainer = TrainerMessage{Type: p.Trainer.Type, Spec: trainer}
+		key := p.Serialize()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], node{x, value, sets[x.TrainSet]})
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		// Find distinct values in decreasing order.
+		var values []float64
+		seen := make(map[float64]bool)
+		for _, n := range group {
+			if !seen[n.value] {
+				seen[n.value] = true
+				values = append(values, n.value)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+		index := make(map[float64]int)
+		for j, v := range values {
+			index[v] = j
+		}
+		// Index the grid by (set, value).
+		grid := make(map[[2]int]TrainInput)
+		hasSet := make(map[int]bool)
+		var setList []int
+		for _, n := range group {
+			if !hasSet[n.set] {
+				hasSet[n.set] = true
+				setList = append(setList, n.set)
+			}
+			grid[[2]int{n.set, index[n.value]}] = n.input
+		}
+		sort.Ints(setList)
+
+		// Assign parent and level to every element.
+		for a, i := range setList {
+			for j := range values {
+				x, ok := grid[[2]int{i, j}]
+				if !ok {
+					continue
+				}
+				var (
+					parent TrainInput
+					found  bool
+				)
+				level := a + j
+				if j > 0 {
+					parent, found = grid[[2]int{i, j - 1}]
+				} else if a > 0 {
+					parent, found = grid[[2]int{setList[a-1], 0}]
+				}
+				if found {
+					x.InitTmplFile = parent.TmplFile()
+				}
+				add(level, x)
+			}
+		}
+	}
+	return waves
+}
+
+// trainWaves trains each wave in sequence.
+func trainWaves(waves [][]TrainInput, trainFunc func([]TrainInput) error) error {
+	for i, wave := range waves {
+		log.Printf("train wave %d / %d: %d detectors", i+1, len(waves), len(wave))
+		if err := trainFunc(wave); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadInitTmpl loads the template from a previous training result.
+// Returns nil if the file does not exist or training failed.
+func loadInitTmpl(fname string) (*TrainResult, error) {
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var result TrainResult
+	if err := fileutil.LoadExt(fname, &result); err != nil {
+		return nil, fmt.Errorf(`load initial template "%s": %v`, fname, err)
+	}
+	if result.Report.Error != "" || result.Tmpl == nil {
+		return nil, nil
+	}
+	return &result, nil
+}
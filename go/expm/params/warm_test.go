@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWarmStartWaves(t *testing.T) {
+	lambdas := []float64{1e-3, 1e-1, 1e-2}
+	var inputs []TrainInput
+	for i := 0; i < 2; i++ {
+		set := Set{Dataset: "train", Subset: fmt.Sprintf("excl-fold-%d", i)}
+		for _, lambda := range lambdas {
+			trainer := &ToeplitzTrainer{Lambda: lambda, Method: ToeplitzMethod{Algo: "cg", Tol: 1e-6}}
+			p := Param{Trainer: TrainerMessage{Type: "toeplitz", Spec: trainer}}
+			inputs = append(inputs, TrainInput{DetectorKey: DetectorKey{Param: p, TrainSet: set}})
+		}
+		// Direct method cannot be warm-started.
+		trainer := &ToeplitzTrainer{Lambda: 1, Method: ToeplitzMethod{Algo: "chol"}}
+		p := Param{Trainer: TrainerMessage{Type: "toeplitz", Spec: trainer}}
+		inputs = append(inputs, TrainInput{DetectorKey: DetectorKey{Param: p, TrainSet: set}})
+	}
+
+	waves := warmStartWaves(inputs)
+	// Two sets and three values give depth 2+3-1.
+	if len(waves) != 4 {
+		t.Fatalf("number of waves: want %d, got %d", 4, len(waves))
+	}
+	var total int
+	trained := make(map[string]int)
+	for i, wave := range waves {
+		for _, x := range wave {
+			total++
+			trained[x.TmplFile()] = i
+			if x.InitTmplFile == "" {
+				continue
+			}
+			level, ok := trained[x.InitTmplFile]
+			if !ok || level >= i {
+				t.Errorf("wave %d: initial template not trained in earlier wave", i)
+			}
+			if x.Trainer.Spec.(*ToeplitzTrainer).Method.Algo == "chol" {
+				t.Errorf("wave %d: direct method has initial template", i)
+			}
+		}
+	}
+	if total != len(inputs) {
+		t.Errorf("number of inputs: want %d, got %d", len(inputs), total)
+	}
+	// The first wave should contain the largest lambda of the first set
+	// and both direct methods.
+	if len(waves[0]) != 3 {
+		t.Errorf("size of first wave: want %d, got %d", 3, len(waves[0]))
+	}
+}
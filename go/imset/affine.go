@@ -0,0 +1,75 @@
+package imset
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// ChannelAffine presents the images of a set after applying
+// an affine transform to the channel vector of every pixel.
+type ChannelAffine struct {
+	Set
+	Transform *vecset.Affine
+}
+
+func (set *ChannelAffine) ImageChannels() int {
+	return set.Transform.Dim(set.Set.ImageChannels())
+}
+
+func (set *ChannelAffine) At(i int) *rimg64.Multi {
+	return ApplyChannels(set.Transform, set.Set.At(i))
+}
+
+// ApplyChannels returns a new image whose channel vector at each pixel
+// is the transform of that of x.
+// It can be used to compute statistics (e.g. toepcov.Stats)
+// in the transformed channel space.
+func ApplyChannels(t *vecset.Affine, x *rimg64.Multi) *rimg64.Multi {
+	y := rimg64.NewMulti(x.Width, x.Height, t.Dim(x.Channels))
+	for u := 0; u < x.Width; u++ {
+		for v := 0; v < x.Height; v++ {
+			z := t.Apply(pixel(x, u, v))
+			for p, zp := range z {
+				y.Set(u, v, p, zp)
+			}
+		}
+	}
+	return y
+}
+
+func pixel(x *rimg64.Multi, u, v int) []float64 {
+	z := make([]float64, x.Channels)
+	for p := range z {
+		z[p] = x.At(u, v, p)
+	}
+	return z
+}
+
+// AddPixels accumulates the channel vector of every pixel in x.
+func AddPixels(m *vecset.Moments, x *rimg64.Multi) {
+	for u := 0; u < x.Width; u++ {
+		for v := 0; v < x.Height; v++ {
+			m.Add(pixel(x, u, v))
+		}
+	}
+}
+
+// FitChannelPCA streams over the pixels of every image in a set
+// and returns the projection onto the k principal components
+// of the channel vectors, and the variance along each.
+func FitChannelPCA(set Set, k int) (*vecset.Affine, []float64, error) {
+	if set.Len() == 0 {
+		return nil, nil, fmt.Errorf("empty set")
+	}
+	if size := set.ImageSize(); size.Eq(image.ZP) {
+		return nil, nil, fmt.Errorf("empty images")
+	}
+	m := vecset.NewMoments(set.ImageChannels())
+	for i := 0; i < set.Len(); i++ {
+		AddPixels(m, set.At(i))
+	}
+	return m.PCA(k)
+}
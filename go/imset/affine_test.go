@@ -0,0 +1,60 @@
+package imset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func TestChannelAffine(t *testing.T) {
+	x := rimg64.NewMulti(2, 3, 2)
+	for i := range x.Elems {
+		x.Elems[i] = float64(i)
+	}
+	tr := &vecset.Affine{Mean: []float64{1, 0}, Proj: [][]float64{{1, 1}}}
+	set := &ChannelAffine{Set: Slice{x}, Transform: tr}
+	if set.ImageChannels() != 1 || !set.ImageSize().Eq(x.Size()) {
+		t.Fatalf("want 2x3x1 images, got %vx%d", set.ImageSize(), set.ImageChannels())
+	}
+	y := set.At(0)
+	for u := 0; u < x.Width; u++ {
+		for v := 0; v < x.Height; v++ {
+			want := x.At(u, v, 0) - 1 + x.At(u, v, 1)
+			if got := y.At(u, v, 0); got != want {
+				t.Errorf("at %d, %d: want %g, got %g", u, v, want, got)
+			}
+		}
+	}
+}
+
+func TestFitChannelPCA(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var ims []*rimg64.Multi
+	for i := 0; i < 5; i++ {
+		x := rimg64.NewMulti(4, 6, 3)
+		for u := 0; u < x.Width; u++ {
+			for v := 0; v < x.Height; v++ {
+				a := r.NormFloat64()
+				// Second channel is twice the first, third is constant.
+				x.Set(u, v, 0, a)
+				x.Set(u, v, 1, 2*a)
+				x.Set(u, v, 2, 1)
+			}
+		}
+		ims = append(ims, x)
+	}
+	tr, vars, err := FitChannelPCA(Slice(ims), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := tr.Proj[0]
+	if d := math.Abs(u[0]+2*u[1]) / math.Sqrt(5); math.Abs(d-1) > 1e-6 || math.Abs(u[2]) > 1e-6 {
+		t.Errorf("component: got %v", u)
+	}
+	if vars[0] <= 0 {
+		t.Errorf("variance: got %g", vars[0])
+	}
+}
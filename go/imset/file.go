@@ -0,0 +1,82 @@
+package imset
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// File is a Set whose images are the rows of a memory-mapped file.
+// The file can be created by SaveFile or vecset.CreateFile.
+type File struct {
+	Rows *vecset.File
+}
+
+// OpenFile maps a file of images.
+// The file must be closed to release the mapping.
+func OpenFile(fname string) (*File, error) {
+	rows, err := vecset.OpenFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &File{rows}, nil
+}
+
+// Close releases the mapping.
+func (f *File) Close() error {
+	return f.Rows.Close()
+}
+
+func (f *File) Len() int {
+	return f.Rows.Len()
+}
+
+func (f *File) ImageSize() image.Point {
+	shape := f.Rows.Shape()
+	return image.Pt(shape.Width, shape.Height)
+}
+
+func (f *File) ImageChannels() int {
+	return f.Rows.Shape().Channels
+}
+
+// At returns a copy of the i-th image.
+func (f *File) At(i int) *rimg64.Multi {
+	shape := f.Rows.Shape()
+	return &rimg64.Multi{
+		Width:    shape.Width,
+		Height:   shape.Height,
+		Channels: shape.Channels,
+		Elems:    f.Rows.At(i),
+	}
+}
+
+// CreateFile creates a file to which images of the given size can be appended.
+func CreateFile(fname string, size image.Point, channels int, elem vecset.Elem) (*vecset.FileWriter, error) {
+	return vecset.CreateFile(fname, vecset.Shape{Width: size.X, Height: size.Y, Channels: channels}, elem)
+}
+
+// WriteImage appends an image to a file of images.
+func WriteImage(w *vecset.FileWriter, x *rimg64.Multi) error {
+	return w.Write(x.Elems)
+}
+
+// SaveFile writes a list of images of the same size to a new file.
+// The list must not be empty.
+func SaveFile(fname string, ims []*rimg64.Multi, elem vecset.Elem) error {
+	set := Slice(ims)
+	size, channels := set.ImageSize(), set.ImageChannels()
+	w, err := CreateFile(fname, size, channels, elem)
+	if err != nil {
+		return err
+	}
+	for i, x := range ims {
+		if err := WriteImage(w, x); err != nil {
+			w.Close()
+			return fmt.Errorf("image %d: %v", i, err)
+		}
+	}
+	return w.Close()
+}
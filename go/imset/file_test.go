@@ -0,0 +1,63 @@
+package imset
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := rand.New(rand.NewSource(1))
+	var ims []*rimg64.Multi
+	for i := 0; i < 4; i++ {
+		x := rimg64.NewMulti(3, 5, 2)
+		for j := range x.Elems {
+			x.Elems[j] = r.NormFloat64()
+		}
+		ims = append(ims, x)
+	}
+	want := Slice(ims)
+
+	fname := path.Join(dir, "ims.bin")
+	if err := SaveFile(fname, ims, vecset.Float64); err != nil {
+		t.Fatal(err)
+	}
+	f, err := OpenFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Len() != want.Len() {
+		t.Fatalf("len: want %d, got %d", want.Len(), f.Len())
+	}
+	if !f.ImageSize().Eq(want.ImageSize()) || f.ImageChannels() != want.ImageChannels() {
+		t.Fatalf("size: want %v x %d, got %v x %d", want.ImageSize(), want.ImageChannels(), f.ImageSize(), f.ImageChannels())
+	}
+	for i := 0; i < want.Len(); i++ {
+		if !reflect.DeepEqual(want.At(i), f.At(i)) {
+			t.Errorf("image %d differs", i)
+		}
+	}
+
+	// Vectors are the same as those of the slice.
+	a, b := &VecSet{Set: want, Bias: 1}, &VecSet{Set: f, Bias: 1}
+	if a.Dim() != b.Dim() {
+		t.Fatalf("dim: want %d, got %d", a.Dim(), b.Dim())
+	}
+	for i := 0; i < a.Len(); i++ {
+		if !reflect.DeepEqual(a.At(i), b.At(i)) {
+			t.Errorf("vector %d differs", i)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Package lowrankcov provides covariance matrices which are
+the sum of a stationary (Toeplitz) part and a low-rank correction.
+
+The stationary model cannot represent position-dependent structure
+such as that near the boundary of a window.
+The correction is estimated from the residual between the Toeplitz model
+and an exact covariance, either from package exactcov
+or from a set of training windows.
+*/
+package lowrankcov
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/exactcov"
+	"github.com/jvlmdr/shift-invar/go/imcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Eigenvalues of the residual are discarded if they are
+// less than eigTol times the largest absolute eigenvalue.
+const eigTol = 1e-9
+
+// Covar describes the covariance of w x h x c images
+//
+//	S = T + sum_i Weights[i] Basis[i] Basis[i]'
+//
+// where T is the Toeplitz matrix of a stationary covariance.
+// Weights are positive, therefore S is positive definite if T is.
+type Covar struct {
+	Toeplitz      *toepcov.Covar
+	Width, Height int
+	// Each element of Basis is Width x Height x Toeplitz.Channels.
+	Basis   []*rimg64.Multi
+	Weights []float64
+}
+
+// Rank gives the rank of the correction.
+func (s *Covar) Rank() int {
+	return len(s.Basis)
+}
+
+// Channels gives the number of channels.
+func (s *Covar) Channels() int {
+	return s.Toeplitz.Channels
+}
+
+// Matrix instantiates the full whc x whc covariance matrix.
+// The matrix is indexed in the same order as toepcov.Covar.Matrix.
+func (s *Covar) Matrix() *mat.Mat {
+	a := s.Toeplitz.Matrix(s.Width, s.Height)
+	n, _ := a.Dims()
+	for k, u := range s.Basis {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a.Set(i, j, a.At(i, j)+s.Weights[k]*u.Elems[i]*u.Elems[j])
+			}
+		}
+	}
+	return a
+}
+
+// FromResidual approximates an exact covariance by the Toeplitz covariance
+// plus the rank-k approximation of the residual.
+// The components with the largest positive eigenvalues are kept.
+// Components with negative eigenvalues are discarded
+// since they could make the sum indefinite,
+// as are those with eigenvalues which are positive only due to round-off,
+// therefore the rank may be less than k.
+func FromResidual(exact *imcov.Covar, toep *toepcov.Covar, rank int) (*Covar, error) {
+	if exact.Channels != toep.Channels {
+		return nil, fmt.Errorf("different number of channels: exact %d, toeplitz %d", exact.Channels, toep.Channels)
+	}
+	w, h, c := exact.Width, exact.Height, exact.Channels
+	r := exact.Matrix()
+	t := toep.Matrix(w, h)
+	n, _ := r.Dims()
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			r.Set(i, j, r.At(i, j)-t.At(i, j))
+		}
+	}
+	vecs, vals, err := lapack.EigSymm(r)
+	if err != nil {
+		return nil, err
+	}
+	// Order components by decreasing eigenvalue.
+	order := make([]int, len(vals))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Sort(byVal{order, vals})
+	if rank > len(order) {
+		rank = len(order)
+	}
+
+	// Eigenvalues which are not significantly positive would give
+	// large elements of the inverse in the Woodbury solve.
+	var maxAbs float64
+	for _, x := range vals {
+		maxAbs = math.Max(maxAbs, math.Abs(x))
+	}
+	tol := eigTol * maxAbs
+
+	s := &Covar{Toeplitz: toep, Width: w, Height: h}
+	for _, k := range order[:rank] {
+		if vals[k] <= tol {
+			break
+		}
+		u := rimg64.NewMulti(w, h, c)
+		for i := 0; i < n; i++ {
+			u.Elems[i] = vecs.At(i, k)
+		}
+		s.Basis = append(s.Basis, u)
+		s.Weights = append(s.Weights, vals[k])
+	}
+	return s, nil
+}
+
+// FromExamples computes the empirical covariance of a set of windows
+// and calls FromResidual.
+// The second moment is centered using the stationary mean pixel,
+// as for the Toeplitz covariance, not the sample mean of the windows,
+// such that the residual measures only the departure from stationarity.
+func FromExamples(examples []*rimg64.Multi, mean []float64, toep *toepcov.Covar, rank int) (*Covar, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("empty set of examples")
+	}
+	w, h, c := examples[0].Width, examples[0].Height, examples[0].Channels
+	if len(mean) != c {
+		return nil, fmt.Errorf("different number of channels: mean %d, examples %d", len(mean), c)
+	}
+	exact := imcov.NewCovar(w, h, c)
+	for _, x := range examples {
+		if x.Width != w || x.Height != h || x.Channels != c {
+			return nil, fmt.Errorf("examples have different sizes: %dx%dx%d, %dx%dx%d", w, h, c, x.Width, x.Height, x.Channels)
+		}
+		for u := 0; u < w; u++ {
+			for v := 0; v < h; v++ {
+				for p := 0; p < c; p++ {
+					for i := 0; i < w; i++ {
+						for j := 0; j < h; j++ {
+							for q := 0; q < c; q++ {
+								exact.AddAt(u, v, p, i, j, q, x.At(u, v, p)*x.At(i, j, q))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	alpha := 1 / float64(len(examples))
+	exact = exact.Scale(alpha).Center(meanImage(mean, w, h))
+	return FromResidual(exact, toep, rank)
+}
+
+// FromTotal obtains the exact covariance of a w x h window
+// from statistics computed by package exactcov and calls FromResidual.
+// Like FromExamples, the covariance is centered using the stationary mean pixel.
+func FromTotal(total *exactcov.Total, mean []float64, toep *toepcov.Covar, w, h, rank int) (*Covar, error) {
+	if w > total.Covar.Width || h > total.Covar.Height {
+		return nil, fmt.Errorf("window larger than statistics: window %dx%d, statistics %dx%d", w, h, total.Covar.Width, total.Covar.Height)
+	}
+	if len(mean) != total.Covar.Channels {
+		return nil, fmt.Errorf("different number of channels: mean %d, statistics %d", len(mean), total.Covar.Channels)
+	}
+	_, exact := total.Subset(w, h, total.Covar.Bandwidth).Normalize()
+	exact = exact.Center(meanImage(mean, w, h))
+	return FromResidual(exact, toep, rank)
+}
+
+// meanImage replicates a mean pixel across a w x h image.
+func meanImage(mean []float64, w, h int) *rimg64.Multi {
+	mu := rimg64.NewMulti(w, h, len(mean))
+	for u := 0; u < w; u++ {
+		for v := 0; v < h; v++ {
+			for p, x := range mean {
+				mu.Set(u, v, p, x)
+			}
+		}
+	}
+	return mu
+}
+
+type byVal struct {
+	order []int
+	vals  []float64
+}
+
+func (s byVal) Len() int      { return len(s.order) }
+func (s byVal) Swap(i, j int) { s.order[i], s.order[j] = s.order[j], s.order[i] }
+func (s byVal) Less(i, j int) bool {
+	return s.vals[s.order[i]] > s.vals[s.order[j]]
+}
@@ -0,0 +1,104 @@
+package lowrankcov
+
+import (
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/imcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Checks that the full-rank residual reproduces the exact covariance.
+func TestFromResidual(t *testing.T) {
+	const (
+		width     = 4
+		height    = 3
+		channels  = 2
+		bandwidth = 2
+	)
+
+	want := randLowRank(width, height, channels, bandwidth, []float64{2, 0.5})
+	exact := exactFromMatrix(t, want)
+	got, err := FromResidual(exact, want.Toeplitz, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rank() != 2 {
+		t.Fatalf("rank: want %d, got %d", 2, got.Rank())
+	}
+	if eq, msg := matsEq(want.Matrix(), got.Matrix()); !eq {
+		t.Error(msg)
+	}
+}
+
+// Checks that negative components of an indefinite residual are discarded
+// and that the result is positive definite.
+func TestFromResidual_indefinite(t *testing.T) {
+	const (
+		width     = 4
+		height    = 3
+		channels  = 2
+		bandwidth = 2
+	)
+
+	s := randLowRank(width, height, channels, bandwidth, []float64{0.1, -100})
+	if _, err := lapack.Chol(s.Matrix()); err == nil {
+		t.Fatal("exact covariance is positive definite")
+	}
+	exact := exactFromMatrix(t, s)
+	got, err := FromResidual(exact, s.Toeplitz, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rank() != 1 {
+		t.Fatalf("rank: want %d, got %d", 1, got.Rank())
+	}
+	if got.Weights[0] <= 0 {
+		t.Errorf("want positive weight, got %.6g", got.Weights[0])
+	}
+	if _, err := lapack.Chol(got.Matrix()); err != nil {
+		t.Errorf("not positive definite: %v", err)
+	}
+}
+
+// Checks that examples are centered using the given mean pixel
+// rather than their sample mean.
+func TestFromExamples_mean(t *testing.T) {
+	const (
+		width    = 4
+		height   = 3
+		channels = 2
+	)
+
+	mean := []float64{1, -2}
+	mu := meanImage(mean, width, height)
+	// The sample mean is twice the given mean.
+	a := mu.Scale(2)
+	d := randImage(width, height, channels)
+	xs := []*rimg64.Multi{a.Plus(d), a.Plus(d.Scale(-1))}
+	got, err := FromExamples(xs, mean, toepcov.NewCovar(channels, 0), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second moment is a a' + d d', from which mu mu' is subtracted.
+	n := width * height * channels
+	want := mat.New(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			want.Set(i, j, 3*mu.Elems[i]*mu.Elems[j]+d.Elems[i]*d.Elems[j])
+		}
+	}
+	if eq, msg := matsEq(want, got.Matrix()); !eq {
+		t.Error(msg)
+	}
+}
+
+func exactFromMatrix(t *testing.T, s *Covar) *imcov.Covar {
+	exact, err := imcov.NewCovarFromMatrix(s.Matrix(), s.Width, s.Height, s.Channels())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exact
+}
@@ -0,0 +1,157 @@
+package lowrankcov
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// SolveOpts specifies how to solve systems in the Toeplitz part.
+type SolveOpts struct {
+	// Can be "chol", "cg" or "pcg".
+	// The preconditioner for "pcg" is the circulant approximation.
+	Algo string
+	// Tolerance and maximum iterations for "cg" and "pcg".
+	// Zero iterations means no limit.
+	Tol  float64
+	Iter int
+}
+
+// InvMuler solves systems with a low-rank-plus-Toeplitz covariance matrix
+// using the Woodbury identity
+//
+//	(T + U D U')^-1 = T^-1 - T^-1 U (D^-1 + U' T^-1 U)^-1 U' T^-1.
+//
+// This requires k+1 solves with the Toeplitz part to initialize
+// and one per product.
+type InvMuler struct {
+	Width, Height, Channels int
+	Basis                   []*rimg64.Multi
+	// Solutions of T Z[i] = Basis[i].
+	Z []*rimg64.Multi
+	// Capacitance matrix D^-1 + U' T^-1 U.
+	Cap *mat.Mat
+
+	solve func(b *rimg64.Multi) (*rimg64.Multi, error)
+}
+
+// Init computes the capacitance matrix.
+// Components with zero weight are ignored.
+func (op *InvMuler) Init(s *Covar, opts SolveOpts) error {
+	op.Width, op.Height, op.Channels = s.Width, s.Height, s.Channels()
+	solve, err := toeplitzSolver(s.Toeplitz, s.Width, s.Height, opts)
+	if err != nil {
+		return err
+	}
+	op.solve = solve
+
+	var weights []float64
+	op.Basis, op.Z = nil, nil
+	for i, u := range s.Basis {
+		if s.Weights[i] == 0 {
+			continue
+		}
+		log.Printf("solve Toeplitz system for component %d / %d", i+1, len(s.Basis))
+		z, err := op.solve(u)
+		if err != nil {
+			return err
+		}
+		op.Basis = append(op.Basis, u)
+		op.Z = append(op.Z, z)
+		weights = append(weights, s.Weights[i])
+	}
+
+	k := len(op.Basis)
+	op.Cap = mat.New(k, k)
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			x := floats.Dot(op.Basis[i].Elems, op.Z[j].Elems)
+			if i == j {
+				x += 1 / weights[i]
+			}
+			op.Cap.Set(i, j, x)
+		}
+	}
+	return nil
+}
+
+// Mul solves for x in S x = b.
+// Init must be called before Mul.
+func (op *InvMuler) Mul(b *rimg64.Multi) (*rimg64.Multi, error) {
+	if b.Width != op.Width || b.Height != op.Height || b.Channels != op.Channels {
+		panic(fmt.Sprintf(
+			"bad dimensions: operator %dx%dx%d, image %dx%dx%d",
+			op.Width, op.Height, op.Channels, b.Width, b.Height, b.Channels,
+		))
+	}
+	y, err := op.solve(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.Basis) == 0 {
+		return y, nil
+	}
+	r := make([]float64, len(op.Basis))
+	for i, u := range op.Basis {
+		r[i] = floats.Dot(u.Elems, y.Elems)
+	}
+	// Capacitance matrix is not definite if weights are negative.
+	z, err := lapack.Solve(op.Cap, r)
+	if err != nil {
+		return nil, err
+	}
+	x := y.Clone()
+	for i := range op.Z {
+		floats.AddScaled(x.Elems, -z[i], op.Z[i].Elems)
+	}
+	return x, nil
+}
+
+// InvMul solves for x in S x = b.
+// To solve several systems, use InvMuler.
+func InvMul(s *Covar, b *rimg64.Multi, opts SolveOpts) (*rimg64.Multi, error) {
+	var op InvMuler
+	if err := op.Init(s, opts); err != nil {
+		return nil, err
+	}
+	return op.Mul(b)
+}
+
+func toeplitzSolver(g *toepcov.Covar, w, h int, opts SolveOpts) (func(*rimg64.Multi) (*rimg64.Multi, error), error) {
+	switch opts.Algo {
+	case "chol":
+		fact, err := lapack.Chol(g.Matrix(w, h))
+		if err != nil {
+			return nil, err
+		}
+		return func(b *rimg64.Multi) (*rimg64.Multi, error) {
+			x, err := fact.Solve(b.Elems)
+			if err != nil {
+				return nil, err
+			}
+			return &rimg64.Multi{x, b.Width, b.Height, b.Channels}, nil
+		}, nil
+
+	case "cg":
+		return func(b *rimg64.Multi) (*rimg64.Multi, error) {
+			x := rimg64.NewMulti(b.Width, b.Height, b.Channels)
+			return toepcov.InvMulConjGrad(g, b, x, opts.Tol, opts.Iter, ioutil.Discard)
+		}, nil
+
+	case "pcg":
+		return func(b *rimg64.Multi) (*rimg64.Multi, error) {
+			x := rimg64.NewMulti(b.Width, b.Height, b.Channels)
+			return circcov.ToeplitzInvMulPCG(g, b, x, opts.Tol, opts.Iter, ioutil.Discard)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown algorithm: %q", opts.Algo)
+	}
+}
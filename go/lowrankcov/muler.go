@@ -0,0 +1,41 @@
+package lowrankcov
+
+import (
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+// Muler multiplies images by a low-rank-plus-Toeplitz covariance matrix.
+// The Toeplitz part is multiplied in the Fourier domain
+// and the correction is applied as a sequence of rank-one updates.
+type Muler struct {
+	Toeplitz toepcov.MulerFFT
+	Basis    []*rimg64.Multi
+	Weights  []float64
+}
+
+// Init pre-computes the transform of the Toeplitz part.
+func (op *Muler) Init(s *Covar) {
+	op.Toeplitz.Init(s.Toeplitz, s.Width, s.Height)
+	op.Basis = s.Basis
+	op.Weights = s.Weights
+}
+
+// Mul computes the product of the covariance matrix with the image f.
+// Init must be called before Mul.
+func (op *Muler) Mul(f *rimg64.Multi) *rimg64.Multi {
+	g := op.Toeplitz.Mul(f)
+	for i, u := range op.Basis {
+		floats.AddScaled(g.Elems, op.Weights[i]*floats.Dot(u.Elems, f.Elems), u.Elems)
+	}
+	return g
+}
+
+// Mul computes the product of the covariance matrix with the image f.
+// To compute several products, use Muler.
+func Mul(s *Covar, f *rimg64.Multi) *rimg64.Multi {
+	var op Muler
+	op.Init(s)
+	return op.Mul(f)
+}
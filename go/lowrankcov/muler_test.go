@@ -0,0 +1,66 @@
+package lowrankcov
+
+import (
+	"testing"
+
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/lin-go/mat"
+)
+
+// Checks that the product is the same as using the full matrix.
+func TestMul(t *testing.T) {
+	const (
+		width     = 7
+		height    = 5
+		channels  = 3
+		bandwidth = 4
+	)
+
+	s := randLowRank(width, height, channels, bandwidth, []float64{1, -0.5, 2})
+	f := randImage(width, height, channels)
+	want := &rimg64.Multi{mat.MulVec(s.Matrix(), f.Elems), width, height, channels}
+	got := Mul(s, f)
+	if eq, msg := imagesEq(want, got); !eq {
+		t.Error(msg)
+	}
+}
+
+// Checks that the Woodbury solution is the same as using the full matrix.
+func TestInvMul(t *testing.T) {
+	const (
+		width     = 6
+		height    = 5
+		channels  = 2
+		bandwidth = 3
+	)
+
+	// T >= I and the negative component has unit norm,
+	// therefore S is positive definite.
+	s := randLowRank(width, height, channels, bandwidth, []float64{1, -0.5, 2})
+	s.Toeplitz.AddLambdaI(1)
+	b := randImage(width, height, channels)
+	x, err := lapack.SolvePosDef(s.Matrix(), b.Elems)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &rimg64.Multi{x, width, height, channels}
+
+	algos := []struct {
+		Name string
+		Eps  float64
+	}{
+		{"chol", 1e-9},
+		{"cg", 1e-6},
+		{"pcg", 1e-6},
+	}
+	for _, algo := range algos {
+		got, err := InvMul(s, b, SolveOpts{Algo: algo.Name, Tol: 1e-12})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eq, msg := imagesEqEps(want, got, algo.Eps); !eq {
+			t.Errorf(`algo "%s": %s`, algo.Name, msg)
+		}
+	}
+}
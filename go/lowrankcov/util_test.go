@@ -0,0 +1,84 @@
+package lowrankcov
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/lin-go/mat"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+const eps = 1e-9
+
+func epsEq(want, got, eps float64) bool {
+	return math.Abs(want-got) <= eps
+}
+
+func imagesEq(want, got *rimg64.Multi) (bool, string) {
+	return imagesEqEps(want, got, eps)
+}
+
+func imagesEqEps(want, got *rimg64.Multi, eps float64) (bool, string) {
+	if want.Width != got.Width || want.Height != got.Height || want.Channels != got.Channels {
+		return false, fmt.Sprintf(
+			"different size: want %dx%dx%d, got %dx%dx%d",
+			want.Width, want.Height, want.Channels, got.Width, got.Height, got.Channels,
+		)
+	}
+	for i := range want.Elems {
+		if !epsEq(want.Elems[i], got.Elems[i], eps) {
+			return false, fmt.Sprintf("element %d: want %.6g, got %.6g", i, want.Elems[i], got.Elems[i])
+		}
+	}
+	return true, ""
+}
+
+func matsEq(want, got mat.Const) (bool, string) {
+	m, n := want.Dims()
+	if p, q := got.Dims(); p != m || q != n {
+		return false, fmt.Sprintf("different size: want %dx%d, got %dx%d", m, n, p, q)
+	}
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			if !epsEq(want.At(i, j), got.At(i, j), eps) {
+				return false, fmt.Sprintf("at %d, %d: want %.6g, got %.6g", i, j, want.At(i, j), got.At(i, j))
+			}
+		}
+	}
+	return true, ""
+}
+
+// Generates random stationary covariance with appropriate symmetry.
+func randCovar(channels, bandwidth int) *toepcov.Covar {
+	f := randImage(4*bandwidth, 4*bandwidth, channels)
+	total := toepcov.Stats(f, bandwidth)
+	// Do not remove mean to ensure semidefinite.
+	return toepcov.Normalize(total, false).Covar
+}
+
+func randImage(width, height, channels int) *rimg64.Multi {
+	f := rimg64.NewMulti(width, height, channels)
+	for i := range f.Elems {
+		f.Elems[i] = rand.NormFloat64()
+	}
+	return f
+}
+
+// Generates a low-rank-plus-Toeplitz matrix with unit-norm basis.
+func randLowRank(width, height, channels, bandwidth int, weights []float64) *Covar {
+	s := &Covar{
+		Toeplitz: randCovar(channels, bandwidth),
+		Width:    width,
+		Height:   height,
+		Weights:  weights,
+	}
+	for _ = range weights {
+		u := randImage(width, height, channels)
+		floats.Scale(1/floats.Norm(u.Elems, 2), u.Elems)
+		s.Basis = append(s.Basis, u)
+	}
+	return s
+}
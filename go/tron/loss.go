@@ -0,0 +1,84 @@
+// Package tron trains L2-regularized linear models
+// by trust-region Newton optimization.
+package tron
+
+import (
+	"fmt"
+	"math"
+)
+
+// Loss is a function of the margin m = y w'x.
+// Deriv2 may be a generalized second derivative
+// where the loss is not twice differentiable.
+type Loss interface {
+	Value(m float64) float64
+	Deriv(m float64) float64
+	Deriv2(m float64) float64
+}
+
+// Losses lists the names accepted by NewLoss.
+var Losses = []string{"logistic", "sq-hinge"}
+
+// NewLoss returns the loss with the given name.
+func NewLoss(name string) (Loss, error) {
+	switch name {
+	case "logistic":
+		return Logistic{}, nil
+	case "sq-hinge":
+		return SquaredHinge{}, nil
+	default:
+		return nil, fmt.Errorf("unknown loss: %q (want one of %v)", name, Losses)
+	}
+}
+
+// Logistic is the loss log(1 + exp(-m)).
+type Logistic struct{}
+
+func (Logistic) Value(m float64) float64 {
+	if m < 0 {
+		// Avoid overflow of exp(-m).
+		return -m + math.Log1p(math.Exp(m))
+	}
+	return math.Log1p(math.Exp(-m))
+}
+
+func (Logistic) Deriv(m float64) float64 {
+	return -sigmoid(-m)
+}
+
+func (Logistic) Deriv2(m float64) float64 {
+	s := sigmoid(m)
+	return s * (1 - s)
+}
+
+func sigmoid(x float64) float64 {
+	if x < 0 {
+		e := math.Exp(x)
+		return e / (1 + e)
+	}
+	return 1 / (1 + math.Exp(-x))
+}
+
+// SquaredHinge is the loss max(0, 1 - m)^2.
+type SquaredHinge struct{}
+
+func (SquaredHinge) Value(m float64) float64 {
+	if m >= 1 {
+		return 0
+	}
+	return (1 - m) * (1 - m)
+}
+
+func (SquaredHinge) Deriv(m float64) float64 {
+	if m >= 1 {
+		return 0
+	}
+	return -2 * (1 - m)
+}
+
+func (SquaredHinge) Deriv2(m float64) float64 {
+	if m >= 1 {
+		return 0
+	}
+	return 2
+}
@@ -0,0 +1,63 @@
+package tron
+
+import (
+	"fmt"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+// Problem is to minimize over w
+//
+//	1/2 ||w||^2 + sum_i C[i] Loss(Y[i] w'X[i]).
+type Problem struct {
+	X    vecset.Set
+	Y    []float64
+	C    []float64
+	Loss Loss
+}
+
+func (p *Problem) check() error {
+	n := p.X.Len()
+	if len(p.Y) != n || len(p.C) != n {
+		return fmt.Errorf("lengths differ: vectors %d, labels %d, costs %d", n, len(p.Y), len(p.C))
+	}
+	return nil
+}
+
+// Eval returns the objective and gradient at w,
+// and the second derivative of the loss for each example,
+// which is needed by HessVec.
+func (p *Problem) Eval(w []float64) (f float64, g, d []float64) {
+	f = 0.5 * floats.Dot(w, w)
+	g = append([]float64(nil), w...)
+	d = make([]float64, p.X.Len())
+	for i := 0; i < p.X.Len(); i++ {
+		x := p.X.At(i)
+		m := p.Y[i] * floats.Dot(w, x)
+		f += p.C[i] * p.Loss.Value(m)
+		if dl := p.Loss.Deriv(m); dl != 0 {
+			floats.AddScaled(g, p.C[i]*p.Y[i]*dl, x)
+		}
+		d[i] = p.Loss.Deriv2(m)
+	}
+	return f, g, d
+}
+
+// HessVec multiplies v by the Hessian
+//
+//	I + sum_i C[i] d[i] X[i] X[i]'
+//
+// where d is returned by Eval.
+// Examples with zero curvature are skipped.
+func (p *Problem) HessVec(d, v []float64) []float64 {
+	h := append([]float64(nil), v...)
+	for i := 0; i < p.X.Len(); i++ {
+		if d[i] == 0 {
+			continue
+		}
+		x := p.X.At(i)
+		floats.AddScaled(h, p.C[i]*d[i]*floats.Dot(x, v), x)
+	}
+	return h
+}
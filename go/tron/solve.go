@@ -0,0 +1,164 @@
+package tron
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/gonum/floats"
+)
+
+// Opts specifies the termination criteria and preconditioner.
+type Opts struct {
+	// Terminate when ||g|| <= Eps ||g0||, where g0 is the gradient at zero.
+	Eps float64
+	// Maximum number of Newton iterations (zero or less means no limit).
+	MaxIter int
+	// Precond multiplies by the inverse of an approximation to the Hessian.
+	// Nil means no preconditioning.
+	// The trust region is measured in the norm of the approximation.
+	Precond func([]float64) []float64
+	// Initial solution. Nil means zero.
+	Init []float64
+}
+
+// Result describes the solution and the work required to find it.
+type Result struct {
+	W       []float64
+	F       float64
+	Iters   int // Number of Newton iterations.
+	CGIters int // Total number of conjugate gradient iterations.
+}
+
+// Parameters of the trust region update,
+// as in Lin, Weng and Keerthi, "Trust region Newton method
+// for large-scale logistic regression" (JMLR 2008).
+const (
+	eta0, eta1, eta2        = 1e-4, 0.25, 0.75
+	sigma1, sigma2, sigma3  = 0.25, 0.5, 4
+	maxCGFrac, relCGTolDefl = 1, 0.1
+	minRelChange            = 1e-12
+)
+
+// Solve minimizes the objective of p by trust-region Newton
+// with a (preconditioned) conjugate gradient inner solver.
+func Solve(p *Problem, opts Opts) (*Result, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	n := p.X.Dim()
+	precond := opts.Precond
+	if precond == nil {
+		precond = func(x []float64) []float64 { return append([]float64(nil), x...) }
+	}
+
+	// The gradient at zero defines the stopping criterion.
+	_, g0, _ := p.Eval(make([]float64, n))
+	gnorm0 := floats.Norm(g0, 2)
+
+	w := make([]float64, n)
+	if opts.Init != nil {
+		if len(opts.Init) != n {
+			return nil, fmt.Errorf("dimension: initial solution has %d, want %d", len(opts.Init), n)
+		}
+		copy(w, opts.Init)
+	}
+	f, g, d := p.Eval(w)
+	delta := math.Sqrt(floats.Dot(g, precond(g)))
+	res := &Result{}
+	for iter := 1; opts.MaxIter <= 0 || iter <= opts.MaxIter; iter++ {
+		gnorm := floats.Norm(g, 2)
+		log.Printf("iter %d: f %.6g, |g| %.3g, |g0| %.3g, delta %.3g", iter-1, f, gnorm, gnorm0, delta)
+		if gnorm <= opts.Eps*gnorm0 {
+			break
+		}
+		s, r, snorm, cgIters := trcg(p, d, g, delta, precond)
+		res.CGIters += cgIters
+		wNew := make([]float64, n)
+		floats.AddTo(wNew, w, s)
+		gs := floats.Dot(g, s)
+		prered := -0.5 * (gs - floats.Dot(s, r))
+		fNew, gNew, dNew := p.Eval(wNew)
+		actred := f - fNew
+
+		if iter == 1 {
+			delta = math.Min(delta, snorm)
+		}
+		var alpha float64
+		if fNew-f-gs <= 0 {
+			alpha = sigma3
+		} else {
+			alpha = math.Max(sigma1, -0.5*(gs/(fNew-f-gs)))
+		}
+		switch {
+		case actred < eta0*prered:
+			delta = math.Min(math.Max(alpha, sigma1)*snorm, sigma2*delta)
+		case actred < eta1*prered:
+			delta = math.Max(sigma1*delta, math.Min(alpha*snorm, sigma2*delta))
+		case actred < eta2*prered:
+			delta = math.Max(sigma1*delta, math.Min(alpha*snorm, sigma3*delta))
+		default:
+			delta = math.Max(delta, math.Min(alpha*snorm, sigma3*delta))
+		}
+		res.Iters = iter
+		if actred > eta0*prered {
+			w, f, g, d = wNew, fNew, gNew, dNew
+		}
+		if actred <= 0 && prered <= 0 {
+			log.Print("actual and predicted reduction are not positive")
+			break
+		}
+		if math.Abs(actred) <= minRelChange*math.Abs(f) && math.Abs(prered) <= minRelChange*math.Abs(f) {
+			log.Print("reduction is too small")
+			break
+		}
+	}
+	res.W, res.F = w, f
+	return res, nil
+}
+
+// trcg approximately solves H s = -g subject to ||s||_M <= delta
+// using preconditioned conjugate gradient (Steihaug).
+// Returns the step, the residual -g - H s, the M-norm of the step
+// and the number of iterations.
+func trcg(p *Problem, d, g []float64, delta float64, precond func([]float64) []float64) (s, r []float64, snorm float64, iters int) {
+	n := len(g)
+	s = make([]float64, n)
+	r = make([]float64, n)
+	floats.AddScaled(r, -1, g)
+	z := precond(r)
+	dir := append([]float64(nil), z...)
+	rz := floats.Dot(r, z)
+	tol := relCGTolDefl * floats.Norm(g, 2)
+	// Track M-norms of s, d and their inner product without M itself.
+	var sMs, sMd float64
+	dMd := rz
+	for iters = 1; iters <= maxCGFrac*n; iters++ {
+		if floats.Norm(r, 2) <= tol {
+			iters--
+			break
+		}
+		hd := p.HessVec(d, dir)
+		alpha := rz / floats.Dot(dir, hd)
+		if next := sMs + 2*alpha*sMd + alpha*alpha*dMd; next > delta*delta {
+			// Move to the trust region boundary and stop.
+			tau := (-sMd + math.Sqrt(sMd*sMd+dMd*(delta*delta-sMs))) / dMd
+			floats.AddScaled(s, tau, dir)
+			floats.AddScaled(r, -tau, hd)
+			return s, r, delta, iters
+		}
+		floats.AddScaled(s, alpha, dir)
+		floats.AddScaled(r, -alpha, hd)
+		sMs += 2*alpha*sMd + alpha*alpha*dMd
+		z = precond(r)
+		rzNew := floats.Dot(r, z)
+		beta := rzNew / rz
+		sMd = beta * (sMd + alpha*dMd)
+		dMd = rzNew + beta*beta*dMd
+		for i := range dir {
+			dir[i] = z[i] + beta*dir[i]
+		}
+		rz = rzNew
+	}
+	return s, r, math.Sqrt(sMs), iters
+}
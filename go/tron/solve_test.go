@@ -0,0 +1,96 @@
+package tron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/jvlmdr/shift-invar/go/vecset"
+)
+
+func randProblem(r *rand.Rand, n, dim int, loss Loss) *Problem {
+	w := make([]float64, dim)
+	for j := range w {
+		w[j] = r.NormFloat64()
+	}
+	p := &Problem{X: make(vecset.Slice, n), Y: make([]float64, n), C: make([]float64, n), Loss: loss}
+	for i := range p.Y {
+		x := make([]float64, dim)
+		for j := range x {
+			// Scale elements to make the problem poorly conditioned.
+			x[j] = float64(j+1) * r.NormFloat64()
+		}
+		p.X.(vecset.Slice)[i] = x
+		p.Y[i] = 1
+		if floats.Dot(w, x)+0.5*r.NormFloat64() < 0 {
+			p.Y[i] = -1
+		}
+		p.C[i] = 10 / float64(n)
+	}
+	return p
+}
+
+func TestSolve(t *testing.T) {
+	const n, dim = 200, 8
+	for _, name := range Losses {
+		loss, err := NewLoss(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := randProblem(rand.New(rand.NewSource(1)), n, dim, loss)
+		// Diagonal preconditioner from the scale of each element.
+		diag := func(x []float64) []float64 {
+			y := make([]float64, len(x))
+			for j := range x {
+				y[j] = x[j] / (1 + 10*float64((j+1)*(j+1)))
+			}
+			return y
+		}
+		for _, precond := range []func([]float64) []float64{nil, diag} {
+			res, err := Solve(p, Opts{Eps: 1e-8, MaxIter: 100, Precond: precond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, g, _ := p.Eval(res.W)
+			_, g0, _ := p.Eval(make([]float64, dim))
+			if gnorm := floats.Norm(g, 2); gnorm > 1e-6*floats.Norm(g0, 2) {
+				t.Errorf("%s (precond %v): gradient norm %.3g", name, precond != nil, gnorm)
+			}
+		}
+	}
+}
+
+func TestSolve_init(t *testing.T) {
+	p := randProblem(rand.New(rand.NewSource(2)), 100, 5, Logistic{})
+	res, err := Solve(p, Opts{Eps: 1e-8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warm, err := Solve(p, Opts{Eps: 1e-8, Init: res.W})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warm.Iters > 1 {
+		t.Errorf("warm start: want at most 1 iteration, got %d", warm.Iters)
+	}
+	if _, err := Solve(p, Opts{Init: make([]float64, 3)}); err == nil {
+		t.Error("expected error for initial solution of wrong dimension")
+	}
+}
+
+func TestLoss_deriv(t *testing.T) {
+	const h = 1e-6
+	for _, loss := range []Loss{Logistic{}, SquaredHinge{}} {
+		for _, m := range []float64{-30, -2, -0.5, 0.3, 0.9, 2, 30} {
+			want := (loss.Value(m+h) - loss.Value(m-h)) / (2 * h)
+			if got := loss.Deriv(m); math.Abs(got-want) > 1e-6 {
+				t.Errorf("%T at %g: derivative: want %.6g, got %.6g", loss, m, want, got)
+			}
+			want = (loss.Deriv(m+h) - loss.Deriv(m-h)) / (2 * h)
+			if got := loss.Deriv2(m); math.Abs(got-want) > 1e-5 {
+				t.Errorf("%T at %g: second derivative: want %.6g, got %.6g", loss, m, want, got)
+			}
+		}
+	}
+}
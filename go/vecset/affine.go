@@ -0,0 +1,83 @@
+package vecset
+
+import "fmt"
+
+// Affine is the transform y = Proj (Scale .* (x - Mean)).
+// Any of the members may be nil to skip that step.
+type Affine struct {
+	Mean  []float64   // Subtracted from x.
+	Scale []float64   // Multiplies each element after subtracting Mean.
+	Proj  [][]float64 // Rows of a projection matrix.
+}
+
+// Dim returns the dimension of the output given that of the input.
+func (t *Affine) Dim(in int) int {
+	if t.Proj != nil {
+		return len(t.Proj)
+	}
+	return in
+}
+
+// Apply returns the transform of x.
+// The input is not modified.
+func (t *Affine) Apply(x []float64) []float64 {
+	if t.Mean != nil && len(t.Mean) != len(x) {
+		panic(fmt.Sprintf("dimension: mean has %d, vector has %d", len(t.Mean), len(x)))
+	}
+	if t.Scale != nil && len(t.Scale) != len(x) {
+		panic(fmt.Sprintf("dimension: scale has %d, vector has %d", len(t.Scale), len(x)))
+	}
+	z := make([]float64, len(x))
+	for i, xi := range x {
+		if t.Mean != nil {
+			xi -= t.Mean[i]
+		}
+		if t.Scale != nil {
+			xi *= t.Scale[i]
+		}
+		z[i] = xi
+	}
+	if t.Proj == nil {
+		return z
+	}
+	y := make([]float64, len(t.Proj))
+	for i, row := range t.Proj {
+		if len(row) != len(z) {
+			panic(fmt.Sprintf("dimension: projection has %d columns, vector has %d", len(row), len(z)))
+		}
+		var s float64
+		for j, zj := range z {
+			s += row[j] * zj
+		}
+		y[i] = s
+	}
+	return y
+}
+
+// AffineSet presents the vectors of a set after an affine transform.
+// If Bias is non-zero, it is appended after the transform.
+type AffineSet struct {
+	Set
+	Transform *Affine
+	Bias      float64
+}
+
+func (set *AffineSet) addBias() bool {
+	return set.Bias != 0
+}
+
+func (set *AffineSet) Dim() int {
+	n := set.Transform.Dim(set.Set.Dim())
+	if set.addBias() {
+		n++
+	}
+	return n
+}
+
+func (set *AffineSet) At(i int) []float64 {
+	y := set.Transform.Apply(set.Set.At(i))
+	if set.addBias() {
+		y = append(y, set.Bias)
+	}
+	return y
+}
@@ -0,0 +1,294 @@
+package vecset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// fileMagic identifies a file of rows.
+const fileMagic = "vecrows1"
+
+// fileHeaderLen is the length of the magic string
+// followed by four uint64s: element size, width, height and channels.
+const fileHeaderLen = len(fileMagic) + 4*8
+
+// Elem specifies the precision with which elements are stored.
+// The value is the number of bytes per element.
+type Elem int
+
+const (
+	Float32 Elem = 4
+	Float64 Elem = 8
+)
+
+func (e Elem) valid() bool {
+	return e == Float32 || e == Float64
+}
+
+// Shape describes the rows of a file.
+// Each row has Width*Height*Channels elements.
+// Rows which are plain vectors have Height and Channels equal to one.
+type Shape struct {
+	Width, Height, Channels int
+}
+
+// Dim returns the number of elements in a row.
+func (s Shape) Dim() int {
+	return s.Width * s.Height * s.Channels
+}
+
+type fileHeader struct {
+	Elem  Elem
+	Shape Shape
+}
+
+func writeFileHeader(w io.Writer, h fileHeader) error {
+	if _, err := io.WriteString(w, fileMagic); err != nil {
+		return err
+	}
+	fields := []uint64{uint64(h.Elem), uint64(h.Shape.Width), uint64(h.Shape.Height), uint64(h.Shape.Channels)}
+	return binary.Write(w, binary.LittleEndian, fields)
+}
+
+func parseFileHeader(b []byte) (fileHeader, error) {
+	if len(b) < fileHeaderLen {
+		return fileHeader{}, fmt.Errorf("header too short: %d bytes", len(b))
+	}
+	if string(b[:len(fileMagic)]) != fileMagic {
+		return fileHeader{}, fmt.Errorf("not a file of rows")
+	}
+	b = b[len(fileMagic):]
+	fields := make([]int, 4)
+	for i := range fields {
+		fields[i] = int(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	h := fileHeader{Elem(fields[0]), Shape{fields[1], fields[2], fields[3]}}
+	if !h.Elem.valid() {
+		return fileHeader{}, fmt.Errorf("invalid element size: %d", h.Elem)
+	}
+	return h, nil
+}
+
+// File is a Set whose vectors are the rows of a memory-mapped file.
+// Rows which are appended to the file after it is opened are not visible.
+type File struct {
+	elem  Elem
+	shape Shape
+	n     int
+	data  []byte // Rows, excluding the header.
+	unmap func() error
+}
+
+// OpenFile maps a file which was created by CreateFile.
+// An incomplete final row (e.g. from an interrupted write) is ignored.
+// The file must be closed to release the mapping.
+func OpenFile(fname string) (*File, error) {
+	data, unmap, err := mapFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	h, err := parseFileHeader(data)
+	if err != nil {
+		unmap()
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	f := &File{elem: h.Elem, shape: h.Shape, data: data[fileHeaderLen:], unmap: unmap}
+	if rowLen := f.rowLen(); rowLen > 0 {
+		f.n = len(f.data) / rowLen
+	}
+	return f, nil
+}
+
+// Close releases the mapping.
+// The vectors returned by At remain valid.
+func (f *File) Close() error {
+	f.data = nil
+	return f.unmap()
+}
+
+func (f *File) rowLen() int {
+	return f.shape.Dim() * int(f.elem)
+}
+
+func (f *File) Len() int {
+	return f.n
+}
+
+func (f *File) Dim() int {
+	return f.shape.Dim()
+}
+
+// Shape returns the shape of each row.
+func (f *File) Shape() Shape {
+	return f.shape
+}
+
+// Elem returns the precision with which elements are stored.
+func (f *File) Elem() Elem {
+	return f.elem
+}
+
+// At returns a copy of the i-th row.
+func (f *File) At(i int) []float64 {
+	if i < 0 || i >= f.n {
+		panic(fmt.Sprintf("index out of range: %d not in [0, %d)", i, f.n))
+	}
+	rowLen := f.rowLen()
+	return decodeRow(f.data[i*rowLen:(i+1)*rowLen], f.elem)
+}
+
+func decodeRow(b []byte, elem Elem) []float64 {
+	x := make([]float64, len(b)/int(elem))
+	switch elem {
+	case Float32:
+		for j := range x {
+			x[j] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4*j:])))
+		}
+	case Float64:
+		for j := range x {
+			x[j] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*j:]))
+		}
+	}
+	return x
+}
+
+func encodeRow(b []byte, x []float64, elem Elem) {
+	switch elem {
+	case Float32:
+		for j, xj := range x {
+			binary.LittleEndian.PutUint32(b[4*j:], math.Float32bits(float32(xj)))
+		}
+	case Float64:
+		for j, xj := range x {
+			binary.LittleEndian.PutUint64(b[8*j:], math.Float64bits(xj))
+		}
+	}
+}
+
+// FileWriter appends rows to a file.
+type FileWriter struct {
+	file  *os.File
+	w     *bufio.Writer
+	elem  Elem
+	shape Shape
+	buf   []byte
+	n     int
+}
+
+// CreateFile creates a file of rows with the given shape, replacing any existing file.
+func CreateFile(fname string, shape Shape, elem Elem) (*FileWriter, error) {
+	if !elem.valid() {
+		return nil, fmt.Errorf("invalid element size: %d", elem)
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	if err := writeFileHeader(w, fileHeader{elem, shape}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return newFileWriter(file, w, elem, shape, 0), nil
+}
+
+// AppendFile opens an existing file of rows for appending.
+// An incomplete final row is discarded.
+func AppendFile(fname string) (*FileWriter, error) {
+	file, err := os.OpenFile(fname, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, fileHeaderLen)
+	if _, err := io.ReadFull(file, b); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: read header: %v", fname, err)
+	}
+	h, err := parseFileHeader(b)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	var n int
+	if rowLen := h.Shape.Dim() * int(h.Elem); rowLen > 0 {
+		n = int(info.Size()-int64(fileHeaderLen)) / rowLen
+	}
+	end := int64(fileHeaderLen + n*h.Shape.Dim()*int(h.Elem))
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return newFileWriter(file, bufio.NewWriter(file), h.Elem, h.Shape, n), nil
+}
+
+func newFileWriter(file *os.File, w *bufio.Writer, elem Elem, shape Shape, n int) *FileWriter {
+	return &FileWriter{
+		file:  file,
+		w:     w,
+		elem:  elem,
+		shape: shape,
+		buf:   make([]byte, shape.Dim()*int(elem)),
+		n:     n,
+	}
+}
+
+// Write appends a row.
+func (w *FileWriter) Write(x []float64) error {
+	if len(x) != w.shape.Dim() {
+		return fmt.Errorf("dimension: file has %d, vector has %d", w.shape.Dim(), len(x))
+	}
+	encodeRow(w.buf, x, w.elem)
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+	w.n++
+	return nil
+}
+
+// Len returns the number of rows in the file, including those written.
+func (w *FileWriter) Len() int {
+	return w.n
+}
+
+// Flush writes any buffered rows to the file.
+func (w *FileWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Close flushes the buffered rows and closes the file.
+func (w *FileWriter) Close() error {
+	if err := w.w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// SaveFile writes every vector in a set to a new file.
+// The set must not be empty.
+func SaveFile(fname string, set Set, elem Elem) error {
+	w, err := CreateFile(fname, Shape{Width: set.Dim(), Height: 1, Channels: 1}, elem)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < set.Len(); i++ {
+		if err := w.Write(set.At(i)); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
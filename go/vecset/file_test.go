@@ -0,0 +1,147 @@
+package vecset
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func randSlice(r *rand.Rand, n, dim int) Slice {
+	x := make(Slice, n)
+	for i := range x {
+		x[i] = make([]float64, dim)
+		for j := range x[i] {
+			x[i][j] = r.NormFloat64()
+		}
+	}
+	return x
+}
+
+func setsEq(a, b Set) bool {
+	if a.Len() != b.Len() || a.Dim() != b.Dim() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if !reflect.DeepEqual(a.At(i), b.At(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vecset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := rand.New(rand.NewSource(1))
+	want := randSlice(r, 10, 7)
+
+	fname := path.Join(dir, "rows.bin")
+	if err := SaveFile(fname, want, Float64); err != nil {
+		t.Fatal(err)
+	}
+	f, err := OpenFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !setsEq(want, f) {
+		t.Error("file differs from slice")
+	}
+
+	// Files can be combined with other sets.
+	u := NewUnion([]Set{want[:4], f})
+	if u.Len() != 14 || u.Dim() != 7 {
+		t.Fatalf("union: want 14 vectors of dim 7, got %d of dim %d", u.Len(), u.Dim())
+	}
+	if !reflect.DeepEqual(want[2], u.At(6)) {
+		t.Errorf("union: want %v, got %v", want[2], u.At(6))
+	}
+}
+
+func TestFile_float32(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vecset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := rand.New(rand.NewSource(1))
+	x := randSlice(r, 5, 3)
+	want := make(Slice, len(x))
+	for i := range x {
+		want[i] = make([]float64, len(x[i]))
+		for j := range x[i] {
+			want[i][j] = float64(float32(x[i][j]))
+		}
+	}
+
+	fname := path.Join(dir, "rows.bin")
+	if err := SaveFile(fname, x, Float32); err != nil {
+		t.Fatal(err)
+	}
+	f, err := OpenFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !setsEq(want, f) {
+		t.Error("file differs from rounded slice")
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vecset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := rand.New(rand.NewSource(1))
+	want := randSlice(r, 6, 4)
+
+	fname := path.Join(dir, "rows.bin")
+	if err := SaveFile(fname, want[:3], Float64); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interrupted write.
+	file, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	w, err := AppendFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Len() != 3 {
+		t.Errorf("len: want 3, got %d", w.Len())
+	}
+	for _, x := range want[3:] {
+		if err := w.Write(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Write(make([]float64, 5)); err == nil {
+		t.Error("expected error for wrong dimension")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !setsEq(want, f) {
+		t.Error("file differs from slice")
+	}
+}
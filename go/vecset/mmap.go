@@ -0,0 +1,30 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package vecset
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the contents of a file into memory (read only).
+func mapFile(fname string) ([]byte, func() error, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package vecset
+
+import "io/ioutil"
+
+// mapFile reads the contents of a file into memory
+// on systems where it is not memory-mapped.
+func mapFile(fname string) ([]byte, func() error, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,141 @@
+package vecset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jvlmdr/lin-go/lapack"
+	"github.com/jvlmdr/lin-go/mat"
+)
+
+// Moments accumulates the sum and the sum of outer products
+// of a stream of vectors in a single pass.
+// The memory required is quadratic in the dimension.
+type Moments struct {
+	N    int
+	Sum  []float64
+	Prod *mat.Mat // Upper triangle only.
+}
+
+func NewMoments(dim int) *Moments {
+	return &Moments{Sum: make([]float64, dim), Prod: mat.New(dim, dim)}
+}
+
+// Add accumulates one vector.
+func (m *Moments) Add(x []float64) {
+	if len(x) != len(m.Sum) {
+		panic(fmt.Sprintf("dimension: moments have %d, vector has %d", len(m.Sum), len(x)))
+	}
+	m.N++
+	for i, xi := range x {
+		m.Sum[i] += xi
+		for j := i; j < len(x); j++ {
+			m.Prod.Set(i, j, m.Prod.At(i, j)+xi*x[j])
+		}
+	}
+}
+
+// AddSet accumulates every vector in a set.
+func (m *Moments) AddSet(set Set) {
+	for i := 0; i < set.Len(); i++ {
+		m.Add(set.At(i))
+	}
+}
+
+// Mean returns the sample mean.
+func (m *Moments) Mean() []float64 {
+	mu := make([]float64, len(m.Sum))
+	for i := range mu {
+		mu[i] = m.Sum[i] / float64(m.N)
+	}
+	return mu
+}
+
+// Covar returns the sample covariance (normalized by N).
+func (m *Moments) Covar() *mat.Mat {
+	n := len(m.Sum)
+	mu := m.Mean()
+	c := mat.New(n, n)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			cij := m.Prod.At(i, j)/float64(m.N) - mu[i]*mu[j]
+			c.Set(i, j, cij)
+			c.Set(j, i, cij)
+		}
+	}
+	return c
+}
+
+// Standardize returns the transform which subtracts the mean
+// and divides each element by its standard deviation.
+// Elements with variance at most eps are only centered.
+func (m *Moments) Standardize(eps float64) (*Affine, error) {
+	if m.N == 0 {
+		return nil, fmt.Errorf("no vectors")
+	}
+	mu := m.Mean()
+	scale := make([]float64, len(mu))
+	for i := range scale {
+		v := m.Prod.At(i, i)/float64(m.N) - mu[i]*mu[i]
+		if v <= eps {
+			scale[i] = 1
+			continue
+		}
+		scale[i] = 1 / math.Sqrt(v)
+	}
+	return &Affine{Mean: mu, Scale: scale}, nil
+}
+
+// PCA returns the transform which subtracts the mean and projects
+// onto the k principal components, and the variance along each.
+// Components are in order of decreasing variance.
+// If k exceeds the dimension, all components are returned.
+func (m *Moments) PCA(k int) (*Affine, []float64, error) {
+	if m.N == 0 {
+		return nil, nil, fmt.Errorf("no vectors")
+	}
+	n := len(m.Sum)
+	vecs, vals, err := lapack.EigSymm(m.Covar())
+	if err != nil {
+		return nil, nil, err
+	}
+	order := make([]int, len(vals))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Sort(byDecr{order, vals})
+	if k > len(order) {
+		k = len(order)
+	}
+	t := &Affine{Mean: m.Mean()}
+	vars := make([]float64, k)
+	for r, i := range order[:k] {
+		row := make([]float64, n)
+		for j := range row {
+			row[j] = vecs.At(j, i)
+		}
+		t.Proj = append(t.Proj, row)
+		vars[r] = vals[i]
+	}
+	return t, vars, nil
+}
+
+// FitPCA streams over a set once and calls Moments.PCA.
+func FitPCA(set Set, k int) (*Affine, []float64, error) {
+	if set.Len() == 0 {
+		return nil, nil, fmt.Errorf("empty set")
+	}
+	m := NewMoments(set.Dim())
+	m.AddSet(set)
+	return m.PCA(k)
+}
+
+type byDecr struct {
+	order []int
+	vals  []float64
+}
+
+func (s byDecr) Len() int           { return len(s.order) }
+func (s byDecr) Swap(i, j int)      { s.order[i], s.order[j] = s.order[j], s.order[i] }
+func (s byDecr) Less(i, j int) bool { return s.vals[s.order[i]] > s.vals[s.order[j]] }
@@ -0,0 +1,94 @@
+package vecset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAffineSet(t *testing.T) {
+	x := Slice{{1, 2, 3}, {4, 5, 6}}
+	tr := &Affine{
+		Mean:  []float64{1, 1, 1},
+		Scale: []float64{1, 2, 0},
+		Proj:  [][]float64{{1, 1, 1}, {1, 0, -1}},
+	}
+	set := &AffineSet{Set: x, Transform: tr, Bias: 10}
+	if set.Dim() != 3 {
+		t.Fatalf("dim: want 3, got %d", set.Dim())
+	}
+	want := [][]float64{{2, 0, 10}, {11, 3, 10}}
+	for i := range want {
+		got := set.At(i)
+		for j := range want[i] {
+			if got[j] != want[i][j] {
+				t.Errorf("vector %d: want %v, got %v", i, want[i], got)
+				break
+			}
+		}
+	}
+}
+
+func TestMoments_Standardize(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	x := make(Slice, 1000)
+	for i := range x {
+		x[i] = []float64{3 + 2*r.NormFloat64(), -1 + 0.5*r.NormFloat64(), 7}
+	}
+	m := NewMoments(3)
+	m.AddSet(x)
+	tr, err := m.Standardize(1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := NewMoments(3)
+	y.AddSet(&AffineSet{Set: x, Transform: tr})
+	mu, cov := y.Mean(), y.Covar()
+	for i := 0; i < 2; i++ {
+		if math.Abs(mu[i]) > 1e-9 || math.Abs(cov.At(i, i)-1) > 1e-9 {
+			t.Errorf("element %d: mean %.3g, variance %.3g", i, mu[i], cov.At(i, i))
+		}
+	}
+	// Constant element is centered but not scaled.
+	if tr.Scale[2] != 1 || math.Abs(mu[2]) > 1e-9 {
+		t.Errorf("constant element: scale %g, mean %g", tr.Scale[2], mu[2])
+	}
+}
+
+func TestFitPCA(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// Points close to the line through (1, 2, 0) in direction (1, 1, 0).
+	x := make(Slice, 500)
+	for i := range x {
+		a, b := 3*r.NormFloat64(), 0.1*r.NormFloat64()
+		x[i] = []float64{1 + a + b, 2 + a - b, 0.01 * r.NormFloat64()}
+	}
+	tr, vars, err := FitPCA(x, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Proj) != 2 || len(vars) != 2 {
+		t.Fatalf("want 2 components, got %d", len(tr.Proj))
+	}
+	if vars[0] < vars[1] {
+		t.Errorf("variances not decreasing: %v", vars)
+	}
+	// First component is the direction of the line.
+	u := tr.Proj[0]
+	if d := math.Abs(u[0]+u[1]) / math.Sqrt2; math.Abs(d-1) > 1e-3 {
+		t.Errorf("first component: got %v", u)
+	}
+	if math.Abs(vars[0]-18) > 3 {
+		t.Errorf("first variance: want about 18, got %.3g", vars[0])
+	}
+	// Projected vectors are centered and uncorrelated.
+	m := NewMoments(2)
+	m.AddSet(&AffineSet{Set: x, Transform: tr})
+	mu, cov := m.Mean(), m.Covar()
+	if math.Abs(mu[0]) > 1e-9 || math.Abs(mu[1]) > 1e-9 {
+		t.Errorf("projection not centered: %v", mu)
+	}
+	if math.Abs(cov.At(0, 1)) > 1e-9 {
+		t.Errorf("projection correlated: %g", cov.At(0, 1))
+	}
+}
@@ -0,0 +1,194 @@
+package vecset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+)
+
+// poolMagic identifies a file written by Pool.Write.
+const poolMagic = "vecpool1"
+
+// Pool is a set of vectors of equal dimension,
+// each identified by a unique key.
+// It can be written to and read from a compact binary format.
+type Pool struct {
+	dim   int
+	keys  []string
+	vecs  [][]float64
+	index map[string]int
+}
+
+// NewPool returns an empty pool of vectors of dimension dim.
+func NewPool(dim int) *Pool {
+	return &Pool{dim: dim, index: make(map[string]int)}
+}
+
+func (p *Pool) Len() int {
+	return len(p.vecs)
+}
+
+func (p *Pool) Dim() int {
+	return p.dim
+}
+
+func (p *Pool) At(i int) []float64 {
+	return p.vecs[i]
+}
+
+// Key returns the key of the i-th vector.
+func (p *Pool) Key(i int) string {
+	return p.keys[i]
+}
+
+// Has returns whether the pool contains a vector with the given key.
+func (p *Pool) Has(key string) bool {
+	_, ok := p.index[key]
+	return ok
+}
+
+// Add appends a copy of x to the pool.
+// Returns false and does not add the vector
+// if the pool already contains the key.
+func (p *Pool) Add(key string, x []float64) bool {
+	if len(x) != p.dim {
+		panic(fmt.Sprintf("dimension: pool has %d, vector has %d", p.dim, len(x)))
+	}
+	if p.Has(key) {
+		return false
+	}
+	p.index[key] = len(p.vecs)
+	p.keys = append(p.keys, key)
+	p.vecs = append(p.vecs, append([]float64(nil), x...))
+	return true
+}
+
+// Filter removes every vector for which keep returns false.
+// The order of the remaining vectors is preserved.
+// Returns the number of vectors removed.
+func (p *Pool) Filter(keep func(i int) bool) int {
+	var (
+		keys []string
+		vecs [][]float64
+	)
+	for i := range p.vecs {
+		if keep(i) {
+			keys = append(keys, p.keys[i])
+			vecs = append(vecs, p.vecs[i])
+		}
+	}
+	n := len(p.vecs) - len(vecs)
+	p.keys, p.vecs = keys, vecs
+	p.index = make(map[string]int, len(keys))
+	for i, key := range keys {
+		p.index[key] = i
+	}
+	return n
+}
+
+// Write encodes the pool.
+// The format is a header (magic string, dimension, count)
+// followed by one record per vector (key length, key, elements),
+// with all numbers little-endian.
+func (p *Pool) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, poolMagic); err != nil {
+		return err
+	}
+	header := []uint64{uint64(p.dim), uint64(len(p.vecs))}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	buf := make([]byte, 8*p.dim)
+	for i, x := range p.vecs {
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(p.keys[i]))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, p.keys[i]); err != nil {
+			return err
+		}
+		for j, xj := range x {
+			binary.LittleEndian.PutUint64(buf[8*j:], math.Float64bits(xj))
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPool decodes a pool which was encoded by Write.
+func ReadPool(r io.Reader) (*Pool, error) {
+	magic := make([]byte, len(poolMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != poolMagic {
+		return nil, fmt.Errorf("not a vector pool")
+	}
+	header := make([]uint64, 2)
+	if err := binary.Read(r, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	dim, n := int(header[0]), int(header[1])
+	p := NewPool(dim)
+	buf := make([]byte, 8*dim)
+	for i := 0; i < n; i++ {
+		var keyLen uint32
+		if err := binary.Read(r, binary.LittleEndian, &keyLen); err != nil {
+			return nil, fmt.Errorf("vector %d: %v", i, err)
+		}
+		key := make([]byte, keyLen)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, fmt.Errorf("vector %d: %v", i, err)
+		}
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("vector %d: %v", i, err)
+		}
+		x := make([]float64, dim)
+		for j := range x {
+			x[j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*j:]))
+		}
+		if !p.Add(string(key), x) {
+			return nil, fmt.Errorf("duplicate key: %s", key)
+		}
+	}
+	return p, nil
+}
+
+// SavePool writes the pool to a file.
+// The file is replaced atomically so that
+// an interrupted save does not corrupt an existing pool.
+func SavePool(fname string, p *Pool) error {
+	tmp := path.Join(path.Dir(fname), "."+path.Base(fname)+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := p.Write(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+// LoadPool reads a pool from a file.
+func LoadPool(fname string) (*Pool, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPool(bufio.NewReader(file))
+}
@@ -0,0 +1,93 @@
+package vecset
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestPool_Add(t *testing.T) {
+	p := NewPool(2)
+	if !p.Add("a", []float64{1, 2}) {
+		t.Fatal("could not add a")
+	}
+	if !p.Add("b", []float64{3, 4}) {
+		t.Fatal("could not add b")
+	}
+	if p.Add("a", []float64{5, 6}) {
+		t.Error("added duplicate key")
+	}
+	if p.Len() != 2 {
+		t.Fatalf("len: want 2, got %d", p.Len())
+	}
+	if want := []float64{1, 2}; !reflect.DeepEqual(want, p.At(0)) {
+		t.Errorf("at 0: want %v, got %v", want, p.At(0))
+	}
+}
+
+func TestPool_Filter(t *testing.T) {
+	p := NewPool(1)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		p.Add(key, []float64{float64(i)})
+	}
+	n := p.Filter(func(i int) bool { return p.At(i)[0] != 1 && p.At(i)[0] != 2 })
+	if n != 2 {
+		t.Errorf("removed: want 2, got %d", n)
+	}
+	if p.Len() != 2 || p.Key(0) != "a" || p.Key(1) != "d" {
+		t.Fatalf("want keys [a d], got %d vectors", p.Len())
+	}
+	if p.Has("b") || !p.Has("d") {
+		t.Error("index not updated")
+	}
+	// A removed key can be added again.
+	if !p.Add("b", []float64{1}) {
+		t.Error("could not add removed key")
+	}
+}
+
+func TestPool_Write(t *testing.T) {
+	want := NewPool(3)
+	want.Add("im1.png 0 0 8 16", []float64{1, -2, 0.5})
+	want.Add("im2.png 4 4 12 20", []float64{0, 1e-300, -7})
+	var buf bytes.Buffer
+	if err := want.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPool(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestSavePool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := path.Join(dir, "pool.bin")
+	want := NewPool(1)
+	want.Add("a", []float64{1})
+	if err := SavePool(fname, want); err != nil {
+		t.Fatal(err)
+	}
+	// Overwrite existing pool.
+	want.Add("b", []float64{2})
+	if err := SavePool(fname, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPool(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,113 @@
+// Package whiten provides a feature transform which whitens
+// the output of another transform using stationary statistics.
+package whiten
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"path"
+	"sync"
+
+	"github.com/jvlmdr/go-cv/feat"
+	"github.com/jvlmdr/go-cv/featset"
+	"github.com/jvlmdr/go-cv/rimg64"
+	"github.com/jvlmdr/shift-invar/go/circcov"
+	"github.com/jvlmdr/shift-invar/go/toepcov"
+)
+
+func init() {
+	featset.RegisterImage("whiten", func() featset.Image { return new(Transform) })
+}
+
+// Transform applies a whitening filter bank to the output of Feat.
+// The filter bank is constructed from the statistics in StatsFile
+// the first time that the transform is applied.
+type Transform struct {
+	Feat featset.ImageMarshaler
+	// Statistics of Feat, as computed by cmd/stats.
+	// Must be an absolute path since the transform
+	// may be applied on a host with a different working directory.
+	StatsFile string
+	// Size of circulant grid on which filters are computed (feature pixels).
+	Res int
+	// Bandwidth of the filters (feature pixels).
+	// If zero, the bandwidth of the covariance.
+	Band   int
+	Lambda float64
+	// Eigenvalues less than MinEig are set to MinEig.
+	MinEig float64
+
+	once     sync.Once
+	whitener *circcov.Whitener
+	err      error
+}
+
+// Transform returns the transform itself.
+func (t *Transform) Transform() feat.Image {
+	return t
+}
+
+func (t *Transform) Rate() int {
+	return t.Feat.Transform().Rate()
+}
+
+func (t *Transform) Size(x image.Point) image.Point {
+	return t.Feat.Transform().Size(x)
+}
+
+func (t *Transform) MinInputSize(x image.Point) image.Point {
+	return t.Feat.Transform().MinInputSize(x)
+}
+
+func (t *Transform) Channels() int {
+	return t.Feat.Transform().Channels()
+}
+
+// Apply computes the features of x and whitens them.
+func (t *Transform) Apply(x image.Image) (*rimg64.Multi, error) {
+	whitener, err := t.Whitener()
+	if err != nil {
+		return nil, err
+	}
+	f, err := t.Feat.Transform().Apply(x)
+	if err != nil {
+		return nil, err
+	}
+	return whitener.Apply(f), nil
+}
+
+// Whitener loads the statistics and constructs the filter bank
+// if it has not been done already.
+func (t *Transform) Whitener() (*circcov.Whitener, error) {
+	t.once.Do(func() {
+		t.whitener, t.err = t.load()
+	})
+	return t.whitener, t.err
+}
+
+func (t *Transform) load() (*circcov.Whitener, error) {
+	if t.Res <= 0 {
+		return nil, fmt.Errorf("whitening resolution must be positive: %d", t.Res)
+	}
+	if !path.IsAbs(t.StatsFile) {
+		return nil, fmt.Errorf(`whitening stats file must be an absolute path: "%s"`, t.StatsFile)
+	}
+	total, err := toepcov.LoadTotalExt(t.StatsFile)
+	if err != nil {
+		return nil, fmt.Errorf(`load stats "%s": %v`, t.StatsFile, err)
+	}
+	distr := toepcov.Normalize(total, true)
+	band := t.Band
+	if band == 0 {
+		band = distr.Covar.Bandwidth
+	}
+	log.Printf("construct whitening filters: res %d, band %d", t.Res, band)
+	return circcov.NewWhitener(distr, circcov.InvApproxOpts{
+		Width:  t.Res,
+		Height: t.Res,
+		Band:   band,
+		Lambda: t.Lambda,
+		MinEig: t.MinEig,
+	})
+}